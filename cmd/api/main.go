@@ -18,6 +18,8 @@ import (
 	"blog-api/internal/repository"
 	"blog-api/internal/service"
 	"blog-api/pkg/auth"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/contentfilter"
 	"blog-api/pkg/database"
 	"blog-api/pkg/logging"
 	"blog-api/pkg/settings"
@@ -39,15 +41,43 @@ func main() {
 	passConfig := &auth.PasswordConfig{}
 	redisConfig := &middleware.RedisConfig{}
 	loggingConfig := &logging.LoggerConfig{}
+	trashConfig := &service.TrashConfig{}
+	commentConfig := &service.CommentConfig{}
+	contentFilterConfig := &service.ContentFilterConfig{}
+	reportConfig := &service.ReportConfig{}
+	webhookConfig := &service.WebhookConfig{}
+	eventsConfig := &service.EventsConfig{}
+	commentStreamConfig := &service.CommentStreamConfig{}
+	gatewayConfig := &service.GatewayConfig{}
+	feedConfig := &service.FeedConfig{}
+	jobsConfig := &service.JobsConfig{}
+	schedulerConfig := &service.SchedulerConfig{}
 	for _, cfg := range []settings.EnvConfigurable{
 		dbConfig,
 		jwtConfig,
 		passConfig,
 		redisConfig,
 		loggingConfig,
+		trashConfig,
+		commentConfig,
+		contentFilterConfig,
+		reportConfig,
+		webhookConfig,
+		eventsConfig,
+		commentStreamConfig,
+		gatewayConfig,
+		feedConfig,
+		jobsConfig,
+		schedulerConfig,
 	} {
 		settings.LoadConfig(cfg)
 	}
+	if err := jobsConfig.Validate(); err != nil {
+		panic(err)
+	}
+	if err := schedulerConfig.Validate(); err != nil {
+		panic(err)
+	}
 
 	// logger
 	logging.Init(loggingConfig)
@@ -72,20 +102,181 @@ func main() {
 	refreshTokenRepo := repository.NewRefreshTokenRepo(db)
 	postRepo := repository.NewPostRepo(db)
 	commentRepo := repository.NewCommentRepo(db)
+	commentRevisionRepo := repository.NewCommentRevisionRepo(db)
+	spamSampleRepo := repository.NewSpamSampleRepo(db)
+	reactionRepo := repository.NewReactionRepo(db)
+	reportRepo := repository.NewReportRepo(db)
+	moderationLogRepo := repository.NewModerationLogRepo(db)
+	notificationRepo := repository.NewNotificationRepo(db)
+	notificationPreferenceRepo := repository.NewNotificationPreferenceRepo(db)
+	followRepo := repository.NewFollowRepo(db)
+	webhookRepo := repository.NewWebhookRepo(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepo(db)
+	eventRepo := repository.NewEventRepo(db)
+	jobRunRepo := repository.NewJobRunRepo(db)
+
+	// content filter
+	spamClassifier := contentfilter.NewBayes()
+	if err := service.TrainSpamClassifier(context.Background(), contentFilterConfig, spamSampleRepo, spamClassifier); err != nil {
+		panic(fmt.Errorf("failed to train spam classifier: %w", err))
+	}
+	contentFilter, err := service.NewContentFilter(contentFilterConfig, postRepo, commentRepo, spamClassifier)
+	if err != nil {
+		panic(fmt.Errorf("failed to build content filter: %w", err))
+	}
+
+	// realtime messages reach the clients connected to every replica through redis pub/sub
+	messageBroker := broker.NewRedisBroker(fmt.Sprintf("%s:%d", redisConfig.Host, redisConfig.Port), redisConfig.Password, redisConfig.DB)
+	// schedule changes wake the post schedulers up through postgres LISTEN/NOTIFY
+	scheduleBroker := broker.NewPostgresBroker(dbConfig.DSN())
 
 	// services
-	userService := service.NewUserService(userRepo, refreshTokenRepo, jwtManager, passManager)
-	postService := service.NewPostService(postRepo, userRepo)
-	commentService := service.NewCommentService(commentRepo, postRepo, userRepo)
+	gatewayService := service.NewGatewayService(userRepo, postRepo, messageBroker, gatewayConfig)
+	userService := service.NewUserService(userRepo, refreshTokenRepo, eventRepo, jwtManager, passManager)
+	notificationService := service.NewNotificationService(notificationRepo, notificationPreferenceRepo, postRepo, commentRepo, gatewayService)
+	webhookService := service.NewWebhookService(webhookRepo, webhookDeliveryRepo, userRepo, webhookConfig)
+	postService := service.NewPostService(postRepo, userRepo, eventRepo, contentFilter, notificationService, webhookService, scheduleBroker)
+	commentService := service.NewCommentService(
+		commentRepo,
+		commentRevisionRepo,
+		postRepo,
+		userRepo,
+		eventRepo,
+		commentConfig,
+		contentFilter,
+		notificationService,
+		webhookService,
+	)
+	trashService := service.NewTrashService(postRepo, commentRepo, reactionRepo, reportRepo)
+	feedService := service.NewFeedService(postService, userRepo, eventRepo, feedConfig)
+	followService := service.NewFollowService(followRepo, userRepo, postRepo)
+	reactionService := service.NewReactionService(reactionRepo, postRepo, commentRepo)
+	moderationService := service.NewModerationService(
+		commentRepo,
+		postRepo,
+		userRepo,
+		spamSampleRepo,
+		moderationLogRepo,
+		eventRepo,
+		spamClassifier,
+	)
+	reportService := service.NewReportService(
+		reportRepo,
+		moderationLogRepo,
+		postRepo,
+		commentRepo,
+		userRepo,
+		reportConfig,
+	)
+
+	// event relay
+	eventRelay := service.NewEventRelay(eventRepo, eventsConfig)
+	if eventsConfig.LogSink {
+		eventRelay.AddSink(service.LogEventSink{})
+	}
+	relayCtx, relayCancel := context.WithCancel(context.Background())
+	go service.StartEventRelay(relayCtx, eventsConfig, eventRelay)
+
+	// comment stream
+	commentStreamService := service.NewCommentStreamService(eventRepo, postRepo, messageBroker, commentStreamConfig)
+	eventRelay.Subscribe(
+		"comment_stream",
+		commentStreamService.Publish,
+		model.EventCommentCreated,
+		model.EventCommentUpdated,
+		model.EventCommentDeleted,
+	)
+	streamCtx, streamCancel := context.WithCancel(context.Background())
+	go service.StartCommentStream(streamCtx, commentStreamService)
+
+	// gateway
+	eventRelay.Subscribe(
+		"gateway",
+		gatewayService.HandleEvent,
+		model.EventPostCreated,
+		model.EventPostUpdated,
+		model.EventPostPublished,
+		model.EventCommentCreated,
+		model.EventCommentUpdated,
+		model.EventCommentDeleted,
+	)
+	gatewayCtx, gatewayCancel := context.WithCancel(context.Background())
+	go service.StartGateway(gatewayCtx, gatewayService)
+
+	// notifications and webhooks, after the gateway so that its retries do not notify twice
+	eventRelay.Subscribe("post_creations", postService.AnnounceCreated, model.EventPostCreated)
+	eventRelay.Subscribe("post_publications", postService.AnnouncePublished, model.EventPostPublished)
+	eventRelay.Subscribe("post_unpublications", postService.AnnounceUnpublished, model.EventPostUnpublished)
+	eventRelay.Subscribe("post_deletions", postService.AnnounceDeleted, model.EventPostDeleted)
+	eventRelay.Subscribe(
+		"comment_announcements",
+		commentService.AnnounceCreated,
+		model.EventCommentCreated,
+		model.EventCommentApproved,
+	)
+
+	// jobs
+	jobService := service.NewJobService(jobRunRepo, userRepo, jobsConfig)
+	postScheduler := service.NewPostScheduler(postRepo, eventRepo, scheduleBroker, schedulerConfig)
+	for _, job := range []struct {
+		name, description, spec string
+		fn                      service.JobFunc
+	}{
+		{
+			service.JobPublishScheduledPosts,
+			"publishes the due scheduled posts and takes down the expired ones",
+			jobsConfig.PostScheduler,
+			postScheduler.PublishDue,
+		},
+		{
+			service.JobPurgeTrash,
+			"hard deletes the trashed posts and comments older than the retention period",
+			jobsConfig.TrashPurge,
+			func(ctx context.Context) error {
+				return trashService.Purge(ctx, time.Now().Add(-trashConfig.Retention()))
+			},
+		},
+		{
+			service.JobCleanupRefreshTokens,
+			"deletes the expired refresh tokens",
+			jobsConfig.RefreshTokenCleanup,
+			userService.PurgeExpiredRefreshTokens,
+		},
+		{
+			service.JobPurgeJobRuns,
+			"fails the runs abandoned by stopped replicas and deletes the runs older than the retention period",
+			jobsConfig.RunPurge,
+			jobService.PurgeRuns,
+		},
+	} {
+		if err := jobService.Register(job.name, job.description, job.spec, job.fn); err != nil {
+			panic(err)
+		}
+	}
+	jobs := service.StartJobs(jobService)
 
 	// post scheduler
-	schedulerCtx, schedulerCancel := context.WithCancel(context.Background())
-	go service.StartPostScheduler(schedulerCtx, postRepo)
+	scheduler := service.StartPostScheduler(postScheduler)
+
+	// webhook dispatcher
+	dispatcherCtx, dispatcherCancel := context.WithCancel(context.Background())
+	go service.StartWebhookDispatcher(dispatcherCtx, webhookConfig, webhookService)
 
 	// handlers
 	userHandler := handler.NewAuthHandler(userService)
 	postHandler := handler.NewPostHandler(postService)
 	commentHandler := handler.NewCommentHandler(commentService)
+	trashHandler := handler.NewTrashHandler(trashService)
+	reactionHandler := handler.NewReactionHandler(reactionService)
+	moderationHandler := handler.NewModerationHandler(moderationService)
+	reportHandler := handler.NewReportHandler(reportService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	followHandler := handler.NewFollowHandler(followService, postService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	commentStreamHandler := handler.NewCommentStreamHandler(commentStreamService)
+	gatewayHandler := handler.NewGatewayHandler(gatewayService)
+	feedHandler := handler.NewFeedHandler(feedService)
+	jobHandler := handler.NewJobHandler(jobService)
 
 	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
 
@@ -138,7 +329,15 @@ func main() {
 	// public post endpoints
 	router.Get("/api/posts", postHandler.GetAll)
 	router.Get("/api/posts/{postID}", postHandler.GetByID)
-	router.Get("/api/posts/{postID}/comments", commentHandler.GetByPost)
+	router.With(authMiddleware.OptionalAuth).Get("/api/posts/{postID}/comments", commentHandler.GetByPost)
+	router.Get("/api/posts/{postID}/comments/stream", commentStreamHandler.Stream)
+
+	// feeds
+	router.Get("/feeds/posts.{format}", feedHandler.GetSiteFeed)
+	router.Get("/feeds/authors/{userID}/posts.{format}", feedHandler.GetAuthorFeed)
+
+	// realtime gateway
+	router.With(authMiddleware.RequireSocketAuth).Get("/api/ws", gatewayHandler.Connect)
 
 	// protected routes
 	protected := chi.NewRouter()
@@ -164,14 +363,107 @@ func main() {
 		middleware.ModelBodyMiddleware[model.CommentUpdateRequest](commentHandler.Update),
 	)
 	protected.Delete("/api/posts/{postID}/comments/{commentID}", commentHandler.Delete)
+	protected.Get("/api/posts/{postID}/comments/{commentID}/revisions", commentHandler.GetRevisions)
+
+	// reactions
+	protected.Put(
+		"/api/posts/{postID}/reactions",
+		middleware.ModelBodyMiddleware[model.ReactionRequest](reactionHandler.SetPostReaction),
+	)
+	protected.Delete("/api/posts/{postID}/reactions", reactionHandler.RemovePostReaction)
+	protected.Put(
+		"/api/posts/{postID}/comments/{commentID}/reactions",
+		middleware.ModelBodyMiddleware[model.ReactionRequest](reactionHandler.SetCommentReaction),
+	)
+	protected.Delete("/api/posts/{postID}/comments/{commentID}/reactions", reactionHandler.RemoveCommentReaction)
+
+	// reports
+	protected.Post(
+		"/api/posts/{postID}/reports",
+		middleware.ModelBodyMiddleware[model.ReportCreateRequest](reportHandler.ReportPost),
+	)
+	protected.Post(
+		"/api/posts/{postID}/comments/{commentID}/reports",
+		middleware.ModelBodyMiddleware[model.ReportCreateRequest](reportHandler.ReportComment),
+	)
 
 	// me
 	protected.Get("/api/users/{userID}", userHandler.GetProfile)
 
+	// follows
+	protected.Get("/api/users/{userID}/follow", followHandler.GetStats)
+	protected.Put("/api/users/{userID}/follow", followHandler.Follow)
+	protected.Delete("/api/users/{userID}/follow", followHandler.Unfollow)
+	protected.Get("/api/users/{userID}/followers", followHandler.GetFollowers)
+	protected.Get("/api/users/{userID}/following", followHandler.GetFollowing)
+	protected.Get("/api/feed", followHandler.GetFeed)
+
 	// delayed posts
 	protected.Get("/api/delayed", postHandler.GetAllDelayed)
 	protected.Get("/api/delayed/{postID}", postHandler.GetDelayedByID)
 
+	// trash
+	protected.Get("/api/trash/posts", trashHandler.GetPosts)
+	protected.Get("/api/trash/comments", trashHandler.GetComments)
+	protected.Post("/api/trash/posts/{postID}/restore", trashHandler.RestorePost)
+	protected.Post("/api/trash/comments/{commentID}/restore", trashHandler.RestoreComment)
+
+	// moderation
+	protected.Get("/api/moderation/comments", moderationHandler.GetQueue)
+	protected.Post(
+		"/api/moderation/comments/approve",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.Approve),
+	)
+	protected.Post(
+		"/api/moderation/comments/reject",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.Reject),
+	)
+	protected.Get("/api/moderation/posts", moderationHandler.GetHeldPosts)
+	protected.Post(
+		"/api/moderation/posts/approve",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.ApprovePosts),
+	)
+	protected.Post(
+		"/api/moderation/posts/reject",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.RejectPosts),
+	)
+
+	protected.Get("/api/moderation/reports", reportHandler.GetReports)
+	protected.Post(
+		"/api/moderation/reports/{reportID}/resolve",
+		middleware.ModelBodyMiddleware[model.ReportDecisionRequest](reportHandler.Resolve),
+	)
+	protected.Post(
+		"/api/moderation/reports/{reportID}/dismiss",
+		middleware.ModelBodyMiddleware[model.ReportDecisionRequest](reportHandler.Dismiss),
+	)
+	protected.Get("/api/moderation/log", reportHandler.GetLog)
+
+	// notifications
+	protected.Get("/api/notifications", notificationHandler.GetAll)
+	protected.Post("/api/notifications/read", notificationHandler.MarkAllRead)
+	protected.Post("/api/notifications/{notificationID}/read", notificationHandler.MarkRead)
+	protected.Get("/api/notifications/preferences", notificationHandler.GetPreferences)
+	protected.Put(
+		"/api/notifications/preferences",
+		middleware.ModelBodyMiddleware[model.NotificationPreferencesRequest](notificationHandler.UpdatePreferences),
+	)
+
+	// webhooks
+	protected.Get("/api/webhooks", webhookHandler.GetAll)
+	protected.Post(
+		"/api/webhooks",
+		middleware.ModelBodyMiddleware[model.WebhookCreateRequest](webhookHandler.Create),
+	)
+	protected.Delete("/api/webhooks/{webhookID}", webhookHandler.Delete)
+	protected.Get("/api/webhooks/{webhookID}/deliveries", webhookHandler.GetDeliveries)
+	protected.Post("/api/webhooks/{webhookID}/deliveries/{deliveryID}/retry", webhookHandler.RetryDelivery)
+
+	// jobs
+	protected.Get("/api/admin/jobs", jobHandler.GetAll)
+	protected.Post("/api/admin/jobs/{name}/run", jobHandler.Run)
+	protected.Get("/api/admin/jobs/{name}/runs", jobHandler.GetRuns)
+
 	router.Mount("/", protected)
 	host := os.Getenv("HOST")
 	if host == "" {
@@ -204,7 +496,10 @@ func main() {
 
 	<-quit
 	logger.Info("shutdown signal received")
-	schedulerCancel()
+	dispatcherCancel()
+	relayCancel()
+	streamCancel()
+	gatewayCancel()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), gracefulShutDownTimeout)
 	defer shutdownCancel()
@@ -214,4 +509,11 @@ func main() {
 	} else {
 		logger.Info("server shut down gracefully")
 	}
+
+	// the job runs and the publication of a fired timer in flight finish before the handles return
+	for _, handle := range []*service.Handle{jobs, scheduler} {
+		if err := handle.Stop(shutdownCtx); err != nil {
+			logger.Error("%v", err)
+		}
+	}
 }
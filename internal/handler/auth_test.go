@@ -51,7 +51,7 @@ func setupAuthTestRouter() http.Handler {
 	}
 	passManager := auth.NewPasswordManager(passCfg)
 
-	userService := service.NewUserService(userRepo, refreshRepo, jwtManager, passManager)
+	userService := service.NewUserService(userRepo, refreshRepo, repository.NewInMemoryEventRepo(), jwtManager, passManager)
 	authHandler := NewAuthHandler(userService)
 
 	router := chi.NewRouter()
@@ -9,6 +9,7 @@ import (
 	"blog-api/internal/model"
 	"blog-api/internal/service"
 	"blog-api/pkg/exception"
+	"blog-api/pkg/validator"
 )
 
 type CommentHandler struct {
@@ -22,6 +23,8 @@ func NewCommentHandler(commentService *service.CommentService) *CommentHandler {
 }
 
 // GET /api/posts/{postID}/comments?limit=20&offset=0
+// GET /api/posts/{postID}/comments?limit=20&cursor={cursor}
+// GET /api/posts/{postID}/comments?view=tree&expand=author
 func (h *CommentHandler) GetByPost(w http.ResponseWriter, r *http.Request) {
 
 	pagination, ok := getPaginationParams(r)
@@ -37,7 +40,51 @@ func (h *CommentHandler) GetByPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, total, err := h.commentService.GetByPost(r.Context(), postID, pagination)
+	query := &model.CommentListParams{View: r.URL.Query().Get("view")}
+	if err := validator.ModelValidate(query); err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	expand, err := getExpandParams(r, model.CommentExpandFields)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	tree := query.View == model.CommentViewTree
+	viewerID := getOptionalActorID(r.Context())
+
+	if pagination.IsCursor() {
+		var page *model.CursorPage[*model.Comment]
+		if tree {
+			page, err = h.commentService.GetTreeByPostByCursor(r.Context(), postID, viewerID, pagination)
+		} else {
+			page, err = h.commentService.GetByPostByCursor(r.Context(), postID, viewerID, pagination)
+		}
+		if err == nil {
+			err = h.commentService.ExpandComments(r.Context(), page.Items, expand)
+		}
+		if err != nil {
+			exception.WriteApiError(w, mapServiceError(err))
+			return
+		}
+		writeCursorPageJSON(w, http.StatusOK, page, pagination)
+		return
+	}
+
+	var (
+		result []*model.Comment
+		total  int
+	)
+	if tree {
+		result, total, err = h.commentService.GetTreeByPost(r.Context(), postID, viewerID, pagination)
+	} else {
+		result, total, err = h.commentService.GetByPost(r.Context(), postID, viewerID, pagination)
+	}
+	if err == nil {
+		err = h.commentService.ExpandComments(r.Context(), result, expand)
+	}
 	if err != nil {
 		exception.WriteApiError(w, mapServiceError(err))
 		return
@@ -126,6 +173,35 @@ func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, result)
 }
 
+// GET /api/posts/{postID}/comments/{commentID}/revisions?limit=20&offset=0
+func (h *CommentHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, commentID, ok := getCommentPath(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post or comment ID"))
+		return
+	}
+
+	result, total, err := h.commentService.GetRevisions(r.Context(), actorID, postID, commentID, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
 // DELETE /api/posts/{postID}/comments/{commentID}
 func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	actorID, ok := getActorID(r.Context())
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+)
+
+type CommentStreamHandler struct {
+	streamService *service.CommentStreamService
+}
+
+func NewCommentStreamHandler(streamService *service.CommentStreamService) *CommentStreamHandler {
+	return &CommentStreamHandler{
+		streamService: streamService,
+	}
+}
+
+// GET /api/posts/{postID}/comments/stream
+// GET /api/posts/{postID}/comments/stream?last_event_id=42
+func (h *CommentStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	// browsers resend the last seen id in the header, the query parameter serves clients that cannot set it
+	lastEventIDStr := r.Header.Get("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = r.URL.Query().Get("last_event_id")
+	}
+	var lastEventID int64
+	if lastEventIDStr != "" {
+		lastEventID, err = strconv.ParseInt(lastEventIDStr, 10, 64)
+		if err != nil || lastEventID < 0 {
+			exception.WriteApiError(w, exception.BadRequestError("Invalid last event ID"))
+			return
+		}
+	}
+
+	// the stream outlives the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		exception.WriteApiError(w, exception.InternalServerError("Streaming unsupported"))
+		return
+	}
+
+	sub, missed, err := h.streamService.Subscribe(r.Context(), postID, lastEventID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+	defer h.streamService.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	config := h.streamService.Config()
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", config.RetryMillis); err != nil {
+		return
+	}
+
+	// live events already sent by the replay are skipped
+	var replayedID int64
+	for _, event := range missed {
+		if err := writeStreamEvent(w, event); err != nil {
+			return
+		}
+		replayedID = event.ID
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(config.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			// dropped for falling behind, the client reconnects with its last event id
+			if !ok {
+				return
+			}
+			if event.ID <= replayedID {
+				continue
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event *model.CommentStreamEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
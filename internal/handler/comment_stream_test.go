@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newCommentStreamTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	eventRepo := repository.NewInMemoryEventRepo()
+
+	ctx := context.Background()
+	postRepo.Create(ctx, &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: 1})
+	postRepo.Create(ctx, &model.Post{Title: "Draft", Content: "Content", AuthorID: 1})
+	for _, e := range []struct {
+		eventType string
+		data      string
+	}{
+		{model.EventCommentCreated, `{"id":1,"post_id":1,"content":"first","status":"approved"}`},
+		{model.EventCommentCreated, `{"id":2,"post_id":1,"content":"held","status":"pending"}`},
+		{model.EventCommentCreated, `{"id":3,"post_id":2,"content":"other","status":"approved"}`},
+		{model.EventCommentUpdated, `{"id":1,"post_id":1,"content":"edited","status":"approved"}`},
+		{model.EventCommentDeleted, `{"id":1,"post_id":1,"content":"edited","status":"approved"}`},
+	} {
+		eventRepo.Create(ctx, &model.Event{AggregateType: model.AggregateComment, Type: e.eventType, Payload: json.RawMessage(e.data)})
+	}
+
+	streamService := service.NewCommentStreamService(eventRepo, postRepo, broker.NewMemoryBroker(), &service.CommentStreamConfig{
+		HeartbeatSeconds: 15,
+		BufferSize:       8,
+		ReplayLimit:      100,
+		RetryMillis:      3000,
+	})
+	streamHandler := NewCommentStreamHandler(streamService)
+
+	router := chi.NewRouter()
+	router.Get("/api/posts/{postID}/comments/stream", streamHandler.Stream)
+
+	return router
+}
+
+// tests
+func TestCommentStreamHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{
+			name:       "Stream with invalid post ID",
+			url:        "/api/posts/abc/comments/stream",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Stream with invalid last event ID",
+			url:        "/api/posts/1/comments/stream?last_event_id=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Stream of unpublished post",
+			url:        "/api/posts/2/comments/stream",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Stream of missing post",
+			url:        "/api/posts/999/comments/stream",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newCommentStreamTestRouter()
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+		})
+	}
+}
+
+func TestCommentStreamHandlerReplay(t *testing.T) {
+	server := httptest.NewServer(newCommentStreamTestRouter())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/posts/1/comments/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	// the held comment and the comments of other posts are not replayed
+	want := []string{
+		"retry: 3000",
+		"",
+		"id: 4",
+		"event: comment.updated",
+		`data: {"id":1,"post_id":1,"content":"edited","status":"approved"}`,
+		"",
+		"id: 5",
+		"event: comment.deleted",
+		`data: {"id":1,"post_id":1}`,
+		"",
+	}
+	scanner := bufio.NewScanner(res.Body)
+	for i, line := range want {
+		if !scanner.Scan() {
+			t.Fatalf("stream ended before line %d: %v", i, scanner.Err())
+		}
+		if got := strings.TrimSpace(scanner.Text()); got != line {
+			t.Fatalf("line %d: expected %q, got %q", i, line, got)
+		}
+	}
+}
@@ -33,6 +33,12 @@ func newCommentTestRouter() http.Handler {
 		Email:    "tester@example.com",
 	})
 
+	userRepo.Create(ctx, &model.User{
+		ID:       2,
+		Username: "reader",
+		Email:    "reader@example.com",
+	})
+
 	postRepo.Create(ctx, &model.Post{
 		ID:        1,
 		Title:     "Test Post",
@@ -41,6 +47,15 @@ func newCommentTestRouter() http.Handler {
 		Published: true,
 	})
 
+	postRepo.Create(ctx, &model.Post{
+		ID:             2,
+		Title:          "Closed Post",
+		Content:        "Comments are closed",
+		AuthorID:       1,
+		Published:      true,
+		CommentsPolicy: model.CommentsPolicyClosed,
+	})
+
 	commentRepo.Create(ctx, &model.Comment{
 		ID:       1,
 		Content:  "Hello",
@@ -48,7 +63,19 @@ func newCommentTestRouter() http.Handler {
 		AuthorID: 1,
 	})
 
-	commentService := service.NewCommentService(commentRepo, postRepo, userRepo)
+	commentRepo.LinkUsers(userRepo)
+
+	commentService := service.NewCommentService(
+		commentRepo,
+		repository.NewInMemoryCommentRevisionRepo(),
+		postRepo,
+		userRepo,
+		repository.NewInMemoryEventRepo(),
+		&service.CommentConfig{MaxDepth: 5},
+		nil,
+		nil,
+		nil,
+	)
 	commentHandler := NewCommentHandler(commentService)
 
 	router := chi.NewRouter()
@@ -73,6 +100,11 @@ func newCommentTestRouter() http.Handler {
 			"/api/posts/{postID}/comments/{commentID}",
 			commentHandler.Delete,
 		)
+
+		r.Get(
+			"/api/posts/{postID}/comments/{commentID}/revisions",
+			commentHandler.GetRevisions,
+		)
 	})
 
 	return router
@@ -101,6 +133,39 @@ func TestCommentHandler(t *testing.T) {
 				validateJsonResponse[model.Comment](t, res)
 			},
 		},
+		{
+			name:       "Create comment on closed post",
+			method:     http.MethodPost,
+			url:        "/api/posts/2/comments",
+			body:       model.CommentCreateRequest{Content: "Hello"},
+			actorID:    2,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Create reply",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/comments",
+			body:       model.CommentCreateRequest{Content: "Reply", ParentID: ptr(1)},
+			actorID:    1,
+			wantStatus: http.StatusCreated,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var comment model.Comment
+				if err := json.NewDecoder(res.Body).Decode(&comment); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if comment.ParentID == nil || *comment.ParentID != 1 || comment.Depth != 1 {
+					t.Fatalf("expected reply to comment 1, got %+v", comment)
+				}
+			},
+		},
+		{
+			name:       "Create reply missing parent",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/comments",
+			body:       model.CommentCreateRequest{Content: "Reply", ParentID: ptr(999)},
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Create comment missing post",
 			method:     http.MethodPost,
@@ -122,6 +187,46 @@ func TestCommentHandler(t *testing.T) {
 				validateJsonResponse[model.PaginatedResponse[[]model.Comment]](t, res)
 			},
 		},
+		{
+			name:       "Get comments with expanded authors",
+			method:     http.MethodGet,
+			url:        "/api/posts/1/comments?expand=author",
+			actorID:    0,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var resp model.PaginatedResponse[[]model.Comment]
+				if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(resp.Data) != 1 || resp.Data[0].Author == nil || resp.Data[0].Author.Username != "tester" {
+					t.Fatalf("expected embedded author, got %+v", resp.Data)
+				}
+			},
+		},
+		{
+			name:       "Get comments unsupported expand",
+			method:     http.MethodGet,
+			url:        "/api/posts/1/comments?expand=comment_count",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get comments as tree",
+			method:     http.MethodGet,
+			url:        "/api/posts/1/comments?view=tree",
+			actorID:    0,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]model.Comment]](t, res)
+			},
+		},
+		{
+			name:       "Get comments unsupported view",
+			method:     http.MethodGet,
+			url:        "/api/posts/1/comments?view=graph",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Get comment by ID",
 			method:     http.MethodGet,
@@ -172,6 +277,34 @@ func TestCommentHandler(t *testing.T) {
 			validateFn: nil,
 		},
 
+		// revisions
+		{
+			name:       "Get comment revisions owner",
+			method:     http.MethodGet,
+			url:        "/api/posts/1/comments/1/revisions",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.CommentRevision]](t, res)
+			},
+		},
+		{
+			name:       "Get comment revisions forbidden",
+			method:     http.MethodGet,
+			url:        "/api/posts/1/comments/1/revisions",
+			actorID:    2,
+			wantStatus: http.StatusForbidden,
+			validateFn: nil,
+		},
+		{
+			name:       "Get comment revisions of another post",
+			method:     http.MethodGet,
+			url:        "/api/posts/2/comments/1/revisions",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+			validateFn: nil,
+		},
+
 		// delete
 		{
 			name:       "Delete comment owner",
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+	"blog-api/pkg/feed"
+)
+
+// feedEncoders maps the feed extensions to their encoders and content types
+var feedEncoders = map[string]struct {
+	encode      func(*feed.Feed) ([]byte, error)
+	contentType string
+}{
+	"rss":  {feed.RSS, feed.ContentTypeRSS},
+	"atom": {feed.Atom, feed.ContentTypeAtom},
+	"json": {feed.JSON, feed.ContentTypeJSON},
+}
+
+type FeedHandler struct {
+	feedService *service.FeedService
+}
+
+func NewFeedHandler(feedService *service.FeedService) *FeedHandler {
+	return &FeedHandler{
+		feedService: feedService,
+	}
+}
+
+// GET /feeds/posts.{rss|atom|json}
+func (h *FeedHandler) GetSiteFeed(w http.ResponseWriter, r *http.Request) {
+
+	f, err := h.feedService.GetSiteFeed(r.Context())
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	h.writeFeed(w, r, f)
+}
+
+// GET /feeds/authors/{userID}/posts.{rss|atom|json}
+func (h *FeedHandler) GetAuthorFeed(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid user ID"))
+		return
+	}
+
+	f, err := h.feedService.GetAuthorFeed(r.Context(), userID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	h.writeFeed(w, r, f)
+}
+
+// writeFeed encodes the feed in the requested format, conditional requests are answered by http.ServeContent
+func (h *FeedHandler) writeFeed(w http.ResponseWriter, r *http.Request, f *feed.Feed) {
+	encoder, ok := feedEncoders[chi.URLParam(r, "format")]
+	if !ok {
+		exception.WriteApiError(w, exception.NotFoundError("Unknown feed format"))
+		return
+	}
+
+	f.FeedURL = strings.TrimSuffix(h.feedService.Config().SiteURL, "/") + r.URL.Path
+	body, err := encoder.encode(f)
+	if err != nil {
+		logger.Error("failed to encode feed %s: %v", r.URL.Path, err)
+		exception.WriteApiError(w, exception.InternalServerError("Failed to encode feed"))
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", encoder.contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(body))
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/feed"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newFeedTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo := repository.NewInMemoryPostRepo()
+	postRepo.LinkUsers(userRepo)
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{Username: "user", Email: "user@example.com"})
+	userRepo.Create(ctx, &model.User{Username: "other", Email: "other@example.com"})
+	eventRepo := repository.NewInMemoryEventRepo()
+	postService := service.NewPostService(postRepo, userRepo, eventRepo, nil, nil, nil, nil)
+	postService.Create(ctx, 1, &model.PostCreateRequest{Title: "First", Content: "Content"})
+	postService.Create(ctx, 2, &model.PostCreateRequest{Title: "Second", Content: "Content"})
+
+	feedService := service.NewFeedService(postService, userRepo, eventRepo, &service.FeedConfig{
+		Title:         "Blog",
+		SiteURL:       "https://blog.example.com",
+		PostURL:       "https://blog.example.com/posts/{id}",
+		Size:          20,
+		ExcerptLength: 100,
+	})
+	feedHandler := NewFeedHandler(feedService)
+
+	router := chi.NewRouter()
+	router.Get("/feeds/posts.{format}", feedHandler.GetSiteFeed)
+	router.Get("/feeds/authors/{userID}/posts.{format}", feedHandler.GetAuthorFeed)
+
+	return router
+}
+
+// tests
+func TestFeedHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantContentType string
+		validateFn      func(*testing.T, []byte)
+	}{
+		{
+			name:            "RSS feed",
+			url:             "/feeds/posts.rss",
+			wantStatus:      http.StatusOK,
+			wantContentType: feed.ContentTypeRSS,
+			validateFn: func(t *testing.T, body []byte) {
+				var doc struct {
+					Items []struct {
+						Title string `xml:"title"`
+					} `xml:"channel>item"`
+				}
+				if err := xml.Unmarshal(body, &doc); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(doc.Items) != 2 || doc.Items[0].Title != "Second" {
+					t.Fatalf("expected 2 items newest first, got %+v", doc.Items)
+				}
+			},
+		},
+		{
+			name:            "Atom feed",
+			url:             "/feeds/posts.atom",
+			wantStatus:      http.StatusOK,
+			wantContentType: feed.ContentTypeAtom,
+			validateFn: func(t *testing.T, body []byte) {
+				var doc struct {
+					ID      string `xml:"id"`
+					Entries []struct {
+						ID string `xml:"id"`
+					} `xml:"entry"`
+				}
+				if err := xml.Unmarshal(body, &doc); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if doc.ID != "https://blog.example.com/feeds/posts.atom" || len(doc.Entries) != 2 {
+					t.Fatalf("expected the feed URL and 2 entries, got %+v", doc)
+				}
+			},
+		},
+		{
+			name:            "JSON feed of author",
+			url:             "/feeds/authors/1/posts.json",
+			wantStatus:      http.StatusOK,
+			wantContentType: feed.ContentTypeJSON,
+			validateFn: func(t *testing.T, body []byte) {
+				var doc struct {
+					Version string `json:"version"`
+					Items   []struct {
+						URL         string `json:"url"`
+						ContentText string `json:"content_text"`
+					} `json:"items"`
+				}
+				if err := json.Unmarshal(body, &doc); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if doc.Version != "https://jsonfeed.org/version/1.1" || len(doc.Items) != 1 || doc.Items[0].URL != "https://blog.example.com/posts/1" {
+					t.Fatalf("expected the post of the author, got %+v", doc)
+				}
+				if doc.Items[0].ContentText != "Content" {
+					t.Fatalf("expected the excerpt as content, got %q", doc.Items[0].ContentText)
+				}
+			},
+		},
+		{
+			name:       "Unknown format",
+			url:        "/feeds/posts.xml",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Feed of missing author",
+			url:        "/feeds/authors/999/posts.rss",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Feed with invalid author ID",
+			url:        "/feeds/authors/abc/posts.rss",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newFeedTestRouter()
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			if res.StatusCode >= http.StatusBadRequest {
+				validateHeaders(t, res)
+				return
+			}
+
+			if got := res.Header.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("incorrect 'Content-Type' header: %q", got)
+			}
+			if res.Header.Get("ETag") == "" || res.Header.Get("Last-Modified") == "" {
+				t.Errorf("expected ETag and Last-Modified headers, got %v", res.Header)
+			}
+			body, _ := io.ReadAll(res.Body)
+			if tt.validateFn != nil {
+				tt.validateFn(t, body)
+			}
+		})
+	}
+}
+
+func TestFeedHandlerConditionalRequests(t *testing.T) {
+	router := newFeedTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/feeds/posts.rss", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	etag, lastModified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"Matching ETag", "If-None-Match", etag, http.StatusNotModified},
+		{"Stale ETag", "If-None-Match", `"stale"`, http.StatusOK},
+		{"Not modified since", "If-Modified-Since", lastModified, http.StatusNotModified},
+		{"Modified since", "If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feeds/posts.rss", nil)
+			req.Header.Set(tt.header, tt.value)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			validateStatus(t, rec.Result(), tt.wantStatus)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+)
+
+type FollowHandler struct {
+	followService *service.FollowService
+	postService   *service.PostService
+}
+
+func NewFollowHandler(followService *service.FollowService, postService *service.PostService) *FollowHandler {
+	return &FollowHandler{
+		followService: followService,
+		postService:   postService,
+	}
+}
+
+// PUT /api/users/{userID}/follow
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.followService.Follow)
+}
+
+// DELETE /api/users/{userID}/follow
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.followService.Unfollow)
+}
+
+// GET /api/users/{userID}/follow
+func (h *FollowHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.followService.GetStats)
+}
+
+// GET /api/users/{userID}/followers?limit=20&offset=0
+func (h *FollowHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.followService.GetFollowers)
+}
+
+// GET /api/users/{userID}/following?limit=20&offset=0
+func (h *FollowHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.followService.GetFollowing)
+}
+
+// GET /api/feed?limit=10&cursor={cursor}&expand=author
+func (h *FollowHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+	if pagination.Offset != nil && *pagination.Offset != 0 {
+		exception.WriteApiError(w, exception.BadRequestError("Feed supports cursor pagination only"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	expand, err := getExpandParams(r, model.PostExpandFields)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	page, err := h.followService.GetFeed(r.Context(), actorID, pagination)
+	if err == nil {
+		err = h.postService.ExpandPosts(r.Context(), page.Items, expand)
+	}
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeCursorPageJSON(w, http.StatusOK, page, pagination)
+}
+
+func (h *FollowHandler) change(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, actorID, userID int) (*model.FollowStats, error),
+) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid user ID"))
+		return
+	}
+
+	result, err := change(r.Context(), actorID, userID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *FollowHandler) list(
+	w http.ResponseWriter,
+	r *http.Request,
+	list func(ctx context.Context, userID int, pagination *model.PaginationParams) ([]*model.UserSummary, int, error),
+) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid user ID"))
+		return
+	}
+
+	result, total, err := list(r.Context(), userID, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newFollowTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo := repository.NewInMemoryPostRepo()
+	followRepo := repository.NewInMemoryFollowRepo(userRepo)
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{ID: 1, Username: "reader", Email: "reader@example.com"})
+	userRepo.Create(ctx, &model.User{ID: 2, Username: "author", Email: "author@example.com"})
+	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Post", Content: "Content", AuthorID: 2, Published: true})
+	followRepo.Create(ctx, &model.Follow{FollowerID: 1, FolloweeID: 2})
+
+	postRepo.LinkUsers(userRepo)
+
+	followHandler := NewFollowHandler(
+		service.NewFollowService(followRepo, userRepo, postRepo),
+		service.NewPostService(postRepo, userRepo, repository.NewInMemoryEventRepo(), nil, nil, nil, nil),
+	)
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Get("/api/users/{userID}/follow", followHandler.GetStats)
+	router.Put("/api/users/{userID}/follow", followHandler.Follow)
+	router.Delete("/api/users/{userID}/follow", followHandler.Unfollow)
+	router.Get("/api/users/{userID}/followers", followHandler.GetFollowers)
+	router.Get("/api/users/{userID}/following", followHandler.GetFollowing)
+	router.Get("/api/feed", followHandler.GetFeed)
+
+	return router
+}
+
+// tests
+func TestFollowHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "Follow user",
+			method:     http.MethodPut,
+			url:        "/api/users/1/follow",
+			actorID:    2,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) { validateJsonResponse[model.FollowStats](t, res) },
+		},
+		{
+			name:       "Follow yourself",
+			method:     http.MethodPut,
+			url:        "/api/users/1/follow",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Follow unknown user",
+			method:     http.MethodPut,
+			url:        "/api/users/999/follow",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Follow invalid user ID",
+			method:     http.MethodPut,
+			url:        "/api/users/abc/follow",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unfollow user",
+			method:     http.MethodDelete,
+			url:        "/api/users/2/follow",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) { validateJsonResponse[model.FollowStats](t, res) },
+		},
+		{
+			name:       "Get follow stats",
+			method:     http.MethodGet,
+			url:        "/api/users/2/follow",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) { validateJsonResponse[model.FollowStats](t, res) },
+		},
+		{
+			name:       "Get followers",
+			method:     http.MethodGet,
+			url:        "/api/users/2/followers?limit=10&offset=0",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.UserSummary]](t, res)
+			},
+		},
+		{
+			name:       "Get following",
+			method:     http.MethodGet,
+			url:        "/api/users/1/following",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.UserSummary]](t, res)
+			},
+		},
+		{
+			name:       "Get feed",
+			method:     http.MethodGet,
+			url:        "/api/feed?limit=10&expand=author",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.Post]](t, res)
+			},
+		},
+		{
+			name:       "Get feed with offset",
+			method:     http.MethodGet,
+			url:        "/api/feed?offset=10",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get feed invalid cursor",
+			method:     http.MethodGet,
+			url:        "/api/feed?cursor=abc",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get feed unauthenticated",
+			method:     http.MethodGet,
+			url:        "/api/feed",
+			actorID:    0,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newFollowTestRouter()
+
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+	"blog-api/pkg/validator"
+)
+
+type GatewayHandler struct {
+	gatewayService *service.GatewayService
+}
+
+func NewGatewayHandler(gatewayService *service.GatewayService) *GatewayHandler {
+	return &GatewayHandler{
+		gatewayService: gatewayService,
+	}
+}
+
+// GET /api/ws
+// GET /api/ws with Sec-WebSocket-Protocol: bearer, {token}
+// GET /api/ws?access_token={token}
+func (h *GatewayHandler) Connect(w http.ResponseWriter, r *http.Request) {
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		exception.WriteApiError(w, exception.BadRequestError("WebSocket upgrade expected"))
+		return
+	}
+
+	server := websocket.Server{
+		// clients authenticate with a token, not with cookies, so any origin is accepted
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			// the token offered as a subprotocol is not echoed back, the marker is
+			if slices.Contains(config.Protocol, middleware.SocketAuthProtocol) {
+				config.Protocol = []string{middleware.SocketAuthProtocol}
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			h.serve(conn, actorID)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// serve reads the commands of the client and writes its queued messages until either side goes away
+func (h *GatewayHandler) serve(conn *websocket.Conn, userID int) {
+	config := h.gatewayService.Config()
+	conn.MaxPayloadBytes = config.MaxMessageBytes
+
+	// the server timeouts were set before the upgrade
+	conn.SetReadDeadline(time.Time{})
+
+	client := h.gatewayService.Connect(userID)
+	defer h.gatewayService.Disconnect(client)
+
+	ctx, cancel := context.WithCancel(conn.Request().Context())
+	defer cancel()
+
+	go func() {
+		defer cancel()
+		for {
+			var data []byte
+			err := websocket.Message.Receive(conn, &data)
+			if errors.Is(err, websocket.ErrFrameTooLarge) {
+				h.gatewayService.Send(client, &model.GatewayMessage{Type: model.GatewayMessageError, Error: "Message too large"})
+				continue
+			}
+			if err != nil {
+				return
+			}
+			h.handleCommand(ctx, client, data)
+		}
+	}()
+
+	heartbeat := time.NewTicker(config.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		var message *model.GatewayMessage
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-client.Messages:
+			if !ok {
+				// dropped for falling behind or on shutdown, the queue is gone so the notice is written directly
+				message = &model.GatewayMessage{Type: model.GatewayMessageError, Error: "Connection closed by server"}
+				conn.SetWriteDeadline(time.Now().Add(config.WriteTimeout()))
+				websocket.JSON.Send(conn, message)
+				return
+			}
+			message = m
+		case <-heartbeat.C:
+			message = &model.GatewayMessage{Type: model.GatewayMessagePing}
+		}
+
+		// a client that does not read is closed once its socket buffers are full
+		conn.SetWriteDeadline(time.Now().Add(config.WriteTimeout()))
+		if err := websocket.JSON.Send(conn, message); err != nil {
+			return
+		}
+	}
+}
+
+func (h *GatewayHandler) handleCommand(ctx context.Context, client *service.GatewayClient, data []byte) {
+	var command model.GatewayCommand
+	if err := json.Unmarshal(data, &command); err != nil {
+		h.gatewayService.Send(client, &model.GatewayMessage{Type: model.GatewayMessageError, Error: "Invalid message"})
+		return
+	}
+	if err := validator.ModelValidate(&command); err != nil {
+		h.gatewayService.Send(client, &model.GatewayMessage{Type: model.GatewayMessageError, Topic: command.Topic, Error: err.Error()})
+		return
+	}
+
+	reply := &model.GatewayMessage{Topic: command.Topic}
+	switch command.Action {
+	case model.GatewayActionPing:
+		reply.Type = model.GatewayMessagePong
+
+	case model.GatewayActionSubscribe:
+		reply.Type = model.GatewayMessageSubscribed
+		if err := h.gatewayService.Subscribe(ctx, client, command.Topic); err != nil {
+			reply.Type = model.GatewayMessageError
+			reply.Error = mapServiceError(err).Message
+		}
+
+	case model.GatewayActionUnsubscribe:
+		reply.Type = model.GatewayMessageUnsubscribed
+		h.gatewayService.Unsubscribe(client, command.Topic)
+	}
+	h.gatewayService.Send(client, reply)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/auth"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newGatewayTestRouter() (http.Handler, *auth.JWTManager) {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo := repository.NewInMemoryPostRepo()
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{Username: "user", Email: "user@example.com"})
+	postRepo.Create(ctx, &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: 1})
+
+	jwtManager := auth.NewJWTManager(&auth.JWTConfig{JWTSecret: "test-secret", AccessTokenTTLMinutes: 15, RefreshTokenTTLHours: 24})
+	gatewayService := service.NewGatewayService(userRepo, postRepo, broker.NewMemoryBroker(), &service.GatewayConfig{
+		SendBufferSize:      8,
+		WriteTimeoutSeconds: 1,
+		HeartbeatSeconds:    30,
+		MaxSubscriptions:    10,
+		MaxMessageBytes:     256,
+	})
+	gatewayHandler := NewGatewayHandler(gatewayService)
+	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestLoggerMiddleware)
+	router.With(authMiddleware.RequireSocketAuth).Get("/api/ws", gatewayHandler.Connect)
+
+	return router, jwtManager
+}
+
+// tests
+func TestGatewayHandlerHandshake(t *testing.T) {
+	router, jwtManager := newGatewayTestRouter()
+	token, _, _ := jwtManager.GenerateToken(context.Background(), 1)
+
+	tests := []struct {
+		name       string
+		url        string
+		header     string
+		protocol   string
+		wantStatus int
+	}{
+		{
+			name:       "Connect without token",
+			url:        "/api/ws",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Connect with invalid token",
+			url:        "/api/ws?access_token=invalid",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Connect without upgrade",
+			url:        "/api/ws?access_token=" + token,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Connect without upgrade using header",
+			url:        "/api/ws",
+			header:     "Bearer " + token,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Connect without upgrade using subprotocol",
+			url:        "/api/ws",
+			protocol:   "bearer, " + token,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Connect with invalid subprotocol token",
+			url:        "/api/ws",
+			protocol:   "bearer, invalid",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set(middleware.AuthorizationHeader, tt.header)
+			}
+			if tt.protocol != "" {
+				req.Header.Set("Sec-WebSocket-Protocol", tt.protocol)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+		})
+	}
+}
+
+func TestGatewayHandlerCommands(t *testing.T) {
+	router, jwtManager := newGatewayTestRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token, _, _ := jwtManager.GenerateToken(context.Background(), 1)
+	// the token travels as a subprotocol, only the marker is echoed back
+	config, _ := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws", server.URL)
+	config.Protocol = []string{middleware.SocketAuthProtocol, token}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if protocol := conn.Config().Protocol; len(protocol) != 1 || protocol[0] != middleware.SocketAuthProtocol {
+		t.Fatalf("expected the %q subprotocol, got %v", middleware.SocketAuthProtocol, protocol)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tests := []struct {
+		name    string
+		command string
+		want    model.GatewayMessage
+	}{
+		{"Ping", `{"action":"ping"}`, model.GatewayMessage{Type: model.GatewayMessagePong}},
+		{"Subscribe", `{"action":"subscribe","topic":"comments:1"}`, model.GatewayMessage{Type: model.GatewayMessageSubscribed, Topic: "comments:1"}},
+		{"Subscribe to forbidden topic", `{"action":"subscribe","topic":"moderation"}`, model.GatewayMessage{Type: model.GatewayMessageError, Topic: "moderation", Error: service.ErrForbidden.Error()}},
+		{"Subscribe to unknown topic", `{"action":"subscribe","topic":"feed"}`, model.GatewayMessage{Type: model.GatewayMessageError, Topic: "feed", Error: service.ErrGatewayUnknownTopic.Error()}},
+		{"Unsubscribe", `{"action":"unsubscribe","topic":"comments:1"}`, model.GatewayMessage{Type: model.GatewayMessageUnsubscribed, Topic: "comments:1"}},
+		{"Invalid JSON", `{"action":`, model.GatewayMessage{Type: model.GatewayMessageError, Error: "Invalid message"}},
+		{"Too large", `{"action":"ping","topic":"` + strings.Repeat("x", 300) + `"}`, model.GatewayMessage{Type: model.GatewayMessageError, Error: "Message too large"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := websocket.Message.Send(conn, tt.command); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+			var got model.GatewayMessage
+			if err := websocket.JSON.Receive(conn, &got); err != nil {
+				t.Fatalf("receive failed: %v", err)
+			}
+			if got.Type != tt.want.Type || got.Topic != tt.want.Topic || got.Error != tt.want.Error {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	// commands that fail validation name the problem
+	websocket.Message.Send(conn, `{"action":"subscribe"}`)
+	var got model.GatewayMessage
+	if err := websocket.JSON.Receive(conn, &got); err != nil || got.Type != model.GatewayMessageError || got.Error == "" {
+		t.Fatalf("expected a validation error, got %+v err=%v", got, err)
+	}
+}
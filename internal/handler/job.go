@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+)
+
+type JobHandler struct {
+	jobService *service.JobService
+}
+
+func NewJobHandler(jobService *service.JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// GET /api/admin/jobs
+func (h *JobHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.jobService.List(r.Context(), actorID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /api/admin/jobs/{name}/run
+func (h *JobHandler) Run(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.jobService.Trigger(r.Context(), actorID, chi.URLParam(r, "name"))
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+// GET /api/admin/jobs/{name}/runs?limit=20&offset=0
+func (h *JobHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, total, err := h.jobService.GetRuns(r.Context(), actorID, chi.URLParam(r, "name"), pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newJobTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	runRepo := repository.NewInMemoryJobRunRepo()
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{Username: "user", Email: "user@example.com"})
+	userRepo.Create(ctx, &model.User{Username: "admin", Email: "admin@example.com", Role: model.UserRoleAdmin})
+	runRepo.Claim(ctx, &model.JobRun{Job: "cleanup", Trigger: model.JobTriggerSchedule, ScheduledAt: time.Now().Add(-time.Hour), Status: model.JobRunSucceeded, Attempts: 1})
+
+	// the jobs are not started, triggered runs stay queued
+	jobService := service.NewJobService(runRepo, userRepo, &service.JobsConfig{Workers: 1, QueueSize: 10, MaxAttempts: 1, TimeoutSeconds: 1})
+	jobService.Register("cleanup", "cleans up", "@hourly", func(ctx context.Context) error { return nil })
+	jobHandler := NewJobHandler(jobService)
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Get("/api/admin/jobs", jobHandler.GetAll)
+	router.Post("/api/admin/jobs/{name}/run", jobHandler.Run)
+	router.Get("/api/admin/jobs/{name}/runs", jobHandler.GetRuns)
+
+	return router
+}
+
+// tests
+func TestJobHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "List jobs",
+			method:     http.MethodGet,
+			url:        "/api/admin/jobs",
+			actorID:    2,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var jobs []model.JobInfo
+				if err := json.NewDecoder(res.Body).Decode(&jobs); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(jobs) != 1 || jobs[0].Schedule != "@hourly" || jobs[0].NextRunAt == nil || jobs[0].LastRun == nil {
+					t.Fatalf("expected the job with its next and last runs, got %+v", jobs)
+				}
+			},
+		},
+		{
+			name:       "List jobs as user",
+			method:     http.MethodGet,
+			url:        "/api/admin/jobs",
+			actorID:    1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "List jobs without auth",
+			method:     http.MethodGet,
+			url:        "/api/admin/jobs",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Trigger job",
+			method:     http.MethodPost,
+			url:        "/api/admin/jobs/cleanup/run",
+			actorID:    2,
+			wantStatus: http.StatusAccepted,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var run model.JobRun
+				if err := json.NewDecoder(res.Body).Decode(&run); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if run.Trigger != model.JobTriggerManual || run.Status != model.JobRunPending {
+					t.Fatalf("expected a pending manual run, got %+v", run)
+				}
+			},
+		},
+		{
+			name:       "Trigger job as user",
+			method:     http.MethodPost,
+			url:        "/api/admin/jobs/cleanup/run",
+			actorID:    1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Trigger missing job",
+			method:     http.MethodPost,
+			url:        "/api/admin/jobs/missing/run",
+			actorID:    2,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "List job runs",
+			method:     http.MethodGet,
+			url:        "/api/admin/jobs/cleanup/runs?limit=10",
+			actorID:    2,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var page struct {
+					Data  []model.JobRun `json:"data"`
+					Total int            `json:"total"`
+				}
+				if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if page.Total != 1 || len(page.Data) != 1 || page.Data[0].Status != model.JobRunSucceeded {
+					t.Fatalf("expected the succeeded run, got %+v", page)
+				}
+			},
+		},
+		{
+			name:       "List runs of missing job",
+			method:     http.MethodGet,
+			url:        "/api/admin/jobs/missing/runs",
+			actorID:    2,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newJobTestRouter()
+
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
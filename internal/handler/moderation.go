@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+	"blog-api/pkg/validator"
+)
+
+type ModerationHandler struct {
+	moderationService *service.ModerationService
+}
+
+func NewModerationHandler(moderationService *service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: moderationService,
+	}
+}
+
+// GET /api/moderation/comments?status=pending&post_id=1&limit=20&offset=0
+func (h *ModerationHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	query := &model.CommentQueueParams{Status: r.URL.Query().Get("status")}
+	if p := r.URL.Query().Get("post_id"); p != "" {
+		postID, err := strconv.Atoi(p)
+		if err != nil {
+			exception.WriteApiError(w, exception.BadRequestError("Invalid post ID"))
+			return
+		}
+		query.PostID = &postID
+	}
+	if err := validator.ModelValidate(query); err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	result, total, err := h.moderationService.GetQueue(r.Context(), actorID, query, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// POST /api/moderation/comments/approve
+func (h *ModerationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ModerationRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.moderationService.Approve(r.Context(), actorID, body.IDs)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /api/moderation/comments/reject
+func (h *ModerationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ModerationRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.moderationService.Reject(r.Context(), actorID, body.IDs, body.Spam)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GET /api/moderation/posts?limit=20&offset=0
+func (h *ModerationHandler) GetHeldPosts(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, total, err := h.moderationService.GetHeldPosts(r.Context(), actorID, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// POST /api/moderation/posts/approve
+func (h *ModerationHandler) ApprovePosts(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ModerationRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.moderationService.ApprovePosts(r.Context(), actorID, body.IDs)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /api/moderation/posts/reject
+func (h *ModerationHandler) RejectPosts(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ModerationRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.moderationService.RejectPosts(r.Context(), actorID, body.IDs, body.Spam)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newModerationTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	commentRepo.LinkPosts(postRepo)
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{ID: 1, Username: "author", Email: "author@example.com"})
+	userRepo.Create(ctx, &model.User{ID: 2, Username: "reader", Email: "reader@example.com"})
+	userRepo.Create(ctx, &model.User{ID: 3, Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator})
+
+	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Post", Content: "Content", AuthorID: 1, Published: true, CommentsPremoderation: true})
+	postRepo.Create(ctx, &model.Post{ID: 2, Title: "Other", Content: "Content", AuthorID: 3, Published: true, CommentsPremoderation: true})
+	postRepo.Create(ctx, &model.Post{ID: 3, Title: "Held", Content: "Content", AuthorID: 2, Held: true})
+	commentRepo.Create(ctx, &model.Comment{ID: 1, Content: "Pending", PostID: 1, AuthorID: 2, Status: model.CommentStatusPending})
+	commentRepo.Create(ctx, &model.Comment{ID: 2, Content: "Pending", PostID: 2, AuthorID: 2, Status: model.CommentStatusPending})
+
+	moderationService := service.NewModerationService(
+		commentRepo,
+		postRepo,
+		userRepo,
+		repository.NewInMemorySpamSampleRepo(),
+		repository.NewInMemoryModerationLogRepo(),
+		repository.NewInMemoryEventRepo(),
+		nil,
+	)
+	moderationHandler := NewModerationHandler(moderationService)
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Get("/api/moderation/comments", moderationHandler.GetQueue)
+	router.Post(
+		"/api/moderation/comments/approve",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.Approve),
+	)
+	router.Post(
+		"/api/moderation/comments/reject",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.Reject),
+	)
+	router.Get("/api/moderation/posts", moderationHandler.GetHeldPosts)
+	router.Post(
+		"/api/moderation/posts/approve",
+		middleware.ModelBodyMiddleware[model.ModerationRequest](moderationHandler.ApprovePosts),
+	)
+
+	return router
+}
+
+// tests
+func TestModerationHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       any
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "Author queue",
+			method:     http.MethodGet,
+			url:        "/api/moderation/comments",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var page model.PaginatedResponse[[]model.Comment]
+				if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if page.Total == nil || *page.Total != 1 || page.Data[0].ID != 1 {
+					t.Fatalf("expected only the comment on own post, got %+v", page.Data)
+				}
+			},
+		},
+		{
+			name:       "Moderator queue",
+			method:     http.MethodGet,
+			url:        "/api/moderation/comments?status=pending",
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var page model.PaginatedResponse[[]model.Comment]
+				if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if page.Total == nil || *page.Total != 2 {
+					t.Fatalf("expected every pending comment, got %+v", page.Data)
+				}
+			},
+		},
+		{
+			name:       "Queue invalid status",
+			method:     http.MethodGet,
+			url:        "/api/moderation/comments?status=deleted",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Queue unauthenticated",
+			method:     http.MethodGet,
+			url:        "/api/moderation/comments",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Approve skips foreign posts",
+			method:     http.MethodPost,
+			url:        "/api/moderation/comments/approve",
+			body:       model.ModerationRequest{IDs: []int{1, 2, 999}},
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var result model.ModerationResult
+				if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(result.Updated) != 1 || result.Updated[0] != 1 || len(result.Skipped) != 2 {
+					t.Fatalf("unexpected result: %+v", result)
+				}
+			},
+		},
+		{
+			name:       "Reject as spam",
+			method:     http.MethodPost,
+			url:        "/api/moderation/comments/reject",
+			body:       model.ModerationRequest{IDs: []int{1, 2}, Spam: true},
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var result model.ModerationResult
+				if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(result.Updated) != 2 || len(result.Skipped) != 0 {
+					t.Fatalf("unexpected result: %+v", result)
+				}
+			},
+		},
+		{
+			name:       "Held posts moderator",
+			method:     http.MethodGet,
+			url:        "/api/moderation/posts",
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]model.Post]](t, res)
+			},
+		},
+		{
+			name:       "Held posts not a moderator",
+			method:     http.MethodGet,
+			url:        "/api/moderation/posts",
+			actorID:    1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Approve held post",
+			method:     http.MethodPost,
+			url:        "/api/moderation/posts/approve",
+			body:       model.ModerationRequest{IDs: []int{3}},
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.ModerationResult](t, res)
+			},
+		},
+		{
+			name:       "Reject empty ids",
+			method:     http.MethodPost,
+			url:        "/api/moderation/comments/reject",
+			body:       model.ModerationRequest{},
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newModerationTestRouter()
+
+			var bodyBytes []byte
+			if tt.body != nil {
+				var err error
+				bodyBytes, err = json.Marshal(tt.body)
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(bodyBytes))
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GET /api/notifications?unread=true&limit=20&offset=0
+func (h *NotificationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	query := &model.NotificationListParams{}
+	if v := r.URL.Query().Get("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			exception.WriteApiError(w, exception.BadRequestError("Invalid unread, expected true or false"))
+			return
+		}
+		query.Unread = unread
+	}
+
+	result, total, err := h.notificationService.GetNotifications(r.Context(), actorID, query, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// POST /api/notifications/{notificationID}/read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	notificationID, err := strconv.Atoi(chi.URLParam(r, "notificationID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid notification ID"))
+		return
+	}
+
+	result, err := h.notificationService.MarkRead(r.Context(), actorID, notificationID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /api/notifications/read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.notificationService.MarkAllRead(r.Context(), actorID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GET /api/notifications/preferences
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.notificationService.GetPreferences(r.Context(), actorID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// PUT /api/notifications/preferences
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.NotificationPreferencesRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.notificationService.UpdatePreferences(r.Context(), actorID, body)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
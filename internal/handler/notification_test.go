@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newNotificationTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+
+	ctx := context.Background()
+	notificationRepo.Create(ctx, &model.Notification{UserID: 1, Kind: model.NotificationComment, ActorID: ptr(2), PostID: 1})
+	notificationRepo.Create(ctx, &model.Notification{UserID: 2, Kind: model.NotificationReply, ActorID: ptr(1), PostID: 1})
+
+	notificationService := service.NewNotificationService(
+		notificationRepo,
+		repository.NewInMemoryNotificationPreferenceRepo(),
+		repository.NewInMemoryPostRepo(),
+		repository.NewInMemoryCommentRepo(),
+		nil,
+	)
+	notificationHandler := NewNotificationHandler(notificationService)
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Get("/api/notifications", notificationHandler.GetAll)
+	router.Post("/api/notifications/read", notificationHandler.MarkAllRead)
+	router.Post("/api/notifications/{notificationID}/read", notificationHandler.MarkRead)
+	router.Get("/api/notifications/preferences", notificationHandler.GetPreferences)
+	router.Put(
+		"/api/notifications/preferences",
+		middleware.ModelBodyMiddleware[model.NotificationPreferencesRequest](notificationHandler.UpdatePreferences),
+	)
+
+	return router
+}
+
+// tests
+func TestNotificationHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       any
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "Get notifications",
+			method:     http.MethodGet,
+			url:        "/api/notifications?limit=10&offset=0",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.Notification]](t, res)
+			},
+		},
+		{
+			name:       "Get unread notifications",
+			method:     http.MethodGet,
+			url:        "/api/notifications?unread=true",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.Notification]](t, res)
+			},
+		},
+		{
+			name:       "Get notifications invalid unread",
+			method:     http.MethodGet,
+			url:        "/api/notifications?unread=maybe",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get notifications unauthenticated",
+			method:     http.MethodGet,
+			url:        "/api/notifications",
+			actorID:    0,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Mark notification read",
+			method:     http.MethodPost,
+			url:        "/api/notifications/1/read",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) { validateJsonResponse[model.Notification](t, res) },
+		},
+		{
+			name:       "Mark notification of another user read",
+			method:     http.MethodPost,
+			url:        "/api/notifications/2/read",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Mark notification invalid ID",
+			method:     http.MethodPost,
+			url:        "/api/notifications/abc/read",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Mark all notifications read",
+			method:     http.MethodPost,
+			url:        "/api/notifications/read",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.NotificationsReadResult](t, res)
+			},
+		},
+		{
+			name:       "Get preferences",
+			method:     http.MethodGet,
+			url:        "/api/notifications/preferences",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.NotificationPreferences](t, res)
+			},
+		},
+		{
+			name:       "Update preferences",
+			method:     http.MethodPut,
+			url:        "/api/notifications/preferences",
+			body:       model.NotificationPreferencesRequest{Reply: ptr(false)},
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.NotificationPreferences](t, res)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newNotificationTestRouter()
+
+			var bodyBytes []byte
+			if tt.body != nil {
+				var err error
+				bodyBytes, err = json.Marshal(tt.body)
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(bodyBytes))
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
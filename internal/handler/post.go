@@ -4,8 +4,12 @@ import (
 	"blog-api/internal/model"
 	"blog-api/internal/service"
 	"blog-api/pkg/exception"
+	"blog-api/pkg/validator"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -20,7 +24,10 @@ func NewPostHandler(postService *service.PostService) *PostHandler {
 	}
 }
 
-// GET /api/posts?limit=10&offset=0&author={authorID}
+// GET /api/posts?limit=10&offset=0&author={authorID},{authorID}&sort=title&order=asc
+// GET /api/posts?created_from=2025-01-01&created_to=2025-01-31&has_comments=true
+// GET /api/posts?limit=10&cursor={cursor}&author={authorID}
+// GET /api/posts?expand=author,comment_count
 func (h *PostHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	pagination, ok := getPaginationParams(r)
 	if !ok {
@@ -28,24 +35,35 @@ func (h *PostHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authorIDStr := r.URL.Query().Get("author")
-	var (
-		result []*model.Post
-		total  int
-		err    error
-	)
+	query, err := getPostListParams(r)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
 
-	if authorIDStr != "" {
-		authorID, err := strconv.Atoi(authorIDStr)
+	expand, err := getExpandParams(r, model.PostExpandFields)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	if pagination.IsCursor() {
+		page, err := h.postService.GetAllByCursor(r.Context(), query, pagination)
+		if err == nil {
+			err = h.postService.ExpandPosts(r.Context(), page.Items, expand)
+		}
 		if err != nil {
-			exception.WriteApiError(w, exception.BadRequestError("Invalid author ID"))
+			exception.WriteApiError(w, mapServiceError(err))
 			return
 		}
-		result, total, err = h.postService.GetByAuthor(r.Context(), authorID, pagination)
-	} else {
-		result, total, err = h.postService.GetAll(r.Context(), pagination)
+		writeCursorPageJSON(w, http.StatusOK, page, pagination)
+		return
 	}
 
+	result, total, err := h.postService.List(r.Context(), query, pagination)
+	if err == nil {
+		err = h.postService.ExpandPosts(r.Context(), result, expand)
+	}
 	if err != nil {
 		exception.WriteApiError(w, mapServiceError(err))
 		return
@@ -54,7 +72,7 @@ func (h *PostHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
 }
 
-// GET /api/posts/{postID}
+// GET /api/posts/{postID}?expand=author,comment_count
 func (h *PostHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	postIDStr := chi.URLParam(r, "postID")
 	postID, err := strconv.Atoi(postIDStr)
@@ -63,7 +81,16 @@ func (h *PostHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expand, err := getExpandParams(r, model.PostExpandFields)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
 	result, err := h.postService.GetByID(r.Context(), postID)
+	if err == nil {
+		err = h.postService.ExpandPosts(r.Context(), []*model.Post{result}, expand)
+	}
 	if err != nil {
 		exception.WriteApiError(w, mapServiceError(err))
 		return
@@ -196,3 +223,68 @@ func (h *PostHandler) GetDelayedByID(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, post)
 }
+
+func getPostListParams(r *http.Request) (*model.PostListParams, error) {
+	query := r.URL.Query()
+	params := &model.PostListParams{
+		Sort:  query.Get("sort"),
+		Order: strings.ToLower(query.Get("order")),
+	}
+
+	if a := query.Get("author"); a != "" {
+		for _, part := range strings.Split(a, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("invalid author ID %q", part)
+			}
+			params.AuthorIDs = append(params.AuthorIDs, id)
+		}
+	}
+
+	if v := query.Get("created_from"); v != "" {
+		t, err := parseQueryTime(v, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_from %q, expected RFC 3339 or YYYY-MM-DD", v)
+		}
+		params.CreatedFrom = &t
+	}
+
+	if v := query.Get("created_to"); v != "" {
+		t, err := parseQueryTime(v, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_to %q, expected RFC 3339 or YYYY-MM-DD", v)
+		}
+		params.CreatedTo = &t
+	}
+
+	if v := query.Get("has_comments"); v != "" {
+		hasComments, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid has_comments %q, expected true or false", v)
+		}
+		params.HasComments = &hasComments
+	}
+
+	if err := validator.ModelValidate(params); err != nil {
+		logger.Info("%s", err.Error())
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// parseQueryTime accepts RFC 3339 timestamps or plain dates, an upper bound date covers the whole day
+func parseQueryTime(value string, upperBound bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// the upper bound is inclusive, a date covers its last microsecond, the precision of postgres timestamps
+	if upperBound {
+		t = t.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+	return t, nil
+}
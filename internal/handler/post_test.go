@@ -35,7 +35,9 @@ func newPostTestRouter() http.Handler {
 	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Test Post", Content: "Content", AuthorID: 1, Published: true, PublishAt: &now})
 	postRepo.Create(ctx, &model.Post{ID: 2, Title: "Future Post", Content: "Delayed", AuthorID: 1, Published: false, PublishAt: ptr(now.Add(1 * time.Hour))})
 
-	postService := service.NewPostService(postRepo, userRepo)
+	postRepo.LinkUsers(userRepo)
+
+	postService := service.NewPostService(postRepo, userRepo, repository.NewInMemoryEventRepo(), nil, nil, nil, nil)
 	postHandler := NewPostHandler(postService)
 
 	router := chi.NewRouter()
@@ -108,6 +110,115 @@ func TestPostHandler(t *testing.T) {
 				validateJsonResponse[model.PaginatedResponse[[]model.Post]](t, res)
 			},
 		},
+		{
+			name:       "Get posts by cursor",
+			method:     http.MethodGet,
+			url:        "/api/posts?limit=10&cursor=",
+			actorID:    0,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var resp model.PaginatedResponse[[]model.Post]
+				if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(resp.Data) != 1 || resp.Total != nil || resp.NextCursor != "" {
+					t.Fatalf("unexpected cursor page: %+v", resp)
+				}
+			},
+		},
+		{
+			name:       "Get posts invalid cursor",
+			method:     http.MethodGet,
+			url:        "/api/posts?cursor=garbage",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get posts cursor with offset",
+			method:     http.MethodGet,
+			url:        "/api/posts?cursor=&offset=10",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get posts sorted and filtered",
+			method:     http.MethodGet,
+			url:        "/api/posts?sort=title&order=asc&author=1,2&created_from=2000-01-01&has_comments=false",
+			actorID:    0,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]model.Post]](t, res)
+			},
+		},
+		{
+			name:       "Get posts unsupported sort field",
+			method:     http.MethodGet,
+			url:        "/api/posts?sort=password_hash",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get posts invalid author list",
+			method:     http.MethodGet,
+			url:        "/api/posts?author=1,abc",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get posts inverted date range",
+			method:     http.MethodGet,
+			url:        "/api/posts?created_from=2025-02-01&created_to=2025-01-01",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get posts cursor with custom sort",
+			method:     http.MethodGet,
+			url:        "/api/posts?cursor=&sort=title",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get posts expanded",
+			method:     http.MethodGet,
+			url:        "/api/posts?expand=author,comment_count",
+			actorID:    0,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var resp model.PaginatedResponse[[]model.Post]
+				if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				for _, p := range resp.Data {
+					if p.Author == nil || p.Author.Username != "tester" || p.CommentCount == nil {
+						t.Fatalf("expected embedded author and comment count, got %+v", p)
+					}
+				}
+			},
+		},
+		{
+			name:       "Get post by ID expanded",
+			method:     http.MethodGet,
+			url:        "/api/posts/1?expand=author",
+			actorID:    0,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var post model.Post
+				if err := json.NewDecoder(res.Body).Decode(&post); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if post.Author == nil || post.Author.ID != 1 || post.CommentCount != nil {
+					t.Fatalf("expected only the author to be embedded, got %+v", post)
+				}
+			},
+		},
+		{
+			name:       "Get posts unsupported expand",
+			method:     http.MethodGet,
+			url:        "/api/posts?expand=password",
+			actorID:    0,
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "Get post by ID",
 			method:     http.MethodGet,
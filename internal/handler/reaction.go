@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+)
+
+type ReactionHandler struct {
+	reactionService *service.ReactionService
+}
+
+func NewReactionHandler(reactionService *service.ReactionService) *ReactionHandler {
+	return &ReactionHandler{
+		reactionService: reactionService,
+	}
+}
+
+// PUT /api/posts/{postID}/reactions
+func (h *ReactionHandler) SetPostReaction(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ReactionRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	result, err := h.reactionService.SetPostReaction(r.Context(), actorID, postID, body.Kind)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DELETE /api/posts/{postID}/reactions
+func (h *ReactionHandler) RemovePostReaction(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	result, err := h.reactionService.RemovePostReaction(r.Context(), actorID, postID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// PUT /api/posts/{postID}/comments/{commentID}/reactions
+func (h *ReactionHandler) SetCommentReaction(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ReactionRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, commentID, ok := getCommentPath(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post or comment ID"))
+		return
+	}
+
+	result, err := h.reactionService.SetCommentReaction(r.Context(), actorID, postID, commentID, body.Kind)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DELETE /api/posts/{postID}/comments/{commentID}/reactions
+func (h *ReactionHandler) RemoveCommentReaction(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, commentID, ok := getCommentPath(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post or comment ID"))
+		return
+	}
+
+	result, err := h.reactionService.RemoveCommentReaction(r.Context(), actorID, postID, commentID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func getCommentPath(r *http.Request) (postID, commentID int, ok bool) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		return 0, 0, false
+	}
+	commentID, err = strconv.Atoi(chi.URLParam(r, "commentID"))
+	if err != nil {
+		return 0, 0, false
+	}
+	return postID, commentID, true
+}
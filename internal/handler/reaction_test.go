@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newReactionTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	reactionRepo := repository.NewInMemoryReactionRepo(postRepo, commentRepo)
+
+	ctx := context.Background()
+	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Post", Content: "Content", AuthorID: 1, Published: true})
+	commentRepo.Create(ctx, &model.Comment{ID: 1, Content: "Hello", PostID: 1, AuthorID: 1})
+	reactionRepo.Set(ctx, &model.Reaction{TargetType: model.ReactionTargetPost, TargetID: 1, UserID: 1, Kind: model.ReactionLike})
+
+	reactionHandler := NewReactionHandler(service.NewReactionService(reactionRepo, postRepo, commentRepo))
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Put(
+		"/api/posts/{postID}/reactions",
+		middleware.ModelBodyMiddleware[model.ReactionRequest](reactionHandler.SetPostReaction),
+	)
+	router.Delete("/api/posts/{postID}/reactions", reactionHandler.RemovePostReaction)
+	router.Put(
+		"/api/posts/{postID}/comments/{commentID}/reactions",
+		middleware.ModelBodyMiddleware[model.ReactionRequest](reactionHandler.SetCommentReaction),
+	)
+	router.Delete("/api/posts/{postID}/comments/{commentID}/reactions", reactionHandler.RemoveCommentReaction)
+
+	return router
+}
+
+// tests
+func TestReactionHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       any
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "React to post",
+			method:     http.MethodPut,
+			url:        "/api/posts/1/reactions",
+			body:       model.ReactionRequest{Kind: model.ReactionLove},
+			actorID:    2,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var summary model.ReactionSummary
+				if err := json.NewDecoder(res.Body).Decode(&summary); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if summary.ReactionCount != 2 || summary.Mine != model.ReactionLove {
+					t.Fatalf("unexpected summary: %+v", summary)
+				}
+			},
+		},
+		{
+			name:       "React with unknown kind",
+			method:     http.MethodPut,
+			url:        "/api/posts/1/reactions",
+			body:       model.ReactionRequest{Kind: "poop"},
+			actorID:    2,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "React to missing post",
+			method:     http.MethodPut,
+			url:        "/api/posts/999/reactions",
+			body:       model.ReactionRequest{Kind: model.ReactionLike},
+			actorID:    2,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Remove post reaction",
+			method:     http.MethodDelete,
+			url:        "/api/posts/1/reactions",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.ReactionSummary](t, res)
+			},
+		},
+		{
+			name:       "Remove missing reaction",
+			method:     http.MethodDelete,
+			url:        "/api/posts/1/reactions",
+			actorID:    2,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "React to comment",
+			method:     http.MethodPut,
+			url:        "/api/posts/1/comments/1/reactions",
+			body:       model.ReactionRequest{Kind: model.ReactionLaugh},
+			actorID:    2,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.ReactionSummary](t, res)
+			},
+		},
+		{
+			name:       "React to comment of another post",
+			method:     http.MethodPut,
+			url:        "/api/posts/2/comments/1/reactions",
+			body:       model.ReactionRequest{Kind: model.ReactionLaugh},
+			actorID:    2,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newReactionTestRouter()
+
+			var bodyBytes []byte
+			if tt.body != nil {
+				var err error
+				bodyBytes, err = json.Marshal(tt.body)
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(bodyBytes))
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
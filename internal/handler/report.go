@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+	"blog-api/pkg/validator"
+)
+
+type ReportHandler struct {
+	reportService *service.ReportService
+}
+
+func NewReportHandler(reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// POST /api/posts/{postID}/reports
+func (h *ReportHandler) ReportPost(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ReportCreateRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "postID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	result, err := h.reportService.ReportPost(r.Context(), actorID, postID, body)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// POST /api/posts/{postID}/comments/{commentID}/reports
+func (h *ReportHandler) ReportComment(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.ReportCreateRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postID, commentID, ok := getCommentPath(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post or comment ID"))
+		return
+	}
+
+	result, err := h.reportService.ReportComment(r.Context(), actorID, postID, commentID, body)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// GET /api/moderation/reports?status=open&target_type=comment&limit=20&offset=0
+func (h *ReportHandler) GetReports(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	query := &model.ReportListParams{
+		Status:     r.URL.Query().Get("status"),
+		TargetType: r.URL.Query().Get("target_type"),
+	}
+	if err := validator.ModelValidate(query); err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	result, total, err := h.reportService.GetReports(r.Context(), actorID, query, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// POST /api/moderation/reports/{reportID}/resolve
+func (h *ReportHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.reportService.Resolve)
+}
+
+// POST /api/moderation/reports/{reportID}/dismiss
+func (h *ReportHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.reportService.Dismiss)
+}
+
+// GET /api/moderation/log?target_type=post&target_id=1&limit=20&offset=0
+func (h *ReportHandler) GetLog(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	query := &model.ModerationLogParams{TargetType: r.URL.Query().Get("target_type")}
+	if t := r.URL.Query().Get("target_id"); t != "" {
+		targetID, err := strconv.Atoi(t)
+		if err != nil {
+			exception.WriteApiError(w, exception.BadRequestError("Invalid target ID"))
+			return
+		}
+		query.TargetID = &targetID
+	}
+	if err := validator.ModelValidate(query); err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	result, total, err := h.reportService.GetLog(r.Context(), actorID, query, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+func (h *ReportHandler) decide(
+	w http.ResponseWriter,
+	r *http.Request,
+	decision func(ctx context.Context, userID, reportID int, note string) (*model.Report, error),
+) {
+	body, ok := getParsedBody[model.ReportDecisionRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid report ID"))
+		return
+	}
+
+	result, err := decision(r.Context(), actorID, reportID, body.Note)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newReportTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	reportRepo := repository.NewInMemoryReportRepo()
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{ID: 1, Username: "author", Email: "author@example.com"})
+	userRepo.Create(ctx, &model.User{ID: 2, Username: "reader", Email: "reader@example.com"})
+	userRepo.Create(ctx, &model.User{ID: 3, Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator})
+
+	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Post", Content: "Content", AuthorID: 1, Published: true})
+	commentRepo.Create(ctx, &model.Comment{ID: 1, Content: "Hello", PostID: 1, AuthorID: 1})
+	reportRepo.Create(ctx, &model.Report{
+		TargetType: model.ReportTargetComment,
+		TargetID:   1,
+		ReporterID: 2,
+		Reason:     model.ReportReasonAbuse,
+	})
+
+	reportService := service.NewReportService(
+		reportRepo,
+		repository.NewInMemoryModerationLogRepo(),
+		postRepo,
+		commentRepo,
+		userRepo,
+		&service.ReportConfig{AutoHideThreshold: 3},
+	)
+	reportHandler := NewReportHandler(reportService)
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Post(
+		"/api/posts/{postID}/reports",
+		middleware.ModelBodyMiddleware[model.ReportCreateRequest](reportHandler.ReportPost),
+	)
+	router.Post(
+		"/api/posts/{postID}/comments/{commentID}/reports",
+		middleware.ModelBodyMiddleware[model.ReportCreateRequest](reportHandler.ReportComment),
+	)
+	router.Get("/api/moderation/reports", reportHandler.GetReports)
+	router.Post(
+		"/api/moderation/reports/{reportID}/resolve",
+		middleware.ModelBodyMiddleware[model.ReportDecisionRequest](reportHandler.Resolve),
+	)
+	router.Post(
+		"/api/moderation/reports/{reportID}/dismiss",
+		middleware.ModelBodyMiddleware[model.ReportDecisionRequest](reportHandler.Dismiss),
+	)
+	router.Get("/api/moderation/log", reportHandler.GetLog)
+
+	return router
+}
+
+// tests
+func TestReportHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       any
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "Report post",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/reports",
+			body:       model.ReportCreateRequest{Reason: model.ReportReasonSpam, Text: "ads"},
+			actorID:    2,
+			wantStatus: http.StatusCreated,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var report model.Report
+				if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if report.Status != model.ReportStatusOpen || report.ReporterID != 2 {
+					t.Fatalf("unexpected report: %+v", report)
+				}
+			},
+		},
+		{
+			name:       "Report with unknown reason",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/reports",
+			body:       model.ReportCreateRequest{Reason: "boring"},
+			actorID:    2,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Report own post",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/reports",
+			body:       model.ReportCreateRequest{Reason: model.ReportReasonSpam},
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Report comment twice",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/comments/1/reports",
+			body:       model.ReportCreateRequest{Reason: model.ReportReasonAbuse},
+			actorID:    2,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Report missing comment",
+			method:     http.MethodPost,
+			url:        "/api/posts/1/comments/999/reports",
+			body:       model.ReportCreateRequest{Reason: model.ReportReasonAbuse},
+			actorID:    2,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "List reports as moderator",
+			method:     http.MethodGet,
+			url:        "/api/moderation/reports?target_type=comment",
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.Report]](t, res)
+			},
+		},
+		{
+			name:       "List reports as reader",
+			method:     http.MethodGet,
+			url:        "/api/moderation/reports",
+			actorID:    2,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "List reports with unknown status",
+			method:     http.MethodGet,
+			url:        "/api/moderation/reports?status=archived",
+			actorID:    3,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Resolve report",
+			method:     http.MethodPost,
+			url:        "/api/moderation/reports/1/resolve",
+			body:       model.ReportDecisionRequest{Note: "insults"},
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var report model.Report
+				if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if report.Status != model.ReportStatusResolved || report.ResolvedBy == nil || *report.ResolvedBy != 3 {
+					t.Fatalf("unexpected report: %+v", report)
+				}
+			},
+		},
+		{
+			name:       "Dismiss report as reader",
+			method:     http.MethodPost,
+			url:        "/api/moderation/reports/1/dismiss",
+			body:       model.ReportDecisionRequest{},
+			actorID:    2,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Dismiss missing report",
+			method:     http.MethodPost,
+			url:        "/api/moderation/reports/999/dismiss",
+			body:       model.ReportDecisionRequest{},
+			actorID:    3,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Get moderation log",
+			method:     http.MethodGet,
+			url:        "/api/moderation/log?target_type=comment&target_id=1",
+			actorID:    3,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]*model.ModerationLogEntry]](t, res)
+			},
+		},
+		{
+			name:       "Get moderation log by target ID only",
+			method:     http.MethodGet,
+			url:        "/api/moderation/log?target_id=1",
+			actorID:    3,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newReportTestRouter()
+
+			var bodyBytes []byte
+			if tt.body != nil {
+				var err error
+				bodyBytes, err = json.Marshal(tt.body)
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(bodyBytes))
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
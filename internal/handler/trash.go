@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+)
+
+type TrashHandler struct {
+	trashService *service.TrashService
+}
+
+func NewTrashHandler(trashService *service.TrashService) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+	}
+}
+
+// GET /api/trash/posts?limit=10&offset=0
+func (h *TrashHandler) GetPosts(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, total, err := h.trashService.GetPosts(r.Context(), actorID, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// GET /api/trash/comments?limit=10&offset=0
+func (h *TrashHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, total, err := h.trashService.GetComments(r.Context(), actorID, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// POST /api/trash/posts/{postID}/restore
+func (h *TrashHandler) RestorePost(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := strconv.Atoi(postIDStr)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	result, err := h.trashService.RestorePost(r.Context(), postID, actorID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /api/trash/comments/{commentID}/restore
+func (h *TrashHandler) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.InternalServerError("Auth misconfigured"))
+		return
+	}
+
+	commentIDStr := chi.URLParam(r, "commentID")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid comment ID"))
+		return
+	}
+
+	result, err := h.trashService.RestoreComment(r.Context(), commentID, actorID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newTrashTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+
+	ctx := context.Background()
+	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Trashed Post", Content: "Content", AuthorID: 1, Published: true})
+	postRepo.Create(ctx, &model.Post{ID: 2, Title: "Live Post", Content: "Content", AuthorID: 1, Published: true})
+	commentRepo.Create(ctx, &model.Comment{ID: 1, Content: "Trashed", PostID: 2, AuthorID: 1})
+	postRepo.Delete(ctx, 1)
+	commentRepo.Delete(ctx, 1)
+
+	trashHandler := NewTrashHandler(service.NewTrashService(postRepo, commentRepo, repository.NewInMemoryReactionRepo(postRepo, commentRepo), repository.NewInMemoryReportRepo()))
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Get("/api/trash/posts", trashHandler.GetPosts)
+	router.Get("/api/trash/comments", trashHandler.GetComments)
+	router.Post("/api/trash/posts/{postID}/restore", trashHandler.RestorePost)
+	router.Post("/api/trash/comments/{commentID}/restore", trashHandler.RestoreComment)
+
+	return router
+}
+
+// tests
+func TestTrashHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "Get trashed posts",
+			method:     http.MethodGet,
+			url:        "/api/trash/posts?limit=10&offset=0",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]model.Post]](t, res)
+			},
+		},
+		{
+			name:       "Get trashed comments",
+			method:     http.MethodGet,
+			url:        "/api/trash/comments",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				validateJsonResponse[model.PaginatedResponse[[]model.Comment]](t, res)
+			},
+		},
+		{
+			name:       "Get trash unauthenticated",
+			method:     http.MethodGet,
+			url:        "/api/trash/posts",
+			actorID:    0,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Restore post owner",
+			method:     http.MethodPost,
+			url:        "/api/trash/posts/1/restore",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) { validateJsonResponse[model.Post](t, res) },
+		},
+		{
+			name:       "Restore post forbidden",
+			method:     http.MethodPost,
+			url:        "/api/trash/posts/1/restore",
+			actorID:    2,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Restore live post",
+			method:     http.MethodPost,
+			url:        "/api/trash/posts/2/restore",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Restore comment owner",
+			method:     http.MethodPost,
+			url:        "/api/trash/comments/1/restore",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) { validateJsonResponse[model.Comment](t, res) },
+		},
+		{
+			name:       "Restore comment not found",
+			method:     http.MethodPost,
+			url:        "/api/trash/comments/999/restore",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTrashTestRouter()
+
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
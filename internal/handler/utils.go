@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"blog-api/internal/middleware"
 	"blog-api/internal/model"
@@ -52,6 +53,14 @@ func getActorID(ctx context.Context) (int, bool) {
 	return userID, true
 }
 
+// getOptionalActorID returns the authenticated user on routes where authentication is optional
+func getOptionalActorID(ctx context.Context) *int {
+	if userID, ok := ctx.Value(middleware.UserIDKey).(int); ok && userID != 0 {
+		return &userID
+	}
+	return nil
+}
+
 func getPaginationParams(r *http.Request) (*model.PaginationParams, bool) {
 	pagination := &model.PaginationParams{}
 	query := r.URL.Query()
@@ -74,6 +83,20 @@ func getPaginationParams(r *http.Request) (*model.PaginationParams, bool) {
 		}
 	}
 
+	if query.Has("cursor") {
+		cursor := query.Get("cursor")
+		pagination.Cursor = &cursor
+	}
+
+	if t := query.Get("with_total"); t != "" {
+		if v, err := strconv.ParseBool(t); err == nil {
+			pagination.WithTotal = &v
+		} else {
+			logger.Error("Invalid with_total value: %v", err)
+			return nil, false
+		}
+	}
+
 	if err := validator.ModelValidate(pagination); err != nil {
 		logger.Error("%s", err.Error())
 		return nil, false
@@ -82,6 +105,25 @@ func getPaginationParams(r *http.Request) (*model.PaginationParams, bool) {
 	return pagination, true
 }
 
+func getExpandParams(r *http.Request, allowed []string) (*model.ExpandParams, error) {
+	expand := &model.ExpandParams{Allowed: allowed}
+
+	if e := r.URL.Query().Get("expand"); e != "" {
+		for _, field := range strings.Split(e, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				expand.Fields = append(expand.Fields, field)
+			}
+		}
+	}
+
+	if err := validator.ModelValidate(expand); err != nil {
+		logger.Info("%s", err.Error())
+		return nil, err
+	}
+
+	return expand, nil
+}
+
 func writePaginatedJSON[T any](
 	w http.ResponseWriter,
 	status int,
@@ -103,17 +145,31 @@ func writePaginatedJSON[T any](
 		Data:   data,
 		Limit:  limit,
 		Offset: offset,
-		Total:  total,
+	}
+	if pagination == nil || pagination.CountTotal() {
+		resp.Total = &total
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		exception.WriteApiError(
-			w,
-			exception.InternalServerError(err.Error()),
-		)
+	writeJSON(w, status, resp)
+}
+
+func writeCursorPageJSON[T any](
+	w http.ResponseWriter,
+	status int,
+	page *model.CursorPage[T],
+	pagination *model.PaginationParams,
+) {
+	resp := model.PaginatedResponse[[]T]{
+		Data:       page.Items,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	if pagination != nil && pagination.Limit != nil {
+		resp.Limit = *pagination.Limit
 	}
+
+	writeJSON(w, status, resp)
 }
 
 func mapServiceError(err error) *exception.ApiError {
@@ -147,12 +203,96 @@ func mapServiceError(err error) *exception.ApiError {
 	// post
 	case errors.Is(err, service.ErrPostNotFound):
 		return exception.NotFoundError(err.Error())
+	case errors.Is(err, service.ErrInvalidUnpublishAt):
+		return exception.BadRequestError(err.Error())
 
 	// comment
 	case errors.Is(err, service.ErrCommentNotFound):
 		return exception.NotFoundError(err.Error())
 
+	case errors.Is(err, service.ErrInvalidParent):
+		return exception.BadRequestError(err.Error())
+
+	case errors.Is(err, service.ErrReplyDepthExceeded):
+		return exception.BadRequestError(err.Error())
+
+	case errors.Is(err, service.ErrCommentsClosed):
+		return exception.CommentsLockedError(err.Error())
+
+	case errors.Is(err, service.ErrCommentsRestricted):
+		return exception.CommentsLockedError(err.Error())
+
+	case errors.Is(err, service.ErrEditWindowExpired):
+		return exception.ForbiddenError(err.Error())
+
+	// reaction
+	case errors.Is(err, service.ErrReactionNotFound):
+		return exception.NotFoundError(err.Error())
+
+	// report
+	case errors.Is(err, service.ErrReportNotFound):
+		return exception.NotFoundError(err.Error())
+
+	case errors.Is(err, service.ErrReportClosed):
+		return exception.ConflictError(err.Error())
+
+	case errors.Is(err, service.ErrAlreadyReported):
+		return exception.ConflictError(err.Error())
+
+	case errors.Is(err, service.ErrSelfReport):
+		return exception.BadRequestError(err.Error())
+
+	// follow
+	case errors.Is(err, service.ErrSelfFollow):
+		return exception.BadRequestError(err.Error())
+
+	// notification
+	case errors.Is(err, service.ErrNotificationNotFound):
+		return exception.NotFoundError(err.Error())
+
+	// webhook
+	case errors.Is(err, service.ErrWebhookNotFound):
+		return exception.NotFoundError(err.Error())
+
+	case errors.Is(err, service.ErrWebhookDeliveryNotFound):
+		return exception.NotFoundError(err.Error())
+
+	case errors.Is(err, service.ErrWebhookDeliveryNotDead):
+		return exception.ConflictError(err.Error())
+
+	// gateway
+	case errors.Is(err, service.ErrGatewayUnknownTopic):
+		return exception.BadRequestError(err.Error())
+
+	case errors.Is(err, service.ErrGatewayTooManySubscriptions):
+		return exception.ConflictError(err.Error())
+
+	// jobs
+	case errors.Is(err, service.ErrJobNotFound):
+		return exception.NotFoundError(err.Error())
+
+	case errors.Is(err, service.ErrJobRunning):
+		return exception.ConflictError(err.Error())
+
+	case errors.Is(err, service.ErrJobsStopped):
+		return exception.ConflictError(err.Error())
+
+	case errors.Is(err, service.ErrJobQueueFull):
+		return exception.TooManyRequestsError(err.Error())
+
 	// common
+	case errors.Is(err, service.ErrUnsupportedContentFormat):
+		return exception.BadRequestError(err.Error())
+
+	case errors.Is(err, service.ErrInvalidCursor):
+		return exception.BadRequestError(err.Error())
+
+	case errors.Is(err, service.ErrCursorSortUnsupported):
+		return exception.BadRequestError(err.Error())
+
+	case errors.Is(err, service.ErrContentRejected):
+		return exception.ContentRejectedError(err.Error())
+
 	case errors.Is(err, service.ErrForbidden):
 		return exception.ForbiddenError(err.Error())
 
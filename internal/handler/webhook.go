@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/service"
+	"blog-api/pkg/exception"
+	"blog-api/pkg/validator"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// POST /api/webhooks
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	body, ok := getParsedBody[model.WebhookCreateRequest](r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid request body"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, err := h.webhookService.Register(r.Context(), actorID, body)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// GET /api/webhooks?limit=20&offset=0
+func (h *WebhookHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	result, total, err := h.webhookService.List(r.Context(), actorID, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// DELETE /api/webhooks/{webhookID}
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid webhook ID"))
+		return
+	}
+
+	if err := h.webhookService.Delete(r.Context(), actorID, webhookID); err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/webhooks/{webhookID}/deliveries?status=dead&limit=20&offset=0
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	pagination, ok := getPaginationParams(r)
+	if !ok {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid pagination parameters"))
+		return
+	}
+
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid webhook ID"))
+		return
+	}
+
+	query := &model.WebhookDeliveryListParams{Status: r.URL.Query().Get("status")}
+	if err := validator.ModelValidate(query); err != nil {
+		exception.WriteApiError(w, exception.BadRequestError(err.Error()))
+		return
+	}
+
+	result, total, err := h.webhookService.GetDeliveries(r.Context(), actorID, webhookID, query, pagination)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writePaginatedJSON(w, http.StatusOK, result, pagination, total)
+}
+
+// POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/retry
+func (h *WebhookHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := getActorID(r.Context())
+	if !ok {
+		exception.WriteApiError(w, exception.UnauthorizedError("Missing authentication"))
+		return
+	}
+
+	webhookID, err := strconv.Atoi(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid webhook ID"))
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		exception.WriteApiError(w, exception.BadRequestError("Invalid delivery ID"))
+		return
+	}
+
+	result, err := h.webhookService.Retry(r.Context(), actorID, webhookID, deliveryID)
+	if err != nil {
+		exception.WriteApiError(w, mapServiceError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
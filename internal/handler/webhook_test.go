@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"blog-api/internal/middleware"
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/internal/service"
+	"blog-api/pkg/logging"
+)
+
+// setup
+func newWebhookTestRouter() http.Handler {
+	logging.Init(&logging.LoggerConfig{})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	webhookRepo := repository.NewInMemoryWebhookRepo()
+	deliveryRepo := repository.NewInMemoryWebhookDeliveryRepo()
+
+	ctx := context.Background()
+	userRepo.Create(ctx, &model.User{Username: "user", Email: "user@example.com"})
+	userRepo.Create(ctx, &model.User{Username: "admin", Email: "admin@example.com", Role: model.UserRoleAdmin})
+	webhookRepo.Create(ctx, &model.Webhook{OwnerID: 1, URL: "https://example.com/hook", Secret: "secret", Events: []string{model.WebhookPostCreated}, Active: true})
+	webhookRepo.Create(ctx, &model.Webhook{OwnerID: 2, URL: "https://example.com/admin", Secret: "secret", Events: []string{model.WebhookPostDeleted}, Active: true})
+	payload := json.RawMessage(`{}`)
+	deliveryRepo.Create(ctx, &model.WebhookDelivery{WebhookID: 1, Event: model.WebhookPostCreated, Payload: payload, Status: model.WebhookDeliveryDead, Attempts: 8, NextAttemptAt: time.Now()})
+	deliveryRepo.Create(ctx, &model.WebhookDelivery{WebhookID: 1, Event: model.WebhookPostCreated, Payload: payload, Status: model.WebhookDeliveryPending, NextAttemptAt: time.Now()})
+
+	webhookService := service.NewWebhookService(webhookRepo, deliveryRepo, userRepo, &service.WebhookConfig{MaxAttempts: 8, TimeoutSeconds: 1})
+	webhookHandler := NewWebhookHandler(webhookService)
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware())
+
+	router.Get("/api/webhooks", webhookHandler.GetAll)
+	router.Post(
+		"/api/webhooks",
+		middleware.ModelBodyMiddleware[model.WebhookCreateRequest](webhookHandler.Create),
+	)
+	router.Delete("/api/webhooks/{webhookID}", webhookHandler.Delete)
+	router.Get("/api/webhooks/{webhookID}/deliveries", webhookHandler.GetDeliveries)
+	router.Post("/api/webhooks/{webhookID}/deliveries/{deliveryID}/retry", webhookHandler.RetryDelivery)
+
+	return router
+}
+
+// tests
+func TestWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       any
+		actorID    int
+		wantStatus int
+		validateFn func(*testing.T, *http.Response)
+	}{
+		{
+			name:       "Register webhook",
+			method:     http.MethodPost,
+			url:        "/api/webhooks",
+			body:       model.WebhookCreateRequest{URL: "https://example.com/rebuild", Events: []string{model.WebhookPostPublished}},
+			actorID:    1,
+			wantStatus: http.StatusCreated,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var hook model.Webhook
+				if err := json.NewDecoder(res.Body).Decode(&hook); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if hook.Secret == "" {
+					t.Fatalf("expected the generated secret to be returned once")
+				}
+			},
+		},
+		{
+			name:       "Register webhook for unpublished posts",
+			method:     http.MethodPost,
+			url:        "/api/webhooks",
+			body:       model.WebhookCreateRequest{URL: "https://example.com/rebuild", Events: []string{model.WebhookPostUnpublished}},
+			actorID:    1,
+			wantStatus: http.StatusCreated,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var hook model.Webhook
+				if err := json.NewDecoder(res.Body).Decode(&hook); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(hook.Events) != 1 || hook.Events[0] != model.WebhookPostUnpublished {
+					t.Fatalf("expected a webhook for post.unpublished, got %+v", hook.Events)
+				}
+			},
+		},
+		{
+			name:       "Register webhook with unknown event",
+			method:     http.MethodPost,
+			url:        "/api/webhooks",
+			body:       model.WebhookCreateRequest{URL: "https://example.com/rebuild", Events: []string{"post.liked"}},
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Register webhook with non http url",
+			method:     http.MethodPost,
+			url:        "/api/webhooks",
+			body:       model.WebhookCreateRequest{URL: "ftp://example.com/rebuild", Events: []string{model.WebhookPostCreated}},
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Register webhook for all users as user",
+			method:     http.MethodPost,
+			url:        "/api/webhooks",
+			body:       model.WebhookCreateRequest{URL: "https://example.com/all", Events: []string{model.WebhookPostCreated}, AllUsers: true},
+			actorID:    1,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Register webhook for all users as admin",
+			method:     http.MethodPost,
+			url:        "/api/webhooks",
+			body:       model.WebhookCreateRequest{URL: "https://example.com/all", Events: []string{model.WebhookPostCreated}, AllUsers: true},
+			actorID:    2,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "List webhooks",
+			method:     http.MethodGet,
+			url:        "/api/webhooks",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var page model.PaginatedResponse[[]model.Webhook]
+				if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(page.Data) != 1 || page.Data[0].Secret != "" {
+					t.Fatalf("expected own webhook without its secret, got %+v", page.Data)
+				}
+			},
+		},
+		{
+			name:       "Get deliveries",
+			method:     http.MethodGet,
+			url:        "/api/webhooks/1/deliveries?status=dead",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var page model.PaginatedResponse[[]model.WebhookDelivery]
+				if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if len(page.Data) != 1 || page.Data[0].ID != 1 {
+					t.Fatalf("expected the dead delivery, got %+v", page.Data)
+				}
+			},
+		},
+		{
+			name:       "Get deliveries with unknown status",
+			method:     http.MethodGet,
+			url:        "/api/webhooks/1/deliveries?status=lost",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Get deliveries of another user",
+			method:     http.MethodGet,
+			url:        "/api/webhooks/2/deliveries",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Retry dead delivery",
+			method:     http.MethodPost,
+			url:        "/api/webhooks/1/deliveries/1/retry",
+			actorID:    1,
+			wantStatus: http.StatusOK,
+			validateFn: func(t *testing.T, res *http.Response) {
+				var delivery model.WebhookDelivery
+				if err := json.NewDecoder(res.Body).Decode(&delivery); err != nil {
+					t.Fatalf("decode failed: %v", err)
+				}
+				if delivery.Status != model.WebhookDeliveryPending || delivery.Attempts != 0 {
+					t.Fatalf("expected the delivery to be requeued, got %+v", delivery)
+				}
+			},
+		},
+		{
+			name:       "Retry pending delivery",
+			method:     http.MethodPost,
+			url:        "/api/webhooks/1/deliveries/2/retry",
+			actorID:    1,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Delete webhook",
+			method:     http.MethodDelete,
+			url:        "/api/webhooks/1",
+			actorID:    1,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Delete webhook of another user",
+			method:     http.MethodDelete,
+			url:        "/api/webhooks/2",
+			actorID:    1,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Delete webhook with invalid ID",
+			method:     http.MethodDelete,
+			url:        "/api/webhooks/abc",
+			actorID:    1,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newWebhookTestRouter()
+
+			var bodyBytes []byte
+			if tt.body != nil {
+				var err error
+				bodyBytes, err = json.Marshal(tt.body)
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(bodyBytes))
+			if tt.body != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			if tt.actorID != 0 {
+				req = req.WithContext(setActorID(req.Context(), tt.actorID))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			validateStatus(t, res, tt.wantStatus)
+			validateHeaders(t, res)
+
+			if tt.validateFn != nil && res.StatusCode < http.StatusBadRequest {
+				tt.validateFn(t, res)
+			}
+		})
+	}
+}
@@ -16,6 +16,15 @@ const UserIDKey contextKey = "userID"
 const AuthorizationHeader string = "Authorization"
 const AuthHeaderPrefix string = "Bearer "
 
+// AccessTokenQueryParam carries the access token of WebSocket handshakes, browsers cannot set their headers
+const AccessTokenQueryParam string = "access_token"
+
+// SocketAuthProtocol marks the access token in the WebSocket subprotocols offered as ["bearer", "<token>"],
+// unlike the query the header does not end up in access logs
+const SocketAuthProtocol string = "bearer"
+
+const webSocketProtocolHeader string = "Sec-WebSocket-Protocol"
+
 type AuthMiddleware struct {
 	jwtManager *auth.JWTManager
 }
@@ -37,31 +46,93 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 				return
 			}
 
-			if !strings.HasPrefix(authHeader, AuthHeaderPrefix) {
-				exception.WriteApiError(w, exception.TokenInvalidError("Invalid authorization scheme"))
+			userID, apiErr := m.authenticate(authHeader)
+			if apiErr != nil {
+				exception.WriteApiError(w, apiErr)
 				return
 			}
 
-			token := strings.TrimPrefix(authHeader, AuthHeaderPrefix)
-			if token == "" {
-				exception.WriteApiError(w, exception.TokenNotProvidedError(
-					fmt.Sprintf("Failed to extract token from %s header", AuthorizationHeader)),
-				)
+			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		},
+	)
+}
+
+// OptionalAuth identifies the user when credentials are present and lets anonymous requests through
+func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get(AuthorizationHeader)
+			if authHeader == "" {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			claims, err := m.jwtManager.ValidateToken(token)
-			if err != nil {
-				if errors.Is(err, auth.ErrExpiredToken) {
-					exception.WriteApiError(w, exception.TokenExpiredError("token expired"))
-					return
-				}
-				exception.WriteApiError(w, exception.TokenInvalidError("token invalid"))
+			userID, apiErr := m.authenticate(authHeader)
+			if apiErr != nil {
+				exception.WriteApiError(w, apiErr)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		},
 	)
 }
+
+// RequireSocketAuth is RequireAuth that also takes the access token from the subprotocols or the query of a WebSocket handshake
+func (m *AuthMiddleware) RequireSocketAuth(next http.Handler) http.Handler {
+	requireAuth := m.RequireAuth(next)
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			token := socketProtocolToken(r)
+			if token == "" {
+				token = r.URL.Query().Get(AccessTokenQueryParam)
+			}
+			if r.Header.Get(AuthorizationHeader) == "" && token != "" {
+				r = r.Clone(r.Context())
+				r.Header.Set(AuthorizationHeader, AuthHeaderPrefix+token)
+			}
+			requireAuth.ServeHTTP(w, r)
+		},
+	)
+}
+
+// socketProtocolToken returns the subprotocol offered right after SocketAuthProtocol
+func socketProtocolToken(r *http.Request) string {
+	var protocols []string
+	for _, value := range r.Header.Values(webSocketProtocolHeader) {
+		for protocol := range strings.SplitSeq(value, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	for i, protocol := range protocols[:max(len(protocols)-1, 0)] {
+		if protocol == SocketAuthProtocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func (m *AuthMiddleware) authenticate(authHeader string) (int, *exception.ApiError) {
+	if !strings.HasPrefix(authHeader, AuthHeaderPrefix) {
+		return 0, exception.TokenInvalidError("Invalid authorization scheme")
+	}
+
+	token := strings.TrimPrefix(authHeader, AuthHeaderPrefix)
+	if token == "" {
+		return 0, exception.TokenNotProvidedError(
+			fmt.Sprintf("Failed to extract token from %s header", AuthorizationHeader),
+		)
+	}
+
+	claims, err := m.jwtManager.ValidateToken(token)
+	if err != nil {
+		if errors.Is(err, auth.ErrExpiredToken) {
+			return 0, exception.TokenExpiredError("token expired")
+		}
+		return 0, exception.TokenInvalidError("token invalid")
+	}
+
+	return claims.UserID, nil
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
@@ -23,6 +25,20 @@ func (w *ResponseWriterWrapper) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Hijack hands the connection over to WebSocket handlers
+func (w *ResponseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the flusher and deadlines of the wrapped writer
+func (w *ResponseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func RequestLoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
@@ -40,11 +56,21 @@ func RequestLoggerMiddleware(next http.Handler) http.Handler {
 				}
 			}
 			duration := time.Since(start)
-			log.Printf("%s %s - status: %d - duration: %s - x_ray: %s", r.Method, r.RequestURI, status, duration, xrayID)
+			log.Printf("%s %s - status: %d - duration: %s - x_ray: %s", r.Method, loggedURI(r), status, duration, xrayID)
 		},
 	)
 }
 
+// loggedURI is the request URI with the access token of WebSocket handshakes redacted
+func loggedURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has(AccessTokenQueryParam) {
+		return r.RequestURI
+	}
+	query.Set(AccessTokenQueryParam, "REDACTED")
+	return r.URL.Path + "?" + query.Encode()
+}
+
 func XRayMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
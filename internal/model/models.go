@@ -1,11 +1,19 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
 // domain
@@ -15,10 +23,29 @@ type User struct {
 	Email        string    `json:"email" gorm:"unique;not null"`
 	Password     string    `json:"password,omitempty" gorm:"-"`
 	PasswordHash string    `json:"-" gorm:"column:password_hash;not null"`
+	Role         string    `json:"role" gorm:"not null;default:user"`
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+// user roles
+const (
+	UserRoleUser      = "user"
+	UserRoleModerator = "moderator"
+	UserRoleAdmin     = "admin"
+)
+
+// CanModerate reports whether the user may moderate content of other users
+func (u *User) CanModerate() bool {
+	return u.Role == UserRoleModerator || u.Role == UserRoleAdmin
+}
+
+// UserSummary is the public part of a user embedded into other resources
+type UserSummary struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
 type RefreshToken struct {
 	Value     uuid.UUID `gorm:"type:uuid;primaryKey"`
 	UserID    int       `gorm:"index;not null"`
@@ -27,25 +54,516 @@ type RefreshToken struct {
 }
 
 type Post struct {
-	ID        int        `json:"id" db:"id" gorm:"primaryKey;autoIncrement"`
-	Title     string     `json:"title" db:"title" gorm:"not null"`
-	Content   string     `json:"content" db:"content" gorm:"not null"`
-	AuthorID  int        `json:"author_id" db:"author_id" gorm:"not null;index"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time  `json:"updated_at" db:"updated_at" gorm:"autoUpdateTime"`
-	PublishAt *time.Time `json:"publish_at,omitempty" db:"publish_at" gorm:"column:publish_at"`
-	Published bool       `json:"published" db:"published" gorm:"default:false"`
+	ID                    int            `json:"id" db:"id" gorm:"primaryKey;autoIncrement"`
+	Title                 string         `json:"title" db:"title" gorm:"not null"`
+	Content               string         `json:"content" db:"content" gorm:"not null"`
+	ContentFormat         string         `json:"content_format" db:"content_format" gorm:"not null;default:plain"`
+	ContentHTML           string         `json:"content_html" db:"content_html" gorm:"column:content_html;not null"`
+	AuthorID              int            `json:"author_id" db:"author_id" gorm:"not null;index"`
+	CreatedAt             time.Time      `json:"created_at" db:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt             time.Time      `json:"updated_at" db:"updated_at" gorm:"autoUpdateTime"`
+	PublishAt             *time.Time     `json:"publish_at,omitempty" db:"publish_at" gorm:"column:publish_at"`
+	Published             bool           `json:"published" db:"published" gorm:"default:false"`
+	Held                  bool           `json:"held" db:"held" gorm:"not null;default:false"` // waits for a moderator after the content filter
+	UnpublishAt           *time.Time     `json:"unpublish_at,omitempty" db:"unpublish_at" gorm:"column:unpublish_at"`
+	Expired               bool           `json:"expired" db:"expired" gorm:"not null;default:false"` // taken down at unpublish_at, visible to the author only
+	CommentsPremoderation bool           `json:"comments_premoderation" db:"comments_premoderation" gorm:"not null;default:false"`
+	CommentsPolicy        string         `json:"comments_policy" db:"comments_policy" gorm:"not null;default:open"`
+	CommentsMinAccountAge int            `json:"comments_min_account_age_days" db:"comments_min_account_age_days" gorm:"column:comments_min_account_age_days;not null;default:0"` // days, members policy
+	CommentsCloseAfter    *int           `json:"comments_close_after_days,omitempty" db:"comments_close_after_days" gorm:"column:comments_close_after_days"`                      // days since publication, nil falls back to COMMENTS_AUTO_CLOSE_DAYS
+	Reactions             ReactionCounts `json:"reactions" db:"reactions" gorm:"type:jsonb;not null;default:'{}'"`
+	ReactionCount         int            `json:"reaction_count" db:"reaction_count" gorm:"not null;default:0"`
+	Mentions              MentionRanges  `json:"mentions" db:"mentions" gorm:"type:jsonb;not null;default:'[]'"`
+	DeletedAt             gorm.DeletedAt `json:"deleted_at" db:"deleted_at" gorm:"index"`
+	Author                *UserSummary   `json:"author,omitempty" gorm:"-"`        // expand=author
+	CommentCount          *int           `json:"comment_count,omitempty" gorm:"-"` // expand=comment_count
 }
 
 type Comment struct {
-	ID        int       `json:"id" db:"id" gorm:"primaryKey;autoIncrement"`
-	Content   string    `json:"content" db:"content" gorm:"not null"`
-	PostID    int       `json:"post_id" db:"post_id" gorm:"not null;index"`
-	AuthorID  int       `json:"author_id" db:"author_id" gorm:"not null;index"`
-	CreatedAt time.Time `json:"created_at" db:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at" gorm:"autoUpdateTime"`
+	ID            int            `json:"id" db:"id" gorm:"primaryKey;autoIncrement"`
+	Content       string         `json:"content" db:"content" gorm:"not null"`
+	ContentFormat string         `json:"content_format" db:"content_format" gorm:"not null;default:plain"`
+	ContentHTML   string         `json:"content_html" db:"content_html" gorm:"column:content_html;not null"`
+	PostID        int            `json:"post_id" db:"post_id" gorm:"not null;index"`
+	ParentID      *int           `json:"parent_id" db:"parent_id" gorm:"index"`
+	RootID        *int           `json:"root_id" db:"root_id" gorm:"index"` // top level comment of the thread
+	Depth         int            `json:"depth" db:"depth" gorm:"not null;default:0"`
+	Status        string         `json:"status" db:"status" gorm:"not null;default:approved;index"`
+	Reactions     ReactionCounts `json:"reactions" db:"reactions" gorm:"type:jsonb;not null;default:'{}'"`
+	ReactionCount int            `json:"reaction_count" db:"reaction_count" gorm:"not null;default:0"`
+	EditCount     int            `json:"edit_count" db:"edit_count" gorm:"not null;default:0"`
+	EditedAt      *time.Time     `json:"edited_at" db:"edited_at"` // nil until the content is changed
+	Mentions      MentionRanges  `json:"mentions" db:"mentions" gorm:"type:jsonb;not null;default:'[]'"`
+	AuthorID      int            `json:"author_id" db:"author_id" gorm:"not null;index"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updated_at" db:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" db:"deleted_at" gorm:"index"`
+	Author        *UserSummary   `json:"author,omitempty" gorm:"-"`    // expand=author
+	Tombstone     bool           `json:"tombstone,omitempty" gorm:"-"` // removed comment kept in place for its replies
+	Replies       []*Comment     `json:"replies,omitempty" gorm:"-"`   // view=tree
+}
+
+// comment policies of a post
+const (
+	CommentsPolicyOpen    = "open"
+	CommentsPolicyClosed  = "closed"
+	CommentsPolicyMembers = "members" // accounts older than CommentsMinAccountAge days only
+)
+
+// PublishedAt is the moment the post went public, the creation time for posts published right away
+func (p *Post) PublishedAt() time.Time {
+	if p.PublishAt != nil && !p.PublishAt.IsZero() {
+		return *p.PublishAt
+	}
+	return p.CreatedAt
+}
+
+// comment statuses
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusRejected = "rejected"
+	CommentStatusSpam     = "spam"
+)
+
+var CommentStatuses = []string{
+	CommentStatusPending,
+	CommentStatusApproved,
+	CommentStatusRejected,
+	CommentStatusSpam,
+}
+
+// CommentRevision keeps a replaced version of a comment, Revision 1 is the original text
+type CommentRevision struct {
+	ID            int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CommentID     int       `json:"comment_id" gorm:"not null;index"`
+	Revision      int       `json:"revision" gorm:"not null"`
+	Content       string    `json:"content" gorm:"not null"`
+	ContentFormat string    `json:"content_format" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"` // when the version was replaced
+}
+
+// SpamSample is a moderated text the spam classifier learns from
+type SpamSample struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Content   string    `json:"content" gorm:"not null"`
+	Spam      bool      `json:"spam" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Reaction is the single reaction of a user to a post or a comment
+type Reaction struct {
+	ID         int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TargetType string    `json:"target_type" gorm:"not null;uniqueIndex:idx_reactions_target_user"`
+	TargetID   int       `json:"target_id" gorm:"not null;uniqueIndex:idx_reactions_target_user"`
+	UserID     int       `json:"user_id" gorm:"not null;uniqueIndex:idx_reactions_target_user"`
+	Kind       string    `json:"kind" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// reaction targets
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// reaction kinds
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionLaugh = "laugh"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+var ReactionKinds = []string{ReactionLike, ReactionLove, ReactionLaugh, ReactionWow, ReactionSad, ReactionAngry}
+
+// ReactionCounts is the denormalized number of reactions per kind, stored as jsonb on the target
+type ReactionCounts map[string]int
+
+func (c ReactionCounts) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]int(c))
+	return string(b), err
+}
+
+func (c *ReactionCounts) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*c = ReactionCounts{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported reaction counts type %T", src)
+	}
+	counts := make(map[string]int)
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+	*c = counts
+	return nil
 }
 
+func (c ReactionCounts) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]int(c))
+}
+
+// Report is a complaint of a reader about a post or a comment, one per reporter and target
+type Report struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	TargetType string     `json:"target_type" gorm:"not null;uniqueIndex:idx_reports_target_reporter"`
+	TargetID   int        `json:"target_id" gorm:"not null;uniqueIndex:idx_reports_target_reporter"`
+	ReporterID int        `json:"reporter_id" gorm:"not null;uniqueIndex:idx_reports_target_reporter"`
+	Reason     string     `json:"reason" gorm:"not null"`
+	Text       string     `json:"text" gorm:"not null;default:''"`
+	Status     string     `json:"status" gorm:"not null;default:open;index"`
+	ResolvedBy *int       `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// report targets
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+)
+
+var ReportTargets = []string{ReportTargetPost, ReportTargetComment}
+
+// report reasons
+const (
+	ReportReasonSpam       = "spam"
+	ReportReasonAbuse      = "abuse"
+	ReportReasonHarassment = "harassment"
+	ReportReasonIllegal    = "illegal"
+	ReportReasonOffTopic   = "off_topic"
+	ReportReasonOther      = "other"
+)
+
+// report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"  // the content was removed
+	ReportStatusDismissed = "dismissed" // the content was kept
+)
+
+var ReportStatuses = []string{ReportStatusOpen, ReportStatusResolved, ReportStatusDismissed}
+
+// ModerationLogEntry records a single moderation decision on a post or a comment
+type ModerationLogEntry struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ModeratorID *int      `json:"moderator_id"` // nil for automatic decisions
+	Action      string    `json:"action" gorm:"not null"`
+	TargetType  string    `json:"target_type" gorm:"not null;index:idx_moderation_log_target"`
+	TargetID    int       `json:"target_id" gorm:"not null;index:idx_moderation_log_target"`
+	ReportID    *int      `json:"report_id,omitempty"`
+	Note        string    `json:"note" gorm:"not null;default:''"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (ModerationLogEntry) TableName() string {
+	return "moderation_log"
+}
+
+// moderation actions
+const (
+	ModerationActionApprove = "approve"
+	ModerationActionReject  = "reject"
+	ModerationActionSpam    = "spam"
+	ModerationActionHide    = "hide" // automatic, after enough reports
+	ModerationActionResolve = "resolve"
+	ModerationActionDismiss = "dismiss"
+)
+
+// MentionRange links an @username of the content to the user, offsets count characters and End is exclusive
+type MentionRange struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// MentionRanges are the resolved mentions of a post or a comment, stored as jsonb next to the content
+type MentionRanges []MentionRange
+
+func (m MentionRanges) Value() (driver.Value, error) {
+	if m == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]MentionRange(m))
+	return string(b), err
+}
+
+func (m *MentionRanges) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*m = MentionRanges{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported mentions type %T", src)
+	}
+	var ranges []MentionRange
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		return err
+	}
+	*m = ranges
+	return nil
+}
+
+func (m MentionRanges) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]MentionRange(m))
+}
+
+// UserIDs lists the distinct mentioned users in order of appearance
+func (m MentionRanges) UserIDs() []int {
+	var ids []int
+	for _, r := range m {
+		if !slices.Contains(ids, r.UserID) {
+			ids = append(ids, r.UserID)
+		}
+	}
+	return ids
+}
+
+// Follow subscribes the follower to the posts of the followed user
+type Follow struct {
+	FollowerID int       `json:"follower_id" gorm:"primaryKey"`
+	FolloweeID int       `json:"followee_id" gorm:"primaryKey;index"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Webhook is an endpoint receiving signed events about the content of its owner, or of everyone when AllUsers is set
+type Webhook struct {
+	ID        int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	OwnerID   int            `json:"owner_id" gorm:"not null;index"`
+	URL       string         `json:"url" gorm:"not null"`
+	Secret    string         `json:"secret,omitempty" gorm:"not null"` // shown once on registration
+	Events    pq.StringArray `json:"events" gorm:"type:text[];not null"`
+	AllUsers  bool           `json:"all_users" gorm:"not null"`
+	Active    bool           `json:"active" gorm:"not null"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// webhook events
+const (
+	WebhookPostCreated     = "post.created"
+	WebhookPostPublished   = "post.published"
+	WebhookPostUnpublished = "post.unpublished"
+	WebhookPostDeleted     = "post.deleted"
+	WebhookCommentCreated  = "comment.created"
+)
+
+var WebhookEvents = []string{
+	WebhookPostCreated,
+	WebhookPostPublished,
+	WebhookPostUnpublished,
+	WebhookPostDeleted,
+	WebhookCommentCreated,
+}
+
+// WebhookDelivery is a queued event for a webhook and the log of its delivery attempts
+type WebhookDelivery struct {
+	ID            int             `json:"id" gorm:"primaryKey;autoIncrement"`
+	WebhookID     int             `json:"webhook_id" gorm:"not null;index"`
+	Event         string          `json:"event" gorm:"not null"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	Status        string          `json:"status" gorm:"not null;index"`
+	Attempts      int             `json:"attempts" gorm:"not null"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" gorm:"not null;index"`
+	ResponseCode  *int            `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty" gorm:"not null;default:''"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending" // waiting for the first attempt or a retry
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead" // gave up after the last attempt
+)
+
+var WebhookDeliveryStatuses = []string{WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryDead}
+
+// WebhookPayload is the signed body posted to webhooks
+type WebhookPayload struct {
+	ID        string    `json:"id"` // stays the same across retries
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Event is a domain event stored in the outbox in the transaction of the change it describes
+type Event struct {
+	ID            int64           `json:"id" gorm:"primaryKey;autoIncrement"`
+	AggregateType string          `json:"aggregate_type" gorm:"not null"`
+	AggregateID   int             `json:"aggregate_id" gorm:"not null"`
+	Type          string          `json:"type" gorm:"not null"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	Attempts      int             `json:"attempts" gorm:"not null"`
+	LastError     string          `json:"last_error,omitempty" gorm:"not null;default:''"`
+	CreatedAt     time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	DispatchedAt  *time.Time      `json:"dispatched_at,omitempty"`
+}
+
+// event aggregates, events of one aggregate are dispatched in the order they were recorded
+const (
+	AggregatePost    = "post"
+	AggregateComment = "comment"
+	AggregateUser    = "user"
+)
+
+// event types
+const (
+	EventPostCreated     = "post.created"
+	EventPostUpdated     = "post.updated"
+	EventPostPublished   = "post.published"
+	EventPostUnpublished = "post.unpublished" // taken down at unpublish_at
+	EventPostDeleted     = "post.deleted"
+	EventCommentCreated  = "comment.created"
+	EventCommentUpdated  = "comment.updated"
+	EventCommentApproved = "comment.approved" // a held comment went public, recorded along with comment.updated
+	EventCommentDeleted  = "comment.deleted"
+	EventUserRegistered  = "user.registered"
+)
+
+// Notification tells a user about something that happened to them or their content
+type Notification struct {
+	ID        int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int        `json:"user_id" gorm:"not null;index"`
+	Kind      string     `json:"kind" gorm:"not null"`
+	ActorID   *int       `json:"actor_id,omitempty"`
+	PostID    int        `json:"post_id" gorm:"not null"`
+	CommentID *int       `json:"comment_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// notification kinds
+const (
+	NotificationComment       = "comment"        // new comment on a post of the user
+	NotificationReply         = "reply"          // reply to a comment of the user
+	NotificationMention       = "mention"        // the user is mentioned in a post or a comment
+	NotificationPostPublished = "post_published" // scheduled post of the user went live
+)
+
+var NotificationKinds = []string{
+	NotificationComment,
+	NotificationReply,
+	NotificationMention,
+	NotificationPostPublished,
+}
+
+// NotificationPreferences are the kinds of notifications the user receives, a user without a row gets all of them
+type NotificationPreferences struct {
+	UserID        int       `json:"-" gorm:"primaryKey"`
+	Comment       bool      `json:"comment" gorm:"not null"`
+	Reply         bool      `json:"reply" gorm:"not null"`
+	Mention       bool      `json:"mention" gorm:"not null"`
+	PostPublished bool      `json:"post_published" gorm:"not null"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func DefaultNotificationPreferences(userID int) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:        userID,
+		Comment:       true,
+		Reply:         true,
+		Mention:       true,
+		PostPublished: true,
+	}
+}
+
+// Enabled tells whether the user wants notifications of the kind
+func (p *NotificationPreferences) Enabled(kind string) bool {
+	switch kind {
+	case NotificationComment:
+		return p.Comment
+	case NotificationReply:
+		return p.Reply
+	case NotificationMention:
+		return p.Mention
+	case NotificationPostPublished:
+		return p.PostPublished
+	default:
+		return true
+	}
+}
+
+// gateway topics, the comments of a post are GatewayTopicCommentsPrefix followed by the post ID
+const (
+	GatewayTopicNotifications  = "notifications" // notifications of the user
+	GatewayTopicPosts          = "posts"         // publication of the posts of the user
+	GatewayTopicModeration     = "moderation"    // content held for review, moderators only
+	GatewayTopicCommentsPrefix = "comments:"     // public comments of a post
+)
+
+// gateway client actions
+const (
+	GatewayActionSubscribe   = "subscribe"
+	GatewayActionUnsubscribe = "unsubscribe"
+	GatewayActionPing        = "ping"
+)
+
+// gateway message types, topics also carry the comment and post event types
+const (
+	GatewayMessageSubscribed   = "subscribed"
+	GatewayMessageUnsubscribed = "unsubscribed"
+	GatewayMessageError        = "error"
+	GatewayMessagePing         = "ping"
+	GatewayMessagePong         = "pong"
+	GatewayMessageNotification = "notification.created"
+	GatewayMessageCommentHeld  = "comment.held"
+	GatewayMessagePostHeld     = "post.held"
+)
+
+// JobRun is one execution of a background job, a scheduled occurrence is run by a single replica
+type JobRun struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Job         string     `json:"job" gorm:"not null"`
+	Trigger     string     `json:"trigger" gorm:"not null"`
+	ScheduledAt time.Time  `json:"scheduled_at" gorm:"not null"` // unique per job
+	Status      string     `json:"status" gorm:"not null"`
+	Attempts    int        `json:"attempts" gorm:"not null"`
+	LastError   string     `json:"last_error,omitempty" gorm:"not null;default:''"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// job run triggers
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual" // by an admin or by the service owning the job
+)
+
+// job run statuses
+const (
+	JobRunPending   = "pending" // waiting for a worker or for the next attempt
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed" // gave up after the last attempt
+)
+
 // requests
 type UserCreateRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
@@ -78,29 +596,101 @@ type RefreshTokenRequest struct {
 }
 
 type PostCreateRequest struct {
-	Title     string     `json:"title" validate:"required,min=1,max=200"`
-	Content   string     `json:"content" validate:"required,min=1"`
-	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Title         string     `json:"title" validate:"required,min=1,max=200"`
+	Content       string     `json:"content" validate:"required,min=1"`
+	ContentFormat string     `json:"content_format,omitempty" validate:"omitempty,oneof=plain markdown html"`
+	PublishAt     *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt   *time.Time `json:"unpublish_at,omitempty"` // after publish_at
+
+	CommentsPremoderation bool   `json:"comments_premoderation,omitempty"`
+	CommentsPolicy        string `json:"comments_policy,omitempty" validate:"omitempty,oneof=open closed members"`
+	CommentsMinAccountAge int    `json:"comments_min_account_age_days,omitempty" validate:"min=0,max=3650"`
+	CommentsCloseAfter    *int   `json:"comments_close_after_days,omitempty" validate:"omitempty,min=0,max=3650"`
 }
 
 type PostUpdateRequest struct {
-	Title     *string    `json:"title,omitempty" validate:"omitempty,max=200"`
-	Content   *string    `json:"content,omitempty" validate:"omitempty,min=1"`
-	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Title         *string    `json:"title,omitempty" validate:"omitempty,max=200"`
+	Content       *string    `json:"content,omitempty" validate:"omitempty,min=1"`
+	ContentFormat *string    `json:"content_format,omitempty" validate:"omitempty,oneof=plain markdown html"`
+	PublishAt     *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt   *time.Time `json:"unpublish_at,omitempty"` // a later one brings an expired post back
+
+	CommentsPremoderation *bool   `json:"comments_premoderation,omitempty"`
+	CommentsPolicy        *string `json:"comments_policy,omitempty" validate:"omitempty,oneof=open closed members"`
+	CommentsMinAccountAge *int    `json:"comments_min_account_age_days,omitempty" validate:"omitempty,min=0,max=3650"`
+	CommentsCloseAfter    *int    `json:"comments_close_after_days,omitempty" validate:"omitempty,min=0,max=3650"`
 }
 
 type CommentCreateRequest struct {
-	Content string `json:"content" validate:"required,min=1,max=1000"`
+	Content       string `json:"content" validate:"required,min=1,max=1000"`
+	ContentFormat string `json:"content_format,omitempty" validate:"omitempty,oneof=plain markdown html"`
+	ParentID      *int   `json:"parent_id,omitempty" validate:"omitempty,min=1"`
 }
 
 type CommentUpdateRequest struct {
-	Content string `json:"content" validate:"required,min=1,max=1000"`
+	Content       string  `json:"content" validate:"required,min=1,max=1000"`
+	ContentFormat *string `json:"content_format,omitempty" validate:"omitempty,oneof=plain markdown html"`
+}
+
+// ModerationRequest is a bulk decision on comments or posts
+type ModerationRequest struct {
+	IDs  []int `json:"ids" validate:"required,min=1,max=100,dive,min=1"`
+	Spam bool  `json:"spam,omitempty"` // reject as spam
+}
+
+type ReactionRequest struct {
+	Kind string `json:"kind" validate:"required,oneof=like love laugh wow sad angry"`
+}
+
+type ReportCreateRequest struct {
+	Reason string `json:"reason" validate:"required,oneof=spam abuse harassment illegal off_topic other"`
+	Text   string `json:"text,omitempty" validate:"max=1000"`
+}
+
+// ReportDecisionRequest closes a report, the note goes to the moderation log
+type ReportDecisionRequest struct {
+	Note string `json:"note,omitempty" validate:"max=500"`
+}
+
+type WebhookCreateRequest struct {
+	URL      string   `json:"url" validate:"required,url,max=2000"`
+	Events   []string `json:"events" validate:"required,min=1"`                     // any of WebhookEvents
+	Secret   string   `json:"secret,omitempty" validate:"omitempty,min=16,max=128"` // generated when empty
+	AllUsers bool     `json:"all_users,omitempty"`                                  // admins only
+}
+
+func (r *WebhookCreateRequest) CustomValidate() error {
+	if !strings.HasPrefix(r.URL, "https://") && !strings.HasPrefix(r.URL, "http://") {
+		return errors.New("webhook url must use http or https")
+	}
+	for _, event := range r.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("unsupported webhook event %q, allowed: %s", event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// NotificationPreferencesRequest changes the given kinds only
+type NotificationPreferencesRequest struct {
+	Comment       *bool `json:"comment,omitempty"`
+	Reply         *bool `json:"reply,omitempty"`
+	Mention       *bool `json:"mention,omitempty"`
+	PostPublished *bool `json:"post_published,omitempty"`
+}
+
+// GatewayCommand is a message sent by a gateway client
+type GatewayCommand struct {
+	Action string `json:"action" validate:"required,oneof=subscribe unsubscribe ping"`
+	Topic  string `json:"topic,omitempty" validate:"required_unless=Action ping,max=64"`
 }
 
 // GET params
 type PaginationParams struct {
-	Limit  *int `form:"limit" validate:"omitempty,min=0,max=100"`
-	Offset *int `form:"offset" validate:"omitempty,min=0"`
+	Limit     *int    `form:"limit" validate:"omitempty,min=0,max=100"`
+	Offset    *int    `form:"offset" validate:"omitempty,min=0"`
+	Cursor    *string `form:"cursor" validate:"omitempty,max=512"` // empty value requests the first cursor page
+	WithTotal *bool   `form:"with_total"`
 }
 
 func (p *PaginationParams) SetDefaults() {
@@ -112,6 +702,262 @@ func (p *PaginationParams) SetDefaults() {
 		defaultOffset := 0
 		p.Offset = &defaultOffset
 	}
+	if p.WithTotal == nil {
+		// counting defeats the purpose of keyset paging, so cursor clients opt in
+		withTotal := !p.IsCursor()
+		p.WithTotal = &withTotal
+	}
+}
+
+func (p *PaginationParams) CustomValidate() error {
+	if !p.IsCursor() {
+		return nil
+	}
+	if p.Offset != nil && *p.Offset != 0 {
+		return errors.New("cursor and offset are mutually exclusive")
+	}
+	if p.Limit != nil && *p.Limit == 0 {
+		return errors.New("cursor pagination requires a positive limit")
+	}
+	if _, err := DecodeCursor(*p.Cursor); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *PaginationParams) IsCursor() bool {
+	return p.Cursor != nil
+}
+
+// CountTotal reports whether the total number of records should be calculated
+func (p *PaginationParams) CountTotal() bool {
+	return p.WithTotal == nil || *p.WithTotal
+}
+
+// Cursor is an opaque keyset position pointing at the (created_at, id) of a boundary record
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an encoded cursor, an empty string stands for the first page
+func DecodeCursor(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// post listing sort fields
+const (
+	PostSortCreatedAt    = "created_at"
+	PostSortUpdatedAt    = "updated_at"
+	PostSortPublishAt    = "publish_at"
+	PostSortPublishedAt  = "published_at" // publish_at, the creation time for posts published right away
+	PostSortUnpublishAt  = "unpublish_at"
+	PostSortTitle        = "title"
+	PostSortCommentCount = "comment_count"
+	PostSortPopularity   = "popularity" // number of reactions
+)
+
+var PostSortFields = []string{
+	PostSortCreatedAt,
+	PostSortUpdatedAt,
+	PostSortPublishAt,
+	PostSortPublishedAt,
+	PostSortUnpublishAt,
+	PostSortTitle,
+	PostSortCommentCount,
+	PostSortPopularity,
+}
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+const PostListMaxAuthors = 50
+
+type PostListParams struct {
+	Sort        string     `form:"sort"`
+	Order       string     `form:"order"`
+	AuthorIDs   []int      `form:"author"` // comma separated
+	CreatedFrom *time.Time `form:"created_from"`
+	CreatedTo   *time.Time `form:"created_to"`
+	HasComments *bool      `form:"has_comments"`
+}
+
+func (p *PostListParams) SetDefaults() {
+	if p.Sort == "" {
+		p.Sort = PostSortCreatedAt
+	}
+	if p.Order == "" {
+		p.Order = SortDesc
+	}
+}
+
+func (p *PostListParams) CustomValidate() error {
+	if !slices.Contains(PostSortFields, p.Sort) {
+		return fmt.Errorf("unsupported sort field %q, allowed: %s", p.Sort, strings.Join(PostSortFields, ", "))
+	}
+	if p.Order != SortAsc && p.Order != SortDesc {
+		return fmt.Errorf("unsupported sort order %q, allowed: %s, %s", p.Order, SortAsc, SortDesc)
+	}
+	if len(p.AuthorIDs) > PostListMaxAuthors {
+		return fmt.Errorf("too many authors, at most %d allowed", PostListMaxAuthors)
+	}
+	for _, id := range p.AuthorIDs {
+		if id <= 0 {
+			return fmt.Errorf("invalid author ID %d", id)
+		}
+	}
+	if p.CreatedFrom != nil && p.CreatedTo != nil && p.CreatedFrom.After(*p.CreatedTo) {
+		return errors.New("created_from must not be after created_to")
+	}
+	return nil
+}
+
+// IsDefaultSort reports whether the listing uses the natural newest first order
+func (p *PostListParams) IsDefaultSort() bool {
+	return p.Sort == PostSortCreatedAt && p.Order == SortDesc
+}
+
+// comment listing views
+const (
+	CommentViewFlat = "flat"
+	CommentViewTree = "tree"
+)
+
+type CommentListParams struct {
+	View string `form:"view"`
+}
+
+func (p *CommentListParams) SetDefaults() {
+	if p.View == "" {
+		p.View = CommentViewFlat
+	}
+}
+
+func (p *CommentListParams) CustomValidate() error {
+	if p.View != CommentViewFlat && p.View != CommentViewTree {
+		return fmt.Errorf("unsupported view %q, allowed: %s, %s", p.View, CommentViewFlat, CommentViewTree)
+	}
+	return nil
+}
+
+type CommentQueueParams struct {
+	Status string `form:"status"`
+	PostID *int   `form:"post_id" validate:"omitempty,min=1"`
+}
+
+func (p *CommentQueueParams) SetDefaults() {
+	if p.Status == "" {
+		p.Status = CommentStatusPending
+	}
+}
+
+func (p *CommentQueueParams) CustomValidate() error {
+	if !slices.Contains(CommentStatuses, p.Status) {
+		return fmt.Errorf("unsupported status %q, allowed: %s", p.Status, strings.Join(CommentStatuses, ", "))
+	}
+	return nil
+}
+
+type ReportListParams struct {
+	Status     string `form:"status"`
+	TargetType string `form:"target_type"`
+}
+
+func (p *ReportListParams) SetDefaults() {
+	if p.Status == "" {
+		p.Status = ReportStatusOpen
+	}
+}
+
+func (p *ReportListParams) CustomValidate() error {
+	if !slices.Contains(ReportStatuses, p.Status) {
+		return fmt.Errorf("unsupported status %q, allowed: %s", p.Status, strings.Join(ReportStatuses, ", "))
+	}
+	if p.TargetType != "" && !slices.Contains(ReportTargets, p.TargetType) {
+		return fmt.Errorf("unsupported target type %q, allowed: %s", p.TargetType, strings.Join(ReportTargets, ", "))
+	}
+	return nil
+}
+
+type ModerationLogParams struct {
+	TargetType string `form:"target_type"`
+	TargetID   *int   `form:"target_id" validate:"omitempty,min=1"`
+}
+
+func (p *ModerationLogParams) CustomValidate() error {
+	if p.TargetType != "" && !slices.Contains(ReportTargets, p.TargetType) {
+		return fmt.Errorf("unsupported target type %q, allowed: %s", p.TargetType, strings.Join(ReportTargets, ", "))
+	}
+	if p.TargetID != nil && p.TargetType == "" {
+		return errors.New("target_id requires target_type")
+	}
+	return nil
+}
+
+// expandable relations
+const (
+	ExpandAuthor       = "author"
+	ExpandCommentCount = "comment_count"
+)
+
+var (
+	PostExpandFields    = []string{ExpandAuthor, ExpandCommentCount}
+	CommentExpandFields = []string{ExpandAuthor}
+)
+
+// ExpandParams lists the related data to embed into the response (expand=author,comment_count)
+type ExpandParams struct {
+	Fields  []string `form:"expand"`
+	Allowed []string `form:"-"`
+}
+
+func (e *ExpandParams) CustomValidate() error {
+	for _, field := range e.Fields {
+		if !slices.Contains(e.Allowed, field) {
+			return fmt.Errorf("unsupported expand field %q, allowed: %s", field, strings.Join(e.Allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func (e *ExpandParams) Has(field string) bool {
+	return e != nil && slices.Contains(e.Fields, field)
+}
+
+type WebhookDeliveryListParams struct {
+	Status string `form:"status"`
+}
+
+func (p *WebhookDeliveryListParams) CustomValidate() error {
+	if p.Status != "" && !slices.Contains(WebhookDeliveryStatuses, p.Status) {
+		return fmt.Errorf("unsupported status %q, allowed: %s", p.Status, strings.Join(WebhookDeliveryStatuses, ", "))
+	}
+	return nil
+}
+
+type NotificationListParams struct {
+	Unread bool `form:"unread"`
 }
 
 // responses
@@ -124,8 +970,71 @@ type TokenResponse struct {
 }
 
 type PaginatedResponse[T any] struct {
-	Data   T   `json:"data"`
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Total  int `json:"total"`
+	Data       T      `json:"data"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// ModerationResult reports which records a bulk moderation action changed
+type ModerationResult struct {
+	Updated []int `json:"updated"`
+	Skipped []int `json:"skipped"` // missing or not moderated by the actor
+}
+
+// FollowStats are the follow counters of a user after a follow or an unfollow
+type FollowStats struct {
+	UserID    int  `json:"user_id"`
+	Followers int  `json:"followers"`
+	Following int  `json:"following"`
+	Followed  bool `json:"followed"` // the actor follows the user
+}
+
+// CommentStreamEvent is a change of a public comment pushed to the readers of its post
+type CommentStreamEvent struct {
+	ID   int64           `json:"id"`   // ID of the outbox event, resumes the stream
+	Type string          `json:"type"` // comment.created, comment.updated or comment.deleted
+	Data json.RawMessage `json:"data"` // the comment, only its id and post_id for comment.deleted
+}
+
+// GatewayMessage is a message pushed to a gateway client
+type GatewayMessage struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// NotificationsReadResult is the number of notifications marked as read
+type NotificationsReadResult struct {
+	Updated int `json:"updated"`
+}
+
+// ReactionSummary is the state of the reactions of a target after a change
+type ReactionSummary struct {
+	TargetType    string         `json:"target_type"`
+	TargetID      int            `json:"target_id"`
+	Reactions     ReactionCounts `json:"reactions"`
+	ReactionCount int            `json:"reaction_count"`
+	Mine          string         `json:"mine,omitempty"` // reaction of the actor, empty after removal
+}
+
+// JobInfo describes a registered background job
+type JobInfo struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Schedule    string     `json:"schedule"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	Running     bool       `json:"running"` // on this replica
+	LastRun     *JobRun    `json:"last_run,omitempty"`
+}
+
+// CursorPage is a keyset paginated slice of records along with the cursors of its neighbours
+type CursorPage[T any] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+	Total      *int
 }
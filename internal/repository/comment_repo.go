@@ -16,6 +16,34 @@ var (
 	ErrCommentNotFound = errors.New("comment not found")
 )
 
+// CommentFilter defines optional filters for fetching comments.
+type CommentFilter struct {
+	IDs            []int
+	PostID         *int
+	PostAuthorID   *int // comments on posts of the given author
+	AuthorID       *int
+	Statuses       []string
+	PendingOf      *int  // with Statuses, also selects pending comments of the given author
+	TopLevel       bool  // comments without a parent only
+	RootIDs        []int // replies within the given threads
+	Trashed        bool  // selects soft deleted comments only
+	WithTombstones bool  // also selects soft deleted or hidden comments that still have replies
+	OldestFirst    bool
+}
+
+const hasRepliesCondition = "EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id)"
+
+// liveRepliesCondition selects comments with a reply matching the live condition anywhere below them,
+// so a tombstone whose replies are all deleted or hidden is left out too
+func liveRepliesCondition(live string) string {
+	return "EXISTS (WITH RECURSIVE descendants AS (" +
+		"SELECT replies.id, replies.status, replies.author_id, replies.deleted_at FROM comments AS replies " +
+		"WHERE replies.parent_id = comments.id " +
+		"UNION ALL SELECT c.id, c.status, c.author_id, c.deleted_at FROM comments AS c " +
+		"JOIN descendants AS d ON c.parent_id = d.id" +
+		") SELECT 1 FROM descendants WHERE " + live + ")"
+}
+
 type CommentRepo struct {
 	db *database.DatabaseManager
 }
@@ -44,32 +72,58 @@ func (r *CommentRepo) GetByID(ctx context.Context, id int) (*model.Comment, erro
 	return &comment, nil
 }
 
-func (r *CommentRepo) GetByPostID(ctx context.Context, postID int, limit, offset int) ([]*model.Comment, error) {
-	var comments []*model.Comment
-	err := r.db.TxDB(ctx).Where("post_id = ?", postID).Order("created_at ASC").Limit(limit).Offset(offset).Find(&comments).Error
+func (r *CommentRepo) GetComment(ctx context.Context, id int, filter *CommentFilter) (*model.Comment, error) {
+	var comment model.Comment
+	err := r.applyFilters(r.db.TxDB(ctx), filter).Where("id = ?", id).First(&comment).Error
 	if err != nil {
-		return nil, fmt.Errorf("failed to get comments for post %d: %w", postID, err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return &comment, nil
+}
+
+func (r *CommentRepo) GetComments(ctx context.Context, filter *CommentFilter, limit, offset int) ([]*model.Comment, error) {
+	var comments []*model.Comment
+	direction := "DESC"
+	if filter != nil && filter.OldestFirst {
+		direction = "ASC"
+	}
+	db := r.applyFilters(r.db.TxDB(ctx), filter).Order("created_at " + direction).Order("id " + direction)
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
 	return comments, nil
 }
 
-func (r *CommentRepo) GetCountByPostID(ctx context.Context, postID int) (int, error) {
-	var count int64
-	err := r.db.TxDB(ctx).Model(&model.Comment{}).Where("post_id = ?", postID).Count(&count).Error
-	if err != nil {
-		return 0, fmt.Errorf("failed to count comments for post %d: %w", postID, err)
+// GetCommentsByCursor returns up to limit comments past the cursor, oldest first
+func (r *CommentRepo) GetCommentsByCursor(
+	ctx context.Context,
+	filter *CommentFilter,
+	cursor *model.Cursor,
+	limit int,
+) ([]*model.Comment, error) {
+	var comments []*model.Comment
+	db := applyCursor(r.applyFilters(r.db.TxDB(ctx), filter), cursor, false, limit)
+	if err := db.Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get comments by cursor: %w", err)
 	}
-	return int(count), nil
+	restoreCursorOrder(comments, cursor)
+	return comments, nil
 }
 
-func (r *CommentRepo) GetCountByAuthorID(ctx context.Context, authorID int) (int, error) {
+func (r *CommentRepo) GetCommentsCount(ctx context.Context, filter *CommentFilter) (int, error) {
 	var count int64
-	err := r.db.TxDB(ctx).
-		Model(&model.Comment{}).
-		Where("author_id = ?", authorID).
-		Count(&count).Error
+	err := r.applyFilters(r.db.TxDB(ctx), filter).Model(&model.Comment{}).Count(&count).Error
 	if err != nil {
-		return 0, fmt.Errorf("failed to count comments by author ID %d: %w", authorID, err)
+		return 0, fmt.Errorf("failed to count comments: %w", err)
 	}
 	return int(count), nil
 }
@@ -79,8 +133,14 @@ func (r *CommentRepo) Update(ctx context.Context, comment *model.Comment) error
 
 	result := r.db.TxDB(ctx).Model(&model.Comment{}).Where("id = ?", comment.ID).Updates(
 		map[string]interface{}{
-			"content":    comment.Content,
-			"updated_at": comment.UpdatedAt,
+			"content":        comment.Content,
+			"content_format": comment.ContentFormat,
+			"content_html":   comment.ContentHTML,
+			"mentions":       comment.Mentions,
+			"status":         comment.Status,
+			"edit_count":     comment.EditCount,
+			"edited_at":      comment.EditedAt,
+			"updated_at":     comment.UpdatedAt,
 		},
 	)
 	if result.Error != nil {
@@ -102,3 +162,126 @@ func (r *CommentRepo) Delete(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+func (r *CommentRepo) Restore(ctx context.Context, id int) error {
+	result := r.db.TxDB(ctx).
+		Unscoped().
+		Model(&model.Comment{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return fmt.Errorf("failed to restore comment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+/*
+Purge permanently removes comments trashed before the given moment.
+Comments that still have replies stay as tombstones, a dead branch shrinks leaf by leaf on subsequent runs.
+*/
+func (r *CommentRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	result := r.db.TxDB(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("NOT " + hasRepliesCondition).
+		Delete(&model.Comment{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge comments: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// LoadAuthors fills in the author summaries of the given comments with a single query
+func (r *CommentRepo) LoadAuthors(ctx context.Context, comments []*model.Comment) error {
+	ids := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.AuthorID)
+	}
+
+	authors, err := userSummaries(r.db.TxDB(ctx), ids)
+	if err != nil {
+		return fmt.Errorf("failed to load comment authors: %w", err)
+	}
+
+	for _, comment := range comments {
+		comment.Author = authors[comment.AuthorID]
+	}
+	return nil
+}
+
+// SetStatus moves the given comments into the moderation status
+func (r *CommentRepo) SetStatus(ctx context.Context, ids []int, status string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.TxDB(ctx).
+		Model(&model.Comment{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"status":     status,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to set comments status: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// statusCondition builds the moderation status clause of the filter
+func statusCondition(filter *CommentFilter) (string, []any) {
+	if len(filter.Statuses) == 0 {
+		return "", nil
+	}
+	if filter.PendingOf == nil {
+		return "status IN ?", []any{filter.Statuses}
+	}
+	return "(status IN ? OR (status = ? AND author_id = ?))",
+		[]any{filter.Statuses, model.CommentStatusPending, *filter.PendingOf}
+}
+
+func (r *CommentRepo) applyFilters(db *gorm.DB, filter *CommentFilter) *gorm.DB {
+	if filter == nil {
+		return db
+	}
+
+	visibility, args := statusCondition(filter)
+
+	switch {
+	case filter.Trashed:
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+		if visibility != "" {
+			db = db.Where(visibility, args...)
+		}
+	case filter.WithTombstones:
+		live := "deleted_at IS NULL"
+		if visibility != "" {
+			live += " AND " + visibility
+		}
+		db = db.Unscoped().Where("(("+live+") OR "+liveRepliesCondition(live)+")", append(args, args...)...)
+	case visibility != "":
+		db = db.Where(visibility, args...)
+	}
+
+	if len(filter.IDs) > 0 {
+		db = db.Where("id IN ?", filter.IDs)
+	}
+	if filter.PostID != nil {
+		db = db.Where("post_id = ?", *filter.PostID)
+	}
+	if filter.PostAuthorID != nil {
+		db = db.Where("post_id IN (SELECT id FROM posts WHERE author_id = ?)", *filter.PostAuthorID)
+	}
+	if filter.AuthorID != nil {
+		db = db.Where("author_id = ?", *filter.AuthorID)
+	}
+	if filter.TopLevel {
+		db = db.Where("parent_id IS NULL")
+	}
+	if len(filter.RootIDs) > 0 {
+		db = db.Where("root_id IN ?", filter.RootIDs)
+	}
+	return db
+}
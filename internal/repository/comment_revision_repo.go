@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+type CommentRevisionRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewCommentRevisionRepo(db *database.DatabaseManager) *CommentRevisionRepo {
+	return &CommentRevisionRepo{db: db}
+}
+
+func (r *CommentRevisionRepo) Create(ctx context.Context, revision *model.CommentRevision) error {
+	if err := r.db.TxDB(ctx).Create(revision).Error; err != nil {
+		return fmt.Errorf("failed to create comment revision: %w", err)
+	}
+	return nil
+}
+
+// GetByCommentID returns the previous versions of the comment, newest first
+func (r *CommentRevisionRepo) GetByCommentID(ctx context.Context, commentID int, limit, offset int) ([]*model.CommentRevision, error) {
+	var revisions []*model.CommentRevision
+	db := r.db.TxDB(ctx).Where("comment_id = ?", commentID).Order("revision DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get revisions of comment %d: %w", commentID, err)
+	}
+	return revisions, nil
+}
+
+func (r *CommentRevisionRepo) GetCountByCommentID(ctx context.Context, commentID int) (int, error) {
+	var count int64
+	err := r.db.TxDB(ctx).Model(&model.CommentRevision{}).Where("comment_id = ?", commentID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count revisions of comment %d: %w", commentID, err)
+	}
+	return int(count), nil
+}
+
+func (r *CommentRevisionRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.WithinTransaction(ctx, fn)
+}
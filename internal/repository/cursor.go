@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"blog-api/internal/model"
+)
+
+// scanDescending reports the direction rows have to be read in to reach the requested page
+func scanDescending(cursor *model.Cursor, desc bool) bool {
+	if cursor != nil && cursor.Backward {
+		return !desc
+	}
+	return desc
+}
+
+// applyCursor narrows the query to the rows past the cursor in (created_at, id) order
+func applyCursor(db *gorm.DB, cursor *model.Cursor, desc bool, limit int) *gorm.DB {
+	scanDesc := scanDescending(cursor, desc)
+
+	direction, op := "ASC", ">"
+	if scanDesc {
+		direction, op = "DESC", "<"
+	}
+
+	if cursor != nil {
+		db = db.Where(fmt.Sprintf("(created_at, id) %s (?, ?)", op), cursor.CreatedAt, cursor.ID)
+	}
+
+	return db.Order("created_at " + direction).Order("id " + direction).Limit(limit)
+}
+
+// restoreCursorOrder puts rows read backward into the listing order
+func restoreCursorOrder[T any](items []T, cursor *model.Cursor) {
+	if cursor != nil && cursor.Backward {
+		slices.Reverse(items)
+	}
+}
+
+// pageByCursor is the in-memory counterpart of applyCursor
+func pageByCursor[T any](
+	items []T,
+	key func(T) (time.Time, int),
+	cursor *model.Cursor,
+	desc bool,
+	limit int,
+) []T {
+	scanDesc := scanDescending(cursor, desc)
+
+	less := func(a, b T) int {
+		at, aid := key(a)
+		bt, bid := key(b)
+		if c := at.Compare(bt); c != 0 {
+			return c
+		}
+		return aid - bid
+	}
+
+	slices.SortFunc(items, func(a, b T) int {
+		if scanDesc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+
+	var result []T
+	for _, item := range items {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		if cursor != nil {
+			t, id := key(item)
+			c := t.Compare(cursor.CreatedAt)
+			if c == 0 {
+				c = id - cursor.ID
+			}
+			if (scanDesc && c >= 0) || (!scanDesc && c <= 0) {
+				continue
+			}
+		}
+		result = append(result, item)
+	}
+
+	restoreCursorOrder(result, cursor)
+	return result
+}
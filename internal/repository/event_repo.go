@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+// EventFilter selects recorded events, PostID matches the comment events of a post
+type EventFilter struct {
+	AfterID       int64
+	AggregateType string
+	PostID        *int
+}
+
+type EventRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewEventRepo(db *database.DatabaseManager) *EventRepo {
+	return &EventRepo{db: db}
+}
+
+// Create appends the event to the outbox, inside a transaction it is committed together with the change
+func (r *EventRepo) Create(ctx context.Context, event *model.Event) error {
+	if err := r.db.TxDB(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+	return nil
+}
+
+/*
+Lock takes the relay lock for the current transaction and reports whether it was free.
+Only one relay across all replicas reads the outbox at a time, which keeps the events
+of every aggregate in order; the lock is released on commit or rollback.
+*/
+func (r *EventRepo) Lock(ctx context.Context) (bool, error) {
+	var locked bool
+	if err := r.db.TxDB(ctx).Raw("SELECT pg_try_advisory_xact_lock(hashtext('events_relay'))").Scan(&locked).Error; err != nil {
+		return false, fmt.Errorf("failed to lock events relay: %w", err)
+	}
+	return locked, nil
+}
+
+// GetPending returns the oldest events that were not dispatched yet, in the order they were recorded
+func (r *EventRepo) GetPending(ctx context.Context, limit int) ([]*model.Event, error) {
+	var events []*model.Event
+	err := r.db.TxDB(ctx).
+		Where("dispatched_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending events: %w", err)
+	}
+	return events, nil
+}
+
+// GetEvents returns the matching events recorded after filter.AfterID, in the order they were recorded
+func (r *EventRepo) GetEvents(ctx context.Context, filter *EventFilter, limit int) ([]*model.Event, error) {
+	var events []*model.Event
+	db := r.db.TxDB(ctx).Where("id > ?", filter.AfterID).Order("id")
+	if filter.AggregateType != "" {
+		db = db.Where("aggregate_type = ?", filter.AggregateType)
+	}
+	if filter.PostID != nil {
+		db = db.Where("(payload->>'post_id')::int = ?", *filter.PostID)
+	}
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if err := db.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return events, nil
+}
+
+// GetLatest returns the most recently recorded event of the aggregate type
+func (r *EventRepo) GetLatest(ctx context.Context, aggregateType string) (*model.Event, error) {
+	var event model.Event
+	err := r.db.TxDB(ctx).Where("aggregate_type = ?", aggregateType).Order("id DESC").First(&event).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest event: %w", err)
+	}
+	return &event, nil
+}
+
+func (r *EventRepo) MarkDispatched(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.db.TxDB(ctx).Model(&model.Event{}).Where("id IN ?", ids).Update("dispatched_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark events as dispatched: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed counts a failed dispatch of the event, a non nil giveUpAt stops further attempts
+func (r *EventRepo) MarkFailed(ctx context.Context, id int64, lastError string, giveUpAt *time.Time) error {
+	err := r.db.TxDB(ctx).Model(&model.Event{}).Where("id = ?", id).Updates(map[string]any{
+		"attempts":      gorm.Expr("attempts + 1"),
+		"last_error":    lastError,
+		"dispatched_at": giveUpAt,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark event as failed: %w", err)
+	}
+	return nil
+}
+
+func (r *EventRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.WithinTransaction(ctx, fn)
+}
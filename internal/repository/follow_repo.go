@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+type FollowRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewFollowRepo(db *database.DatabaseManager) *FollowRepo {
+	return &FollowRepo{db: db}
+}
+
+// Create stores the follow and reports whether it is new
+func (r *FollowRepo) Create(ctx context.Context, follow *model.Follow) (bool, error) {
+	result := r.db.TxDB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(follow)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create follow: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Delete removes the follow and reports whether it existed
+func (r *FollowRepo) Delete(ctx context.Context, followerID, followeeID int) (bool, error) {
+	result := r.db.TxDB(ctx).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&model.Follow{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete follow: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *FollowRepo) Exists(ctx context.Context, followerID, followeeID int) (bool, error) {
+	var count int64
+	err := r.db.TxDB(ctx).Model(&model.Follow{}).
+		Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetFollowers returns the users following the user, latest follows first
+func (r *FollowRepo) GetFollowers(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error) {
+	return r.getUsers(ctx, "follower_id", "followee_id", userID, limit, offset)
+}
+
+// GetFollowing returns the users the user follows, latest follows first
+func (r *FollowRepo) GetFollowing(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error) {
+	return r.getUsers(ctx, "followee_id", "follower_id", userID, limit, offset)
+}
+
+func (r *FollowRepo) GetFollowersCount(ctx context.Context, userID int) (int, error) {
+	return r.count(ctx, "followee_id", userID)
+}
+
+func (r *FollowRepo) GetFollowingCount(ctx context.Context, userID int) (int, error) {
+	return r.count(ctx, "follower_id", userID)
+}
+
+// GetFolloweeIDs returns the IDs of every user the user follows
+func (r *FollowRepo) GetFolloweeIDs(ctx context.Context, userID int) ([]int, error) {
+	var ids []int
+	err := r.db.TxDB(ctx).Model(&model.Follow{}).
+		Where("follower_id = ?", userID).
+		Pluck("followee_id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followees: %w", err)
+	}
+	return ids, nil
+}
+
+// getUsers lists the users in the column of the follows matching the user in by
+func (r *FollowRepo) getUsers(ctx context.Context, column, by string, userID int, limit, offset int) ([]*model.UserSummary, error) {
+	var users []*model.UserSummary
+	db := r.db.TxDB(ctx).Model(&model.Follow{}).
+		Select("users.id, users.username").
+		Joins("JOIN users ON users.id = follows."+column).
+		Where("follows."+by+" = ?", userID).
+		Order("follows.created_at DESC").
+		Order("users.id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Scan(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get follows: %w", err)
+	}
+	return users, nil
+}
+
+func (r *FollowRepo) count(ctx context.Context, column string, userID int) (int, error) {
+	var count int64
+	err := r.db.TxDB(ctx).Model(&model.Follow{}).Where(column+" = ?", userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count follows: %w", err)
+	}
+	return int(count), nil
+}
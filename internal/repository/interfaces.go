@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 
@@ -21,6 +22,7 @@ type RefreshTokenRepository interface {
 	GetByValue(ctx context.Context, value uuid.UUID) (*model.RefreshToken, error)
 	DeleteByValue(ctx context.Context, value uuid.UUID) error
 	DeleteByUserID(ctx context.Context, userID int) error
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
 	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
@@ -28,17 +30,126 @@ type PostRepository interface {
 	Create(ctx context.Context, post *model.Post) error
 	GetPost(ctx context.Context, id int, filter *PostFilter) (*model.Post, error)
 	GetPosts(ctx context.Context, filter *PostFilter, limit, offset int) ([]*model.Post, error)
+	GetPostsByCursor(ctx context.Context, filter *PostFilter, cursor *model.Cursor, limit int) ([]*model.Post, error)
 	GetPostsCount(ctx context.Context, filter *PostFilter) (int, error)
 	Update(ctx context.Context, post *model.Post) error
 	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int, error)
+	PublishDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error)
+	ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error)
+	LoadAuthors(ctx context.Context, posts []*model.Post) error
+	LoadCommentCounts(ctx context.Context, posts []*model.Post) error
 }
 
 type CommentRepository interface {
 	Create(ctx context.Context, comment *model.Comment) error
 	GetByID(ctx context.Context, id int) (*model.Comment, error)
-	GetByPostID(ctx context.Context, postID int, limit, offset int) ([]*model.Comment, error)
-	GetCountByPostID(ctx context.Context, postID int) (int, error)
-	GetCountByAuthorID(ctx context.Context, authorID int) (int, error)
+	GetComment(ctx context.Context, id int, filter *CommentFilter) (*model.Comment, error)
+	GetComments(ctx context.Context, filter *CommentFilter, limit, offset int) ([]*model.Comment, error)
+	GetCommentsByCursor(ctx context.Context, filter *CommentFilter, cursor *model.Cursor, limit int) ([]*model.Comment, error)
+	GetCommentsCount(ctx context.Context, filter *CommentFilter) (int, error)
 	Update(ctx context.Context, comment *model.Comment) error
 	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, before time.Time) (int, error)
+	LoadAuthors(ctx context.Context, comments []*model.Comment) error
+	SetStatus(ctx context.Context, ids []int, status string) (int, error)
+}
+
+type CommentRevisionRepository interface {
+	Create(ctx context.Context, revision *model.CommentRevision) error
+	GetByCommentID(ctx context.Context, commentID int, limit, offset int) ([]*model.CommentRevision, error)
+	GetCountByCommentID(ctx context.Context, commentID int) (int, error)
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type SpamSampleRepository interface {
+	Create(ctx context.Context, sample *model.SpamSample) error
+	GetLatest(ctx context.Context, limit int) ([]*model.SpamSample, error)
+}
+
+type ReactionRepository interface {
+	Set(ctx context.Context, reaction *model.Reaction) (model.ReactionCounts, error)
+	Delete(ctx context.Context, targetType string, targetID, userID int) (model.ReactionCounts, error)
+	DeleteOrphaned(ctx context.Context) (int, error)
+}
+
+type ReportRepository interface {
+	Create(ctx context.Context, report *model.Report) error
+	GetByID(ctx context.Context, id int) (*model.Report, error)
+	GetReports(ctx context.Context, filter *ReportFilter, limit, offset int) ([]*model.Report, error)
+	GetReportsCount(ctx context.Context, filter *ReportFilter) (int, error)
+	Close(ctx context.Context, targetType string, targetID int, status string, moderatorID int) (int, error)
+	DeleteOrphaned(ctx context.Context) (int, error)
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type ModerationLogRepository interface {
+	Create(ctx context.Context, entry *model.ModerationLogEntry) error
+	GetEntries(ctx context.Context, filter *ModerationLogFilter, limit, offset int) ([]*model.ModerationLogEntry, error)
+	GetEntriesCount(ctx context.Context, filter *ModerationLogFilter) (int, error)
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *model.Notification) error
+	GetByID(ctx context.Context, id int) (*model.Notification, error)
+	GetNotifications(ctx context.Context, filter *NotificationFilter, limit, offset int) ([]*model.Notification, error)
+	GetNotificationsCount(ctx context.Context, filter *NotificationFilter) (int, error)
+	MarkRead(ctx context.Context, userID int, ids []int, at time.Time) (int, error)
+}
+
+type NotificationPreferenceRepository interface {
+	Get(ctx context.Context, userID int) (*model.NotificationPreferences, error)
+	Save(ctx context.Context, preferences *model.NotificationPreferences) error
+}
+
+type FollowRepository interface {
+	Create(ctx context.Context, follow *model.Follow) (bool, error)
+	Delete(ctx context.Context, followerID, followeeID int) (bool, error)
+	Exists(ctx context.Context, followerID, followeeID int) (bool, error)
+	GetFollowers(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error)
+	GetFollowing(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error)
+	GetFollowersCount(ctx context.Context, userID int) (int, error)
+	GetFollowingCount(ctx context.Context, userID int) (int, error)
+	GetFolloweeIDs(ctx context.Context, userID int) ([]int, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	GetByID(ctx context.Context, id int) (*model.Webhook, error)
+	GetByOwnerID(ctx context.Context, ownerID int, limit, offset int) ([]*model.Webhook, error)
+	GetCountByOwnerID(ctx context.Context, ownerID int) (int, error)
+	GetSubscribed(ctx context.Context, event string, ownerID int) ([]*model.Webhook, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetByID(ctx context.Context, id int) (*model.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, filter *WebhookDeliveryFilter, limit, offset int) ([]*model.WebhookDelivery, error)
+	GetDeliveriesCount(ctx context.Context, filter *WebhookDeliveryFilter) (int, error)
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error)
+	Update(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+type EventRepository interface {
+	Create(ctx context.Context, event *model.Event) error
+	Lock(ctx context.Context) (bool, error)
+	GetPending(ctx context.Context, limit int) ([]*model.Event, error)
+	GetEvents(ctx context.Context, filter *EventFilter, limit int) ([]*model.Event, error)
+	GetLatest(ctx context.Context, aggregateType string) (*model.Event, error)
+	MarkDispatched(ctx context.Context, ids []int64, at time.Time) error
+	MarkFailed(ctx context.Context, id int64, lastError string, giveUpAt *time.Time) error
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type JobRunRepository interface {
+	Claim(ctx context.Context, run *model.JobRun) (bool, error)
+	Update(ctx context.Context, run *model.JobRun) error
+	GetRuns(ctx context.Context, job string, limit, offset int) ([]*model.JobRun, error)
+	GetRunsCount(ctx context.Context, job string) (int, error)
+	GetLastRuns(ctx context.Context) (map[string]*model.JobRun, error)
+	Abandon(ctx context.Context, before, at time.Time, reason string) (int, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
 }
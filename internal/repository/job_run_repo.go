@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var ErrJobRunNotFound = errors.New("job run not found")
+
+type JobRunRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewJobRunRepo(db *database.DatabaseManager) *JobRunRepo {
+	return &JobRunRepo{db: db}
+}
+
+// Claim records the run unless another replica has already taken the same occurrence of the job
+func (r *JobRunRepo) Claim(ctx context.Context, run *model.JobRun) (bool, error) {
+	result := r.db.TxDB(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "job"}, {Name: "scheduled_at"}}, DoNothing: true}).
+		Create(run)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim job run: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *JobRunRepo) Update(ctx context.Context, run *model.JobRun) error {
+	result := r.db.TxDB(ctx).Model(run).
+		Select("status", "attempts", "last_error", "started_at", "finished_at").
+		Updates(run)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update job run: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobRunNotFound
+	}
+	return nil
+}
+
+// GetRuns returns the runs of the job, newest first
+func (r *JobRunRepo) GetRuns(ctx context.Context, job string, limit, offset int) ([]*model.JobRun, error) {
+	var runs []*model.JobRun
+	db := r.db.TxDB(ctx).Where("job = ?", job).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get job runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *JobRunRepo) GetRunsCount(ctx context.Context, job string) (int, error) {
+	var count int64
+	if err := r.db.TxDB(ctx).Model(&model.JobRun{}).Where("job = ?", job).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count job runs: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetLastRuns returns the latest run of every job that has run
+func (r *JobRunRepo) GetLastRuns(ctx context.Context) (map[string]*model.JobRun, error) {
+	var runs []*model.JobRun
+	err := r.db.TxDB(ctx).Raw("SELECT DISTINCT ON (job) * FROM job_runs ORDER BY job, id DESC").Scan(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last job runs: %w", err)
+	}
+	last := make(map[string]*model.JobRun, len(runs))
+	for _, run := range runs {
+		last[run.Job] = run
+	}
+	return last, nil
+}
+
+/*
+Abandon fails the pending and running runs that have not started or been scheduled since before,
+they were left behind by a replica that stopped and nothing is going to finish them.
+*/
+func (r *JobRunRepo) Abandon(ctx context.Context, before, at time.Time, reason string) (int, error) {
+	result := r.db.TxDB(ctx).Model(&model.JobRun{}).
+		Where("status IN ? AND COALESCE(started_at, scheduled_at) < ?",
+			[]string{model.JobRunPending, model.JobRunRunning}, before).
+		Updates(map[string]any{"status": model.JobRunFailed, "last_error": reason, "finished_at": at})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to abandon job runs: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// Purge deletes the runs finished before the given moment
+func (r *JobRunRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	result := r.db.TxDB(ctx).Where("finished_at < ?", before).Delete(&model.JobRun{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge job runs: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
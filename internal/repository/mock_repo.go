@@ -2,13 +2,17 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
 
 	"blog-api/internal/model"
 )
@@ -41,6 +45,9 @@ func (r *InMemoryUserRepo) Create(ctx context.Context, user *model.User) error {
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
+	if user.Role == "" {
+		user.Role = model.UserRoleUser
+	}
 
 	r.users[user.ID] = user
 	return nil
@@ -208,15 +215,43 @@ func (r *InMemoryRefreshTokenRepo) DeleteByUserID(ctx context.Context, userID in
 	return nil
 }
 
+func (r *InMemoryRefreshTokenRepo) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for key, token := range r.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.tokens, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
 func (r *InMemoryRefreshTokenRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	return fn(ctx)
 }
 
+func userSummary(users *InMemoryUserRepo, id int) *model.UserSummary {
+	if users == nil {
+		return nil
+	}
+	u, err := users.GetByID(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	return &model.UserSummary{ID: u.ID, Username: u.Username}
+}
+
 // post
 type InMemoryPostRepo struct {
-	mu    sync.RWMutex
-	seq   int
-	posts map[int]*model.Post
+	mu       sync.RWMutex
+	seq      int
+	posts    map[int]*model.Post
+	comments *InMemoryCommentRepo
+	users    *InMemoryUserRepo
 }
 
 func NewInMemoryPostRepo() *InMemoryPostRepo {
@@ -225,6 +260,50 @@ func NewInMemoryPostRepo() *InMemoryPostRepo {
 	}
 }
 
+// LinkComments lets comment based filters and sorting see the given comments
+func (r *InMemoryPostRepo) LinkComments(comments *InMemoryCommentRepo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.comments = comments
+}
+
+// LinkUsers lets author expansion see the given users
+func (r *InMemoryPostRepo) LinkUsers(users *InMemoryUserRepo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = users
+}
+
+func (r *InMemoryPostRepo) LoadAuthors(ctx context.Context, posts []*model.Post) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, post := range posts {
+		post.Author = userSummary(r.users, post.AuthorID)
+	}
+	return nil
+}
+
+func (r *InMemoryPostRepo) LoadCommentCounts(ctx context.Context, posts []*model.Post) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, post := range posts {
+		count := r.commentCount(post.ID)
+		post.CommentCount = &count
+	}
+	return nil
+}
+
+func (r *InMemoryPostRepo) commentCount(postID int) int {
+	if r.comments == nil {
+		return 0
+	}
+	count, _ := r.comments.GetCommentsCount(context.Background(), &CommentFilter{
+		PostID:   &postID,
+		Statuses: []string{model.CommentStatusApproved},
+	})
+	return count
+}
+
 func (r *InMemoryPostRepo) Create(ctx context.Context, post *model.Post) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -233,6 +312,9 @@ func (r *InMemoryPostRepo) Create(ctx context.Context, post *model.Post) error {
 	now := time.Now()
 	post.CreatedAt = now
 	post.UpdatedAt = now
+	if post.CommentsPolicy == "" {
+		post.CommentsPolicy = model.CommentsPolicyOpen
+	}
 	r.posts[post.ID] = post
 	return nil
 }
@@ -241,22 +323,10 @@ func (r *InMemoryPostRepo) GetPost(ctx context.Context, id int, filter *PostFilt
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	post, ok := r.posts[id]
-	if !ok {
+	if !ok || !r.matchesFilter(post, filter) {
 		return nil, ErrPostNotFound
 	}
 
-	if filter != nil {
-		if filter.AuthorID != nil && post.AuthorID != *filter.AuthorID {
-			return nil, ErrPostNotFound
-		}
-		if filter.Published != nil && post.Published != *filter.Published {
-			return nil, ErrPostNotFound
-		}
-		if filter.DueBefore != nil && post.PublishAt != nil && post.PublishAt.After(*filter.DueBefore) {
-			return nil, ErrPostNotFound
-		}
-	}
-
 	copy := *post
 	return &copy, nil
 }
@@ -266,23 +336,12 @@ func (r *InMemoryPostRepo) GetPosts(ctx context.Context, filter *PostFilter, lim
 	defer r.mu.RUnlock()
 	var result []*model.Post
 	for _, post := range r.posts {
-		if filter != nil {
-			if filter.AuthorID != nil && post.AuthorID != *filter.AuthorID {
-				continue
-			}
-			if filter.Published != nil && post.Published != *filter.Published {
-				continue
-			}
-			if filter.DueBefore != nil && post.PublishAt != nil && post.PublishAt.After(*filter.DueBefore) {
-				continue
-			}
+		if r.matchesFilter(post, filter) {
+			result = append(result, post)
 		}
-		result = append(result, post)
 	}
 
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].CreatedAt.After(result[j].CreatedAt)
-	})
+	r.sortPosts(result, filter)
 
 	if offset >= len(result) {
 		return []*model.Post{}, nil
@@ -302,23 +361,27 @@ func (r *InMemoryPostRepo) GetPosts(ctx context.Context, filter *PostFilter, lim
 	return copied, nil
 }
 
+func (r *InMemoryPostRepo) GetPostsByCursor(ctx context.Context, filter *PostFilter, cursor *model.Cursor, limit int) ([]*model.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matched []*model.Post
+	for _, post := range r.posts {
+		if r.matchesFilter(post, filter) {
+			p := *post
+			matched = append(matched, &p)
+		}
+	}
+	return pageByCursor(matched, postCursorKey, cursor, true, limit), nil
+}
+
 func (r *InMemoryPostRepo) GetPostsCount(ctx context.Context, filter *PostFilter) (int, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	count := 0
 	for _, post := range r.posts {
-		if filter != nil {
-			if filter.AuthorID != nil && post.AuthorID != *filter.AuthorID {
-				continue
-			}
-			if filter.Published != nil && post.Published != *filter.Published {
-				continue
-			}
-			if filter.DueBefore != nil && post.PublishAt != nil && post.PublishAt.After(*filter.DueBefore) {
-				continue
-			}
+		if r.matchesFilter(post, filter) {
+			count++
 		}
-		count++
 	}
 	return count, nil
 }
@@ -327,7 +390,7 @@ func (r *InMemoryPostRepo) Update(ctx context.Context, post *model.Post) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	existing, ok := r.posts[post.ID]
-	if !ok {
+	if !ok || existing.DeletedAt.Valid {
 		return ErrPostNotFound
 	}
 	post.CreatedAt = existing.CreatedAt
@@ -339,18 +402,220 @@ func (r *InMemoryPostRepo) Update(ctx context.Context, post *model.Post) error {
 func (r *InMemoryPostRepo) Delete(ctx context.Context, id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.posts[id]; !ok {
+	post, ok := r.posts[id]
+	if !ok || post.DeletedAt.Valid {
+		return ErrPostNotFound
+	}
+	post.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *InMemoryPostRepo) Restore(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post, ok := r.posts[id]
+	if !ok || !post.DeletedAt.Valid {
 		return ErrPostNotFound
 	}
-	delete(r.posts, id)
+	post.DeletedAt = gorm.DeletedAt{}
 	return nil
 }
 
+func (r *InMemoryPostRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	purged := 0
+	for id, post := range r.posts {
+		if post.DeletedAt.Valid && post.DeletedAt.Time.Before(before) {
+			delete(r.posts, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// PublishDue publishes the due posts under the repo lock, concurrent callers never get the same post
+func (r *InMemoryPostRepo) PublishDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*model.Post
+	for _, post := range r.posts {
+		if post.Published || post.Held || post.Expired || post.DeletedAt.Valid {
+			continue
+		}
+		if post.PublishAt != nil && !post.PublishAt.After(now) && (post.UnpublishAt == nil || post.UnpublishAt.After(now)) {
+			due = append(due, post)
+		}
+	}
+	slices.SortFunc(due, func(a, b *model.Post) int {
+		if c := a.PublishAt.Compare(*b.PublishAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	res := make([]*model.Post, 0, len(due))
+	for _, post := range due {
+		published := *post
+		published.Published = true
+		published.UpdatedAt = now
+		r.posts[post.ID] = &published
+		cp := published
+		res = append(res, &cp)
+	}
+	return res, nil
+}
+
+// ExpireDue takes down the posts whose unpublish_at has come under the repo lock
+func (r *InMemoryPostRepo) ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*model.Post
+	for _, post := range r.posts {
+		if !post.Expired && !post.DeletedAt.Valid && post.UnpublishAt != nil && !post.UnpublishAt.After(now) {
+			due = append(due, post)
+		}
+	}
+	slices.SortFunc(due, func(a, b *model.Post) int {
+		if c := a.UnpublishAt.Compare(*b.UnpublishAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	res := make([]*model.Post, 0, len(due))
+	for _, post := range due {
+		expired := *post
+		expired.Published = false
+		expired.Expired = true
+		expired.UpdatedAt = now
+		r.posts[post.ID] = &expired
+		cp := expired
+		res = append(res, &cp)
+	}
+	return res, nil
+}
+
+func postCursorKey(p *model.Post) (time.Time, int) {
+	return p.CreatedAt, p.ID
+}
+
+func (r *InMemoryPostRepo) sortPosts(posts []*model.Post, filter *PostFilter) {
+	sortBy := &PostSort{Field: model.PostSortCreatedAt, Desc: true}
+	if filter != nil && filter.Sort != nil {
+		sortBy = filter.Sort
+	}
+
+	counts := make(map[int]int)
+	if sortBy.Field == model.PostSortCommentCount {
+		for _, p := range posts {
+			counts[p.ID] = r.commentCount(p.ID)
+		}
+	}
+
+	compare := func(a, b *model.Post) int {
+		switch sortBy.Field {
+		case model.PostSortUpdatedAt:
+			return a.UpdatedAt.Compare(b.UpdatedAt)
+		case model.PostSortPublishAt:
+			switch {
+			case a.PublishAt == nil && b.PublishAt == nil:
+				return 0
+			case a.PublishAt == nil:
+				return 1
+			case b.PublishAt == nil:
+				return -1
+			}
+			return a.PublishAt.Compare(*b.PublishAt)
+		case model.PostSortPublishedAt:
+			return a.PublishedAt().Compare(b.PublishedAt())
+		case model.PostSortUnpublishAt:
+			switch {
+			case a.UnpublishAt == nil && b.UnpublishAt == nil:
+				return 0
+			case a.UnpublishAt == nil:
+				return 1
+			case b.UnpublishAt == nil:
+				return -1
+			}
+			return a.UnpublishAt.Compare(*b.UnpublishAt)
+		case model.PostSortTitle:
+			return strings.Compare(a.Title, b.Title)
+		case model.PostSortCommentCount:
+			return counts[a.ID] - counts[b.ID]
+		case model.PostSortPopularity:
+			return a.ReactionCount - b.ReactionCount
+		default:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		c := compare(posts[i], posts[j])
+		if c == 0 {
+			c = posts[i].ID - posts[j].ID
+		}
+		if sortBy.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func (r *InMemoryPostRepo) matchesFilter(post *model.Post, filter *PostFilter) bool {
+	trashed := filter != nil && filter.Trashed
+	if post.DeletedAt.Valid != trashed {
+		return false
+	}
+	if filter == nil {
+		return true
+	}
+	if filter.AuthorID != nil && post.AuthorID != *filter.AuthorID {
+		return false
+	}
+	if filter.Published != nil && post.Published != *filter.Published {
+		return false
+	}
+	if filter.Held != nil && post.Held != *filter.Held {
+		return false
+	}
+	if filter.Expired != nil && post.Expired != *filter.Expired {
+		return false
+	}
+	if filter.DueBefore != nil && post.PublishAt != nil && post.PublishAt.After(*filter.DueBefore) {
+		return false
+	}
+	if filter.ExpiresBefore != nil && (post.UnpublishAt == nil || post.UnpublishAt.After(*filter.ExpiresBefore)) {
+		return false
+	}
+	if len(filter.AuthorIDs) > 0 && !slices.Contains(filter.AuthorIDs, post.AuthorID) {
+		return false
+	}
+	if filter.CreatedFrom != nil && post.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && post.CreatedAt.After(*filter.CreatedTo) {
+		return false
+	}
+	if filter.HasComments != nil && (r.commentCount(post.ID) > 0) != *filter.HasComments {
+		return false
+	}
+	return true
+}
+
 // comment
 type InMemoryCommentRepo struct {
 	mu       sync.RWMutex
 	seq      int
 	comments map[int]*model.Comment
+	users    *InMemoryUserRepo
+	posts    *InMemoryPostRepo
 }
 
 func NewInMemoryCommentRepo() *InMemoryCommentRepo {
@@ -359,6 +624,43 @@ func NewInMemoryCommentRepo() *InMemoryCommentRepo {
 	}
 }
 
+// LinkUsers lets author expansion see the given users
+func (r *InMemoryCommentRepo) LinkUsers(users *InMemoryUserRepo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = users
+}
+
+// LinkPosts lets post author filters see the given posts
+func (r *InMemoryCommentRepo) LinkPosts(posts *InMemoryPostRepo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts = posts
+}
+
+func (r *InMemoryCommentRepo) SetStatus(ctx context.Context, ids []int, status string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	updated := 0
+	for _, id := range ids {
+		if c, ok := r.comments[id]; ok && !c.DeletedAt.Valid {
+			c.Status = status
+			c.UpdatedAt = time.Now()
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func (r *InMemoryCommentRepo) LoadAuthors(ctx context.Context, comments []*model.Comment) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range comments {
+		c.Author = userSummary(r.users, c.AuthorID)
+	}
+	return nil
+}
+
 func (r *InMemoryCommentRepo) Create(ctx context.Context, comment *model.Comment) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -367,51 +669,70 @@ func (r *InMemoryCommentRepo) Create(ctx context.Context, comment *model.Comment
 	now := time.Now()
 	comment.CreatedAt = now
 	comment.UpdatedAt = now
+	if comment.Status == "" {
+		comment.Status = model.CommentStatusApproved
+	}
 	r.comments[comment.ID] = comment
 	return nil
 }
 
 func (r *InMemoryCommentRepo) GetByID(ctx context.Context, id int) (*model.Comment, error) {
+	return r.GetComment(ctx, id, nil)
+}
+
+func (r *InMemoryCommentRepo) GetComment(ctx context.Context, id int, filter *CommentFilter) (*model.Comment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	c, ok := r.comments[id]
-	if !ok {
+	if !ok || !r.matchesFilter(c, filter) {
 		return nil, ErrCommentNotFound
 	}
 	copy := *c
 	return &copy, nil
 }
 
-func (r *InMemoryCommentRepo) GetByPostID(ctx context.Context, postID, limit, offset int) ([]*model.Comment, error) {
+func (r *InMemoryCommentRepo) GetComments(ctx context.Context, filter *CommentFilter, limit, offset int) ([]*model.Comment, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	var res []*model.Comment
 	for _, c := range r.comments {
-		if c.PostID == postID {
+		if r.matchesFilter(c, filter) {
 			res = append(res, c)
 		}
 	}
-	if offset >= len(res) {
-		return []*model.Comment{}, nil
-	}
-	end := offset + limit
-	if limit <= 0 || end > len(res) {
-		end = len(res)
-	}
-	copied := make([]*model.Comment, end-offset)
-	for i := offset; i < end; i++ {
-		cc := *res[i]
-		copied[i-offset] = &cc
+	oldestFirst := filter != nil && filter.OldestFirst
+	sort.Slice(res, func(i, j int) bool {
+		c := res[i].CreatedAt.Compare(res[j].CreatedAt)
+		if c == 0 {
+			c = res[i].ID - res[j].ID
+		}
+		if oldestFirst {
+			return c < 0
+		}
+		return c > 0
+	})
+	return paginateComments(res, limit, offset), nil
+}
+
+func (r *InMemoryCommentRepo) GetCommentsByCursor(ctx context.Context, filter *CommentFilter, cursor *model.Cursor, limit int) ([]*model.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matched []*model.Comment
+	for _, c := range r.comments {
+		if r.matchesFilter(c, filter) {
+			cc := *c
+			matched = append(matched, &cc)
+		}
 	}
-	return copied, nil
+	return pageByCursor(matched, commentCursorKey, cursor, false, limit), nil
 }
 
-func (r *InMemoryCommentRepo) GetCountByPostID(ctx context.Context, postID int) (int, error) {
+func (r *InMemoryCommentRepo) GetCommentsCount(ctx context.Context, filter *CommentFilter) (int, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	count := 0
 	for _, c := range r.comments {
-		if c.PostID == postID {
+		if r.matchesFilter(c, filter) {
 			count++
 		}
 	}
@@ -422,7 +743,7 @@ func (r *InMemoryCommentRepo) Update(ctx context.Context, comment *model.Comment
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	existing, ok := r.comments[comment.ID]
-	if !ok {
+	if !ok || existing.DeletedAt.Valid {
 		return ErrCommentNotFound
 	}
 	comment.CreatedAt = existing.CreatedAt
@@ -434,22 +755,1222 @@ func (r *InMemoryCommentRepo) Update(ctx context.Context, comment *model.Comment
 func (r *InMemoryCommentRepo) Delete(ctx context.Context, id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if _, ok := r.comments[id]; !ok {
+	c, ok := r.comments[id]
+	if !ok || c.DeletedAt.Valid {
+		return ErrCommentNotFound
+	}
+	c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *InMemoryCommentRepo) Restore(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.comments[id]
+	if !ok || !c.DeletedAt.Valid {
 		return ErrCommentNotFound
 	}
-	delete(r.comments, id)
+	c.DeletedAt = gorm.DeletedAt{}
 	return nil
 }
 
-func (r *InMemoryCommentRepo) GetCountByAuthorID(ctx context.Context, authorID int) (int, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+func (r *InMemoryCommentRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var purged []int
+	for id, c := range r.comments {
+		if c.DeletedAt.Valid && c.DeletedAt.Time.Before(before) && !r.hasReplies(id) {
+			purged = append(purged, id)
+		}
+	}
+	for _, id := range purged {
+		delete(r.comments, id)
+	}
+	return len(purged), nil
+}
 
-	count := 0
+func commentCursorKey(c *model.Comment) (time.Time, int) {
+	return c.CreatedAt, c.ID
+}
+
+func matchesCommentStatus(c *model.Comment, filter *CommentFilter) bool {
+	if len(filter.Statuses) == 0 || slices.Contains(filter.Statuses, c.Status) {
+		return true
+	}
+	return filter.PendingOf != nil && c.Status == model.CommentStatusPending && c.AuthorID == *filter.PendingOf
+}
+
+func (r *InMemoryCommentRepo) hasReplies(id int) bool {
 	for _, c := range r.comments {
-		if c.AuthorID == authorID {
-			count++
+		if c.ParentID != nil && *c.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryCommentRepo) hasLiveReplies(id int, filter *CommentFilter) bool {
+	for _, c := range r.comments {
+		if c.ParentID == nil || *c.ParentID != id {
+			continue
+		}
+		if (!c.DeletedAt.Valid && matchesCommentStatus(c, filter)) || r.hasLiveReplies(c.ID, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryCommentRepo) matchesFilter(c *model.Comment, filter *CommentFilter) bool {
+	if filter == nil {
+		return !c.DeletedAt.Valid
+	}
+	visible := matchesCommentStatus(c, filter)
+	switch {
+	case filter.Trashed:
+		if !c.DeletedAt.Valid || !visible {
+			return false
+		}
+	case filter.WithTombstones:
+		if (c.DeletedAt.Valid || !visible) && !r.hasLiveReplies(c.ID, filter) {
+			return false
+		}
+	default:
+		if c.DeletedAt.Valid || !visible {
+			return false
+		}
+	}
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, c.ID) {
+		return false
+	}
+	if filter.PostID != nil && c.PostID != *filter.PostID {
+		return false
+	}
+	if filter.PostAuthorID != nil {
+		if r.posts == nil {
+			return false
+		}
+		post, err := r.posts.GetPost(context.Background(), c.PostID, nil)
+		if err != nil || post.AuthorID != *filter.PostAuthorID {
+			return false
+		}
+	}
+	if filter.AuthorID != nil && c.AuthorID != *filter.AuthorID {
+		return false
+	}
+	if filter.TopLevel && c.ParentID != nil {
+		return false
+	}
+	if len(filter.RootIDs) > 0 && (c.RootID == nil || !slices.Contains(filter.RootIDs, *c.RootID)) {
+		return false
+	}
+	return true
+}
+
+func paginateComments(res []*model.Comment, limit, offset int) []*model.Comment {
+	if offset >= len(res) {
+		return []*model.Comment{}
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	copied := make([]*model.Comment, end-offset)
+	for i := offset; i < end; i++ {
+		cc := *res[i]
+		copied[i-offset] = &cc
+	}
+	return copied
+}
+
+// comment revisions
+type InMemoryCommentRevisionRepo struct {
+	mu        sync.RWMutex
+	seq       int
+	revisions []*model.CommentRevision
+}
+
+func NewInMemoryCommentRevisionRepo() *InMemoryCommentRevisionRepo {
+	return &InMemoryCommentRevisionRepo{}
+}
+
+func (r *InMemoryCommentRevisionRepo) Create(ctx context.Context, revision *model.CommentRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	revision.ID = r.seq
+	revision.CreatedAt = time.Now()
+	cp := *revision
+	r.revisions = append(r.revisions, &cp)
+	return nil
+}
+
+func (r *InMemoryCommentRevisionRepo) GetByCommentID(ctx context.Context, commentID int, limit, offset int) ([]*model.CommentRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.CommentRevision
+	for i := len(r.revisions) - 1; i >= 0; i-- {
+		if r.revisions[i].CommentID == commentID {
+			cp := *r.revisions[i]
+			res = append(res, &cp)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.CommentRevision{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end], nil
+}
+
+func (r *InMemoryCommentRevisionRepo) GetCountByCommentID(ctx context.Context, commentID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, revision := range r.revisions {
+		if revision.CommentID == commentID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryCommentRevisionRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// spam samples
+type InMemorySpamSampleRepo struct {
+	mu      sync.RWMutex
+	seq     int
+	samples []*model.SpamSample
+}
+
+func NewInMemorySpamSampleRepo() *InMemorySpamSampleRepo {
+	return &InMemorySpamSampleRepo{}
+}
+
+func (r *InMemorySpamSampleRepo) Create(ctx context.Context, sample *model.SpamSample) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	sample.ID = r.seq
+	sample.CreatedAt = time.Now()
+	r.samples = append(r.samples, sample)
+	return nil
+}
+
+func (r *InMemorySpamSampleRepo) GetLatest(ctx context.Context, limit int) ([]*model.SpamSample, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := slices.Clone(r.samples)
+	slices.Reverse(res)
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// reactions
+type InMemoryReactionRepo struct {
+	mu        sync.Mutex
+	seq       int
+	reactions map[string]*model.Reaction
+	posts     *InMemoryPostRepo
+	comments  *InMemoryCommentRepo
+}
+
+func NewInMemoryReactionRepo(posts *InMemoryPostRepo, comments *InMemoryCommentRepo) *InMemoryReactionRepo {
+	return &InMemoryReactionRepo{
+		reactions: make(map[string]*model.Reaction),
+		posts:     posts,
+		comments:  comments,
+	}
+}
+
+func reactionKey(targetType string, targetID, userID int) string {
+	return fmt.Sprintf("%s:%d:%d", targetType, targetID, userID)
+}
+
+func (r *InMemoryReactionRepo) Set(ctx context.Context, reaction *model.Reaction) (model.ReactionCounts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reactionKey(reaction.TargetType, reaction.TargetID, reaction.UserID)
+	now := time.Now()
+	if existing, ok := r.reactions[key]; ok {
+		existing.Kind = reaction.Kind
+		existing.UpdatedAt = now
+		*reaction = *existing
+	} else {
+		r.seq++
+		reaction.ID = r.seq
+		reaction.CreatedAt = now
+		reaction.UpdatedAt = now
+		stored := *reaction
+		r.reactions[key] = &stored
+	}
+	return r.refreshCounts(reaction.TargetType, reaction.TargetID)
+}
+
+func (r *InMemoryReactionRepo) Delete(ctx context.Context, targetType string, targetID, userID int) (model.ReactionCounts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reactionKey(targetType, targetID, userID)
+	if _, ok := r.reactions[key]; !ok {
+		return nil, ErrReactionNotFound
+	}
+	delete(r.reactions, key)
+	return r.refreshCounts(targetType, targetID)
+}
+
+func (r *InMemoryReactionRepo) DeleteOrphaned(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted := 0
+	for key, reaction := range r.reactions {
+		if !targetStored(r.posts, r.comments, reaction.TargetType, reaction.TargetID) {
+			delete(r.reactions, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// targetStored reports whether the post or comment is stored, trashed ones included
+func targetStored(posts *InMemoryPostRepo, comments *InMemoryCommentRepo, targetType string, id int) bool {
+	switch targetType {
+	case model.ReactionTargetPost:
+		posts.mu.Lock()
+		defer posts.mu.Unlock()
+		_, ok := posts.posts[id]
+		return ok
+	case model.ReactionTargetComment:
+		comments.mu.Lock()
+		defer comments.mu.Unlock()
+		_, ok := comments.comments[id]
+		return ok
+	}
+	return false
+}
+
+func (r *InMemoryReactionRepo) refreshCounts(targetType string, targetID int) (model.ReactionCounts, error) {
+	counts := model.ReactionCounts{}
+	total := 0
+	for _, reaction := range r.reactions {
+		if reaction.TargetType == targetType && reaction.TargetID == targetID {
+			counts[reaction.Kind]++
+			total++
+		}
+	}
+
+	switch targetType {
+	case model.ReactionTargetPost:
+		r.posts.mu.Lock()
+		defer r.posts.mu.Unlock()
+		post, ok := r.posts.posts[targetID]
+		if !ok {
+			return nil, ErrReactionTargetNotFound
+		}
+		post.Reactions, post.ReactionCount = counts, total
+	case model.ReactionTargetComment:
+		r.comments.mu.Lock()
+		defer r.comments.mu.Unlock()
+		comment, ok := r.comments.comments[targetID]
+		if !ok {
+			return nil, ErrReactionTargetNotFound
+		}
+		comment.Reactions, comment.ReactionCount = counts, total
+	default:
+		return nil, fmt.Errorf("unsupported reaction target: %s", targetType)
+	}
+	return counts, nil
+}
+
+// reports
+type InMemoryReportRepo struct {
+	mu       sync.RWMutex
+	seq      int
+	reports  map[int]*model.Report
+	posts    *InMemoryPostRepo
+	comments *InMemoryCommentRepo
+}
+
+func NewInMemoryReportRepo() *InMemoryReportRepo {
+	return &InMemoryReportRepo{
+		reports: make(map[int]*model.Report),
+	}
+}
+
+func (r *InMemoryReportRepo) Create(ctx context.Context, report *model.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.reports {
+		if existing.TargetType == report.TargetType &&
+			existing.TargetID == report.TargetID &&
+			existing.ReporterID == report.ReporterID {
+			return ErrReportExists
+		}
+	}
+	r.seq++
+	report.ID = r.seq
+	if report.Status == "" {
+		report.Status = model.ReportStatusOpen
+	}
+	report.CreatedAt = time.Now()
+	cp := *report
+	r.reports[report.ID] = &cp
+	return nil
+}
+
+func (r *InMemoryReportRepo) GetByID(ctx context.Context, id int) (*model.Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	report, ok := r.reports[id]
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+	cp := *report
+	return &cp, nil
+}
+
+func (r *InMemoryReportRepo) GetReports(ctx context.Context, filter *ReportFilter, limit, offset int) ([]*model.Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.Report
+	for _, report := range r.reports {
+		if matchesReportFilter(report, filter) {
+			cp := *report
+			res = append(res, &cp)
+		}
+	}
+	slices.SortFunc(res, func(a, b *model.Report) int { return a.ID - b.ID })
+	if offset >= len(res) {
+		return []*model.Report{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end], nil
+}
+
+func (r *InMemoryReportRepo) GetReportsCount(ctx context.Context, filter *ReportFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, report := range r.reports {
+		if matchesReportFilter(report, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryReportRepo) Close(ctx context.Context, targetType string, targetID int, status string, moderatorID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	closed := 0
+	for _, report := range r.reports {
+		if report.TargetType == targetType && report.TargetID == targetID && report.Status == model.ReportStatusOpen {
+			report.Status = status
+			report.ResolvedBy = &moderatorID
+			report.ResolvedAt = &now
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+// LinkTargets lets the orphan cleanup see the reported posts and comments
+func (r *InMemoryReportRepo) LinkTargets(posts *InMemoryPostRepo, comments *InMemoryCommentRepo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.posts, r.comments = posts, comments
+}
+
+func (r *InMemoryReportRepo) DeleteOrphaned(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.posts == nil || r.comments == nil {
+		return 0, nil
+	}
+	deleted := 0
+	for id, report := range r.reports {
+		if !targetStored(r.posts, r.comments, report.TargetType, report.TargetID) {
+			delete(r.reports, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *InMemoryReportRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func matchesReportFilter(report *model.Report, filter *ReportFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.Status != nil && report.Status != *filter.Status {
+		return false
+	}
+	if filter.TargetType != nil && report.TargetType != *filter.TargetType {
+		return false
+	}
+	if filter.TargetID != nil && report.TargetID != *filter.TargetID {
+		return false
+	}
+	return true
+}
+
+// moderation log
+type InMemoryModerationLogRepo struct {
+	mu      sync.RWMutex
+	seq     int
+	entries []*model.ModerationLogEntry
+}
+
+func NewInMemoryModerationLogRepo() *InMemoryModerationLogRepo {
+	return &InMemoryModerationLogRepo{}
+}
+
+func (r *InMemoryModerationLogRepo) Create(ctx context.Context, entry *model.ModerationLogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	entry.ID = r.seq
+	entry.CreatedAt = time.Now()
+	cp := *entry
+	r.entries = append(r.entries, &cp)
+	return nil
+}
+
+func (r *InMemoryModerationLogRepo) GetEntries(ctx context.Context, filter *ModerationLogFilter, limit, offset int) ([]*model.ModerationLogEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.ModerationLogEntry
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if matchesModerationLogFilter(r.entries[i], filter) {
+			cp := *r.entries[i]
+			res = append(res, &cp)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.ModerationLogEntry{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end], nil
+}
+
+func (r *InMemoryModerationLogRepo) GetEntriesCount(ctx context.Context, filter *ModerationLogFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, entry := range r.entries {
+		if matchesModerationLogFilter(entry, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func matchesModerationLogFilter(entry *model.ModerationLogEntry, filter *ModerationLogFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.TargetType != nil && entry.TargetType != *filter.TargetType {
+		return false
+	}
+	if filter.TargetID != nil && entry.TargetID != *filter.TargetID {
+		return false
+	}
+	if filter.ModeratorID != nil && (entry.ModeratorID == nil || *entry.ModeratorID != *filter.ModeratorID) {
+		return false
+	}
+	return true
+}
+
+// notifications
+type InMemoryNotificationRepo struct {
+	mu            sync.RWMutex
+	seq           int
+	notifications []*model.Notification
+}
+
+func NewInMemoryNotificationRepo() *InMemoryNotificationRepo {
+	return &InMemoryNotificationRepo{}
+}
+
+func (r *InMemoryNotificationRepo) Create(ctx context.Context, notification *model.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	notification.ID = r.seq
+	notification.CreatedAt = time.Now()
+	cp := *notification
+	r.notifications = append(r.notifications, &cp)
+	return nil
+}
+
+// All returns every stored notification in creation order
+func (r *InMemoryNotificationRepo) All() []*model.Notification {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.notifications)
+}
+
+func (r *InMemoryNotificationRepo) GetByID(ctx context.Context, id int) (*model.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, n := range r.notifications {
+		if n.ID == id {
+			cp := *n
+			return &cp, nil
+		}
+	}
+	return nil, ErrNotificationNotFound
+}
+
+func (r *InMemoryNotificationRepo) GetNotifications(ctx context.Context, filter *NotificationFilter, limit, offset int) ([]*model.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.Notification
+	for i := len(r.notifications) - 1; i >= 0; i-- {
+		if matchesNotificationFilter(r.notifications[i], filter) {
+			cp := *r.notifications[i]
+			res = append(res, &cp)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.Notification{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end], nil
+}
+
+func (r *InMemoryNotificationRepo) GetNotificationsCount(ctx context.Context, filter *NotificationFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, n := range r.notifications {
+		if matchesNotificationFilter(n, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryNotificationRepo) MarkRead(ctx context.Context, userID int, ids []int, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	updated := 0
+	for _, n := range r.notifications {
+		if n.UserID != userID || n.ReadAt != nil || (ids != nil && !slices.Contains(ids, n.ID)) {
+			continue
+		}
+		n.ReadAt = &at
+		updated++
+	}
+	return updated, nil
+}
+
+func matchesNotificationFilter(n *model.Notification, filter *NotificationFilter) bool {
+	return n.UserID == filter.UserID && (!filter.Unread || n.ReadAt == nil)
+}
+
+// notification preferences
+type InMemoryNotificationPreferenceRepo struct {
+	mu          sync.RWMutex
+	preferences map[int]model.NotificationPreferences
+}
+
+func NewInMemoryNotificationPreferenceRepo() *InMemoryNotificationPreferenceRepo {
+	return &InMemoryNotificationPreferenceRepo{preferences: make(map[int]model.NotificationPreferences)}
+}
+
+func (r *InMemoryNotificationPreferenceRepo) Get(ctx context.Context, userID int) (*model.NotificationPreferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	preferences, ok := r.preferences[userID]
+	if !ok {
+		return nil, ErrNotificationPreferencesNotFound
+	}
+	return &preferences, nil
+}
+
+func (r *InMemoryNotificationPreferenceRepo) Save(ctx context.Context, preferences *model.NotificationPreferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	preferences.UpdatedAt = time.Now()
+	r.preferences[preferences.UserID] = *preferences
+	return nil
+}
+
+// follows
+type InMemoryFollowRepo struct {
+	mu      sync.RWMutex
+	follows []*model.Follow
+	users   *InMemoryUserRepo
+}
+
+// NewInMemoryFollowRepo lists the given users in follower and following lists
+func NewInMemoryFollowRepo(users *InMemoryUserRepo) *InMemoryFollowRepo {
+	return &InMemoryFollowRepo{users: users}
+}
+
+func (r *InMemoryFollowRepo) Create(ctx context.Context, follow *model.Follow) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.indexOf(follow.FollowerID, follow.FolloweeID) >= 0 {
+		return false, nil
+	}
+	follow.CreatedAt = time.Now()
+	cp := *follow
+	r.follows = append(r.follows, &cp)
+	return true, nil
+}
+
+func (r *InMemoryFollowRepo) Delete(ctx context.Context, followerID, followeeID int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.indexOf(followerID, followeeID)
+	if i < 0 {
+		return false, nil
+	}
+	r.follows = slices.Delete(r.follows, i, i+1)
+	return true, nil
+}
+
+func (r *InMemoryFollowRepo) Exists(ctx context.Context, followerID, followeeID int) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.indexOf(followerID, followeeID) >= 0, nil
+}
+
+func (r *InMemoryFollowRepo) GetFollowers(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error) {
+	return r.getUsers(userID, false, limit, offset), nil
+}
+
+func (r *InMemoryFollowRepo) GetFollowing(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error) {
+	return r.getUsers(userID, true, limit, offset), nil
+}
+
+func (r *InMemoryFollowRepo) GetFollowersCount(ctx context.Context, userID int) (int, error) {
+	return len(r.getUsers(userID, false, 0, 0)), nil
+}
+
+func (r *InMemoryFollowRepo) GetFollowingCount(ctx context.Context, userID int) (int, error) {
+	return len(r.getUsers(userID, true, 0, 0)), nil
+}
+
+func (r *InMemoryFollowRepo) GetFolloweeIDs(ctx context.Context, userID int) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ids []int
+	for _, f := range r.follows {
+		if f.FollowerID == userID {
+			ids = append(ids, f.FolloweeID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *InMemoryFollowRepo) indexOf(followerID, followeeID int) int {
+	return slices.IndexFunc(r.follows, func(f *model.Follow) bool {
+		return f.FollowerID == followerID && f.FolloweeID == followeeID
+	})
+}
+
+// getUsers lists the followees of the user when following is set and the followers otherwise, latest follows first
+func (r *InMemoryFollowRepo) getUsers(userID int, following bool, limit, offset int) []*model.UserSummary {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.UserSummary
+	for i := len(r.follows) - 1; i >= 0; i-- {
+		f := r.follows[i]
+		id := f.FollowerID
+		if following {
+			if f.FollowerID != userID {
+				continue
+			}
+			id = f.FolloweeID
+		} else if f.FolloweeID != userID {
+			continue
+		}
+		if summary := userSummary(r.users, id); summary != nil {
+			res = append(res, summary)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.UserSummary{}
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end]
+}
+
+// webhooks
+type InMemoryWebhookRepo struct {
+	mu       sync.RWMutex
+	seq      int
+	webhooks []*model.Webhook
+}
+
+func NewInMemoryWebhookRepo() *InMemoryWebhookRepo {
+	return &InMemoryWebhookRepo{}
+}
+
+func (r *InMemoryWebhookRepo) Create(ctx context.Context, webhook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	webhook.ID = r.seq
+	webhook.CreatedAt = time.Now()
+	cp := *webhook
+	r.webhooks = append(r.webhooks, &cp)
+	return nil
+}
+
+func (r *InMemoryWebhookRepo) GetByID(ctx context.Context, id int) (*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, w := range r.webhooks {
+		if w.ID == id {
+			cp := *w
+			return &cp, nil
+		}
+	}
+	return nil, ErrWebhookNotFound
+}
+
+func (r *InMemoryWebhookRepo) GetByOwnerID(ctx context.Context, ownerID int, limit, offset int) ([]*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.Webhook
+	for i := len(r.webhooks) - 1; i >= 0; i-- {
+		if r.webhooks[i].OwnerID == ownerID {
+			cp := *r.webhooks[i]
+			res = append(res, &cp)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.Webhook{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end], nil
+}
+
+func (r *InMemoryWebhookRepo) GetCountByOwnerID(ctx context.Context, ownerID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, w := range r.webhooks {
+		if w.OwnerID == ownerID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryWebhookRepo) GetSubscribed(ctx context.Context, event string, ownerID int) ([]*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.Webhook
+	for _, w := range r.webhooks {
+		if w.Active && slices.Contains(w.Events, event) && (w.OwnerID == ownerID || w.AllUsers) {
+			cp := *w
+			res = append(res, &cp)
+		}
+	}
+	return res, nil
+}
+
+func (r *InMemoryWebhookRepo) Delete(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.webhooks, func(w *model.Webhook) bool { return w.ID == id })
+	if i < 0 {
+		return ErrWebhookNotFound
+	}
+	r.webhooks = slices.Delete(r.webhooks, i, i+1)
+	return nil
+}
+
+// webhook deliveries
+type InMemoryWebhookDeliveryRepo struct {
+	mu         sync.RWMutex
+	seq        int
+	deliveries []*model.WebhookDelivery
+}
+
+func NewInMemoryWebhookDeliveryRepo() *InMemoryWebhookDeliveryRepo {
+	return &InMemoryWebhookDeliveryRepo{}
+}
+
+func (r *InMemoryWebhookDeliveryRepo) Create(ctx context.Context, delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	delivery.ID = r.seq
+	delivery.CreatedAt = time.Now()
+	cp := *delivery
+	r.deliveries = append(r.deliveries, &cp)
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepo) GetByID(ctx context.Context, id int) (*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			cp := *d
+			return &cp, nil
+		}
+	}
+	return nil, ErrWebhookDeliveryNotFound
+}
+
+func (r *InMemoryWebhookDeliveryRepo) GetDeliveries(ctx context.Context, filter *WebhookDeliveryFilter, limit, offset int) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		if matchesWebhookDeliveryFilter(r.deliveries[i], filter) {
+			cp := *r.deliveries[i]
+			res = append(res, &cp)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.WebhookDelivery{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(res) {
+		end = len(res)
+	}
+	return res[offset:end], nil
+}
+
+func (r *InMemoryWebhookDeliveryRepo) GetDeliveriesCount(ctx context.Context, filter *WebhookDeliveryFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, d := range r.deliveries {
+		if matchesWebhookDeliveryFilter(d, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryWebhookDeliveryRepo) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []*model.WebhookDelivery
+	for _, d := range r.deliveries {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		if d.Status == model.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = leaseUntil
+			cp := *d
+			res = append(res, &cp)
+		}
+	}
+	return res, nil
+}
+
+func (r *InMemoryWebhookDeliveryRepo) Update(ctx context.Context, delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, d := range r.deliveries {
+		if d.ID == delivery.ID {
+			cp := *delivery
+			r.deliveries[i] = &cp
+			return nil
+		}
+	}
+	return ErrWebhookDeliveryNotFound
+}
+
+func matchesWebhookDeliveryFilter(d *model.WebhookDelivery, filter *WebhookDeliveryFilter) bool {
+	return d.WebhookID == filter.WebhookID && (filter.Status == nil || d.Status == *filter.Status)
+}
+
+// events
+type eventTxKey struct{}
+
+type InMemoryEventRepo struct {
+	mu     sync.RWMutex
+	seq    int64
+	events []*model.Event
+	relay  sync.Mutex
+}
+
+func NewInMemoryEventRepo() *InMemoryEventRepo {
+	return &InMemoryEventRepo{}
+}
+
+// All returns copies of every recorded event in the order they were recorded
+func (r *InMemoryEventRepo) All() []*model.Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]*model.Event, 0, len(r.events))
+	for _, e := range r.events {
+		cp := *e
+		res = append(res, &cp)
+	}
+	return res
+}
+
+func (r *InMemoryEventRepo) Create(ctx context.Context, event *model.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	event.ID = r.seq
+	event.CreatedAt = time.Now()
+	cp := *event
+	r.events = append(r.events, &cp)
+	return nil
+}
+
+// Lock holds the relay lock until the surrounding WithinTransaction returns
+func (r *InMemoryEventRepo) Lock(ctx context.Context) (bool, error) {
+	locked, ok := ctx.Value(eventTxKey{}).(*bool)
+	if !ok {
+		return false, errors.New("relay lock requires a transaction")
+	}
+	if !*locked {
+		*locked = r.relay.TryLock()
+	}
+	return *locked, nil
+}
+
+func (r *InMemoryEventRepo) GetPending(ctx context.Context, limit int) ([]*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.Event
+	for _, e := range r.events {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		if e.DispatchedAt == nil {
+			cp := *e
+			res = append(res, &cp)
+		}
+	}
+	return res, nil
+}
+
+func (r *InMemoryEventRepo) GetEvents(ctx context.Context, filter *EventFilter, limit int) ([]*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.Event
+	for _, e := range r.events {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		if e.ID <= filter.AfterID || (filter.AggregateType != "" && e.AggregateType != filter.AggregateType) {
+			continue
+		}
+		if filter.PostID != nil {
+			var target struct {
+				PostID int `json:"post_id"`
+			}
+			if err := json.Unmarshal(e.Payload, &target); err != nil || target.PostID != *filter.PostID {
+				continue
+			}
+		}
+		cp := *e
+		res = append(res, &cp)
+	}
+	return res, nil
+}
+
+func (r *InMemoryEventRepo) GetLatest(ctx context.Context, aggregateType string) (*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, e := range slices.Backward(r.events) {
+		if e.AggregateType == aggregateType {
+			cp := *e
+			return &cp, nil
+		}
+	}
+	return nil, ErrEventNotFound
+}
+
+func (r *InMemoryEventRepo) MarkDispatched(ctx context.Context, ids []int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if slices.Contains(ids, e.ID) {
+			e.DispatchedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryEventRepo) MarkFailed(ctx context.Context, id int64, lastError string, giveUpAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e.ID == id {
+			e.Attempts++
+			e.LastError = lastError
+			e.DispatchedAt = giveUpAt
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryEventRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(eventTxKey{}).(*bool); ok {
+		return fn(ctx)
+	}
+	locked := false
+	defer func() {
+		if locked {
+			r.relay.Unlock()
+		}
+	}()
+	return fn(context.WithValue(ctx, eventTxKey{}, &locked))
+}
+
+// job runs
+type InMemoryJobRunRepo struct {
+	mu   sync.RWMutex
+	seq  int
+	runs []*model.JobRun
+}
+
+func NewInMemoryJobRunRepo() *InMemoryJobRunRepo {
+	return &InMemoryJobRunRepo{}
+}
+
+func (r *InMemoryJobRunRepo) Claim(ctx context.Context, run *model.JobRun) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.runs {
+		if existing.Job == run.Job && existing.ScheduledAt.Equal(run.ScheduledAt) {
+			return false, nil
+		}
+	}
+	r.seq++
+	run.ID = r.seq
+	run.CreatedAt = time.Now()
+	cp := *run
+	r.runs = append(r.runs, &cp)
+	return true, nil
+}
+
+func (r *InMemoryJobRunRepo) Update(ctx context.Context, run *model.JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.runs {
+		if existing.ID == run.ID {
+			cp := *run
+			r.runs[i] = &cp
+			return nil
+		}
+	}
+	return ErrJobRunNotFound
+}
+
+func (r *InMemoryJobRunRepo) GetRuns(ctx context.Context, job string, limit, offset int) ([]*model.JobRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []*model.JobRun
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		if r.runs[i].Job == job {
+			cp := *r.runs[i]
+			res = append(res, &cp)
+		}
+	}
+	if offset >= len(res) {
+		return []*model.JobRun{}, nil
+	}
+	res = res[offset:]
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *InMemoryJobRunRepo) GetRunsCount(ctx context.Context, job string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, run := range r.runs {
+		if run.Job == job {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryJobRunRepo) GetLastRuns(ctx context.Context) (map[string]*model.JobRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	last := map[string]*model.JobRun{}
+	for _, run := range r.runs {
+		cp := *run
+		last[run.Job] = &cp
+	}
+	return last, nil
+}
+
+func (r *InMemoryJobRunRepo) Abandon(ctx context.Context, before, at time.Time, reason string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for i, run := range r.runs {
+		if run.Status != model.JobRunPending && run.Status != model.JobRunRunning {
+			continue
+		}
+		since := run.ScheduledAt
+		if run.StartedAt != nil {
+			since = *run.StartedAt
+		}
+		if !since.Before(before) {
+			continue
+		}
+		cp := *run
+		cp.Status = model.JobRunFailed
+		cp.LastError = reason
+		cp.FinishedAt = &at
+		r.runs[i] = &cp
+		count++
+	}
+	return count, nil
+}
+
+func (r *InMemoryJobRunRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.runs[:0]
+	for _, run := range r.runs {
+		if run.FinishedAt == nil || !run.FinishedAt.Before(before) {
+			kept = append(kept, run)
 		}
 	}
+	count := len(r.runs) - len(kept)
+	clear(r.runs[len(kept):])
+	r.runs = kept
 	return count, nil
 }
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+// ModerationLogFilter defines optional filters for fetching moderation log entries.
+type ModerationLogFilter struct {
+	TargetType  *string
+	TargetID    *int
+	ModeratorID *int
+}
+
+type ModerationLogRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewModerationLogRepo(db *database.DatabaseManager) *ModerationLogRepo {
+	return &ModerationLogRepo{db: db}
+}
+
+func (r *ModerationLogRepo) Create(ctx context.Context, entry *model.ModerationLogEntry) error {
+	if err := r.db.TxDB(ctx).Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create moderation log entry: %w", err)
+	}
+	return nil
+}
+
+// GetEntries returns the matching entries, newest first
+func (r *ModerationLogRepo) GetEntries(ctx context.Context, filter *ModerationLogFilter, limit, offset int) ([]*model.ModerationLogEntry, error) {
+	var entries []*model.ModerationLogEntry
+	db := r.applyFilters(r.db.TxDB(ctx), filter).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get moderation log: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *ModerationLogRepo) GetEntriesCount(ctx context.Context, filter *ModerationLogFilter) (int, error) {
+	var count int64
+	err := r.applyFilters(r.db.TxDB(ctx), filter).Model(&model.ModerationLogEntry{}).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count moderation log: %w", err)
+	}
+	return int(count), nil
+}
+
+func (r *ModerationLogRepo) applyFilters(db *gorm.DB, filter *ModerationLogFilter) *gorm.DB {
+	if filter == nil {
+		return db
+	}
+	if filter.TargetType != nil {
+		db = db.Where("target_type = ?", *filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		db = db.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.ModeratorID != nil {
+		db = db.Where("moderator_id = ?", *filter.ModeratorID)
+	}
+	return db
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var ErrNotificationPreferencesNotFound = errors.New("notification preferences not found")
+
+type NotificationPreferenceRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewNotificationPreferenceRepo(db *database.DatabaseManager) *NotificationPreferenceRepo {
+	return &NotificationPreferenceRepo{db: db}
+}
+
+// Get returns the stored preferences, users that never changed them have none
+func (r *NotificationPreferenceRepo) Get(ctx context.Context, userID int) (*model.NotificationPreferences, error) {
+	var preferences model.NotificationPreferences
+	err := r.db.TxDB(ctx).Where("user_id = ?", userID).First(&preferences).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotificationPreferencesNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return &preferences, nil
+}
+
+// Save creates or replaces the preferences of the user
+func (r *NotificationPreferenceRepo) Save(ctx context.Context, preferences *model.NotificationPreferences) error {
+	err := r.db.TxDB(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(preferences).Error
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationFilter defines optional filters for fetching the notifications of a user.
+type NotificationFilter struct {
+	UserID int
+	Unread bool
+}
+
+type NotificationRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewNotificationRepo(db *database.DatabaseManager) *NotificationRepo {
+	return &NotificationRepo{db: db}
+}
+
+func (r *NotificationRepo) Create(ctx context.Context, notification *model.Notification) error {
+	if err := r.db.TxDB(ctx).Create(notification).Error; err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepo) GetByID(ctx context.Context, id int) (*model.Notification, error) {
+	var notification model.Notification
+	err := r.db.TxDB(ctx).First(&notification, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification by ID: %w", err)
+	}
+	return &notification, nil
+}
+
+// GetNotifications returns the matching notifications, newest first
+func (r *NotificationRepo) GetNotifications(ctx context.Context, filter *NotificationFilter, limit, offset int) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+	db := r.applyFilters(r.db.TxDB(ctx), filter).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepo) GetNotificationsCount(ctx context.Context, filter *NotificationFilter) (int, error) {
+	var count int64
+	err := r.applyFilters(r.db.TxDB(ctx), filter).Model(&model.Notification{}).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return int(count), nil
+}
+
+// MarkRead marks the unread notifications of the user as read, nil ids mark all of them
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID int, ids []int, at time.Time) (int, error) {
+	db := r.db.TxDB(ctx).Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if ids != nil {
+		if len(ids) == 0 {
+			return 0, nil
+		}
+		db = db.Where("id IN ?", ids)
+	}
+	result := db.Update("read_at", at)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+func (r *NotificationRepo) applyFilters(db *gorm.DB, filter *NotificationFilter) *gorm.DB {
+	db = db.Where("user_id = ?", filter.UserID)
+	if filter.Unread {
+		db = db.Where("read_at IS NULL")
+	}
+	return db
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 
 	"blog-api/internal/model"
@@ -18,9 +19,39 @@ var (
 
 // PostFilter defines optional filters for fetching posts.
 type PostFilter struct {
-	AuthorID  *int
-	Published *bool
-	DueBefore *time.Time
+	AuthorID      *int
+	AuthorIDs     []int
+	Published     *bool
+	Held          *bool
+	Expired       *bool
+	DueBefore     *time.Time
+	ExpiresBefore *time.Time
+	CreatedFrom   *time.Time // inclusive
+	CreatedTo     *time.Time // inclusive
+	HasComments   *bool
+	Trashed       bool // selects soft deleted posts only
+	Sort          *PostSort
+}
+
+// PostSort orders a posts listing by one of the model.PostSortFields, newest first when nil
+type PostSort struct {
+	Field string
+	Desc  bool
+}
+
+const liveCommentsSubquery = "FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL " +
+	"AND comments.status = '" + model.CommentStatusApproved + "'"
+
+// postSortColumns maps whitelisted sort fields to SQL expressions
+var postSortColumns = map[string]string{
+	model.PostSortCreatedAt:    "created_at",
+	model.PostSortUpdatedAt:    "updated_at",
+	model.PostSortPublishAt:    "publish_at",
+	model.PostSortPublishedAt:  "COALESCE(publish_at, created_at)",
+	model.PostSortUnpublishAt:  "unpublish_at",
+	model.PostSortTitle:        "title",
+	model.PostSortCommentCount: "(SELECT COUNT(*) " + liveCommentsSubquery + ")",
+	model.PostSortPopularity:   "reaction_count",
 }
 
 type PostRepo struct {
@@ -65,8 +96,10 @@ func (r *PostRepo) GetPosts(
 ) ([]*model.Post, error) {
 	var posts []*model.Post
 
-	db := r.applyFilters(r.db.TxDB(ctx), filter).
-		Order("created_at DESC")
+	db, err := r.applySort(r.applyFilters(r.db.TxDB(ctx), filter), filter)
+	if err != nil {
+		return nil, err
+	}
 
 	if limit > 0 {
 		db = db.Limit(limit)
@@ -82,6 +115,25 @@ func (r *PostRepo) GetPosts(
 	return posts, nil
 }
 
+// GetPostsByCursor returns up to limit posts past the cursor, newest first
+func (r *PostRepo) GetPostsByCursor(
+	ctx context.Context,
+	filter *PostFilter,
+	cursor *model.Cursor,
+	limit int,
+) ([]*model.Post, error) {
+	var posts []*model.Post
+
+	db := applyCursor(r.applyFilters(r.db.TxDB(ctx), filter), cursor, true, limit)
+
+	if err := db.Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get posts by cursor: %w", err)
+	}
+
+	restoreCursorOrder(posts, cursor)
+	return posts, nil
+}
+
 func (r *PostRepo) GetPostsCount(
 	ctx context.Context,
 	filter *PostFilter,
@@ -105,11 +157,21 @@ func (r *PostRepo) Update(ctx context.Context, post *model.Post) error {
 		Model(&model.Post{}).
 		Where("id = ?", post.ID).
 		Updates(map[string]any{
-			"title":      post.Title,
-			"content":    post.Content,
-			"publish_at": post.PublishAt,
-			"published":  post.Published,
-			"updated_at": post.UpdatedAt,
+			"title":                         post.Title,
+			"content":                       post.Content,
+			"content_format":                post.ContentFormat,
+			"content_html":                  post.ContentHTML,
+			"mentions":                      post.Mentions,
+			"publish_at":                    post.PublishAt,
+			"published":                     post.Published,
+			"held":                          post.Held,
+			"unpublish_at":                  post.UnpublishAt,
+			"expired":                       post.Expired,
+			"updated_at":                    post.UpdatedAt,
+			"comments_premoderation":        post.CommentsPremoderation,
+			"comments_policy":               post.CommentsPolicy,
+			"comments_min_account_age_days": post.CommentsMinAccountAge,
+			"comments_close_after_days":     post.CommentsCloseAfter,
 		})
 
 	if result.Error != nil {
@@ -137,22 +199,209 @@ func (r *PostRepo) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+func (r *PostRepo) Restore(ctx context.Context, id int) error {
+	result := r.db.TxDB(ctx).
+		Unscoped().
+		Model(&model.Post{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to restore post: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPostNotFound
+	}
+
+	return nil
+}
+
+// Purge permanently removes posts trashed before the given moment
+func (r *PostRepo) Purge(ctx context.Context, before time.Time) (int, error) {
+	result := r.db.TxDB(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Delete(&model.Post{})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge posts: %w", result.Error)
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+/*
+PublishDue publishes up to limit scheduled posts due at now in a single statement and returns them.
+SKIP LOCKED lets the schedulers of several API replicas claim disjoint batches, so every post is published once.
+*/
+func (r *PostRepo) PublishDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error) {
+	var posts []*model.Post
+	err := r.db.TxDB(ctx).Raw(`
+		UPDATE posts SET published = TRUE, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE published = FALSE AND held = FALSE AND expired = FALSE AND publish_at <= ?
+				AND (unpublish_at IS NULL OR unpublish_at > ?) AND deleted_at IS NULL
+			ORDER BY publish_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now, now, now, limit,
+	).Scan(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish due posts: %w", err)
+	}
+	return posts, nil
+}
+
+// ExpireDue takes down up to limit posts whose unpublish_at has come, claimed the same way as in PublishDue
+func (r *PostRepo) ExpireDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error) {
+	var posts []*model.Post
+	err := r.db.TxDB(ctx).Raw(`
+		UPDATE posts SET published = FALSE, expired = TRUE, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE expired = FALSE AND unpublish_at <= ? AND deleted_at IS NULL
+			ORDER BY unpublish_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now, now, limit,
+	).Scan(&posts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire due posts: %w", err)
+	}
+	return posts, nil
+}
+
+// LoadAuthors fills in the author summaries of the given posts with a single query
+func (r *PostRepo) LoadAuthors(ctx context.Context, posts []*model.Post) error {
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.AuthorID)
+	}
+
+	authors, err := userSummaries(r.db.TxDB(ctx), ids)
+	if err != nil {
+		return fmt.Errorf("failed to load post authors: %w", err)
+	}
+
+	for _, post := range posts {
+		post.Author = authors[post.AuthorID]
+	}
+	return nil
+}
+
+// LoadCommentCounts fills in the number of live comments of the given posts with a single grouped query
+func (r *PostRepo) LoadCommentCounts(ctx context.Context, posts []*model.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	var rows []struct {
+		PostID int
+		Count  int
+	}
+	err := r.db.TxDB(ctx).
+		Model(&model.Comment{}).
+		Select("post_id, COUNT(*) AS count").
+		Where("post_id IN ? AND status = ?", uniqueIDs(ids), model.CommentStatusApproved).
+		Group("post_id").
+		Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to load post comment counts: %w", err)
+	}
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.PostID] = row.Count
+	}
+	for _, post := range posts {
+		count := counts[post.ID]
+		post.CommentCount = &count
+	}
+	return nil
+}
+
 func (r *PostRepo) applyFilters(db *gorm.DB, filter *PostFilter) *gorm.DB {
 	if filter == nil {
 		return db
 	}
 
+	if filter.Trashed {
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
 	if filter.AuthorID != nil {
 		db = db.Where("author_id = ?", *filter.AuthorID)
 	}
 
+	// a single array parameter keeps the statement small for feeds of thousands of authors
+	if len(filter.AuthorIDs) > 0 {
+		db = db.Where("author_id = ANY(?)", pq.Array(filter.AuthorIDs))
+	}
+
 	if filter.Published != nil {
 		db = db.Where("published = ?", *filter.Published)
 	}
 
+	if filter.Held != nil {
+		db = db.Where("held = ?", *filter.Held)
+	}
+
+	if filter.Expired != nil {
+		db = db.Where("expired = ?", *filter.Expired)
+	}
+
 	if filter.DueBefore != nil {
 		db = db.Where("publish_at <= ?", *filter.DueBefore)
 	}
 
+	if filter.ExpiresBefore != nil {
+		db = db.Where("unpublish_at <= ?", *filter.ExpiresBefore)
+	}
+
+	if filter.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+
+	if filter.CreatedTo != nil {
+		db = db.Where("created_at <= ?", *filter.CreatedTo)
+	}
+
+	if filter.HasComments != nil {
+		if *filter.HasComments {
+			db = db.Where("EXISTS (SELECT 1 " + liveCommentsSubquery + ")")
+		} else {
+			db = db.Where("NOT EXISTS (SELECT 1 " + liveCommentsSubquery + ")")
+		}
+	}
+
 	return db
 }
+
+func (r *PostRepo) applySort(db *gorm.DB, filter *PostFilter) (*gorm.DB, error) {
+	if filter == nil || filter.Sort == nil {
+		return db.Order("created_at DESC").Order("id DESC"), nil
+	}
+
+	column, ok := postSortColumns[filter.Sort.Field]
+	if !ok {
+		return nil, fmt.Errorf("unsupported post sort field: %s", filter.Sort.Field)
+	}
+
+	direction := "ASC"
+	if filter.Sort.Desc {
+		direction = "DESC"
+	}
+
+	return db.Order(column + " " + direction + " NULLS LAST").Order("id " + direction), nil
+}
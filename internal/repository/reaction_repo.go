@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var (
+	ErrReactionNotFound       = errors.New("reaction not found")
+	ErrReactionTargetNotFound = errors.New("reaction target not found")
+)
+
+// reactionTables maps reaction targets to the tables holding their denormalized counts
+var reactionTables = map[string]string{
+	model.ReactionTargetPost:    "posts",
+	model.ReactionTargetComment: "comments",
+}
+
+type ReactionRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewReactionRepo(db *database.DatabaseManager) *ReactionRepo {
+	return &ReactionRepo{db: db}
+}
+
+// Set creates or replaces the reaction of the user and refreshes the counts of the target
+func (r *ReactionRepo) Set(ctx context.Context, reaction *model.Reaction) (model.ReactionCounts, error) {
+	var counts model.ReactionCounts
+	err := r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		db := r.db.TxDB(ctx)
+		if err := lockReactionTarget(db, reaction.TargetType, reaction.TargetID); err != nil {
+			return err
+		}
+
+		err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "target_type"}, {Name: "target_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"kind", "updated_at"}),
+		}).Create(reaction).Error
+		if err != nil {
+			return fmt.Errorf("failed to save reaction: %w", err)
+		}
+
+		counts, err = refreshReactionCounts(db, reaction.TargetType, reaction.TargetID)
+		return err
+	})
+	return counts, err
+}
+
+// Delete removes the reaction of the user and refreshes the counts of the target
+func (r *ReactionRepo) Delete(ctx context.Context, targetType string, targetID, userID int) (model.ReactionCounts, error) {
+	var counts model.ReactionCounts
+	err := r.db.WithinTransaction(ctx, func(ctx context.Context) error {
+		db := r.db.TxDB(ctx)
+		if err := lockReactionTarget(db, targetType, targetID); err != nil {
+			return err
+		}
+
+		result := db.Where("target_type = ? AND target_id = ? AND user_id = ?", targetType, targetID, userID).
+			Delete(&model.Reaction{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete reaction: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrReactionNotFound
+		}
+
+		var err error
+		counts, err = refreshReactionCounts(db, targetType, targetID)
+		return err
+	})
+	return counts, err
+}
+
+// lockReactionTarget serializes reactions of a single target so that the recount sees every committed change
+func lockReactionTarget(db *gorm.DB, targetType string, targetID int) error {
+	table, ok := reactionTables[targetType]
+	if !ok {
+		return fmt.Errorf("unsupported reaction target: %s", targetType)
+	}
+
+	var ids []int
+	if err := db.Raw("SELECT id FROM "+table+" WHERE id = ? FOR UPDATE", targetID).Scan(&ids).Error; err != nil {
+		return fmt.Errorf("failed to lock reaction target: %w", err)
+	}
+	if len(ids) == 0 {
+		return ErrReactionTargetNotFound
+	}
+	return nil
+}
+
+func refreshReactionCounts(db *gorm.DB, targetType string, targetID int) (model.ReactionCounts, error) {
+	var rows []struct {
+		Kind  string
+		Count int
+	}
+	err := db.Model(&model.Reaction{}).
+		Select("kind, COUNT(*) AS count").
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Group("kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reactions: %w", err)
+	}
+
+	counts := model.ReactionCounts{}
+	total := 0
+	for _, row := range rows {
+		counts[row.Kind] = row.Count
+		total += row.Count
+	}
+
+	// UpdateColumns keeps updated_at, a reaction is not an edit of the target
+	err = db.Table(reactionTables[targetType]).Where("id = ?", targetID).UpdateColumns(map[string]any{
+		"reactions":      counts,
+		"reaction_count": total,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update reaction counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// DeleteOrphaned deletes the reactions to posts and comments that were hard deleted, target_id has no foreign key
+func (r *ReactionRepo) DeleteOrphaned(ctx context.Context) (int, error) {
+	deleted := 0
+	for targetType, table := range reactionTables {
+		result := r.db.TxDB(ctx).
+			Where("target_type = ? AND NOT EXISTS (SELECT 1 FROM "+table+" WHERE "+table+".id = reactions.target_id)", targetType).
+			Delete(&model.Reaction{})
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to delete orphaned reactions: %w", result.Error)
+		}
+		deleted += int(result.RowsAffected)
+	}
+	return deleted, nil
+}
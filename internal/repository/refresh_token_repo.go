@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"blog-api/internal/model"
 
@@ -66,6 +67,17 @@ func (r *RefreshTokenRepo) DeleteByUserID(
 		Delete(&model.RefreshToken{}).Error
 }
 
+// DeleteExpired removes the tokens expired before the given moment
+func (r *RefreshTokenRepo) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	result := r.db.TxDB(ctx).
+		Where("expires_at < ?", before).
+		Delete(&model.RefreshToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
+
 func (r *RefreshTokenRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	return r.db.WithinTransaction(ctx, fn)
 }
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var (
+	ErrReportNotFound = errors.New("report not found")
+	ErrReportExists   = errors.New("report already exists")
+)
+
+// reportTables maps report targets to their tables
+var reportTables = map[string]string{
+	model.ReportTargetPost:    "posts",
+	model.ReportTargetComment: "comments",
+}
+
+// ReportFilter defines optional filters for fetching reports.
+type ReportFilter struct {
+	Status     *string
+	TargetType *string
+	TargetID   *int
+}
+
+type ReportRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewReportRepo(db *database.DatabaseManager) *ReportRepo {
+	return &ReportRepo{db: db}
+}
+
+// Create stores the report, a second report of the same user on the target is ErrReportExists
+func (r *ReportRepo) Create(ctx context.Context, report *model.Report) error {
+	result := r.db.TxDB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create report: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrReportExists
+	}
+	return nil
+}
+
+func (r *ReportRepo) GetByID(ctx context.Context, id int) (*model.Report, error) {
+	var report model.Report
+	err := r.db.TxDB(ctx).First(&report, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get report by ID: %w", err)
+	}
+	return &report, nil
+}
+
+// GetReports returns the matching reports, oldest first
+func (r *ReportRepo) GetReports(ctx context.Context, filter *ReportFilter, limit, offset int) ([]*model.Report, error) {
+	var reports []*model.Report
+	db := r.applyFilters(r.db.TxDB(ctx), filter).Order("created_at ASC").Order("id ASC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reports: %w", err)
+	}
+	return reports, nil
+}
+
+func (r *ReportRepo) GetReportsCount(ctx context.Context, filter *ReportFilter) (int, error) {
+	var count int64
+	err := r.applyFilters(r.db.TxDB(ctx), filter).Model(&model.Report{}).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+	return int(count), nil
+}
+
+// Close moves all open reports on the target to the given status and returns their number
+func (r *ReportRepo) Close(ctx context.Context, targetType string, targetID int, status string, moderatorID int) (int, error) {
+	result := r.db.TxDB(ctx).
+		Model(&model.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, model.ReportStatusOpen).
+		Updates(map[string]any{
+			"status":      status,
+			"resolved_by": moderatorID,
+			"resolved_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to close reports: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+func (r *ReportRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.WithinTransaction(ctx, fn)
+}
+
+func (r *ReportRepo) applyFilters(db *gorm.DB, filter *ReportFilter) *gorm.DB {
+	if filter == nil {
+		return db
+	}
+	if filter.Status != nil {
+		db = db.Where("status = ?", *filter.Status)
+	}
+	if filter.TargetType != nil {
+		db = db.Where("target_type = ?", *filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		db = db.Where("target_id = ?", *filter.TargetID)
+	}
+	return db
+}
+
+// DeleteOrphaned deletes the reports on posts and comments that were hard deleted, target_id has no foreign key
+func (r *ReportRepo) DeleteOrphaned(ctx context.Context) (int, error) {
+	deleted := 0
+	for targetType, table := range reportTables {
+		result := r.db.TxDB(ctx).
+			Where("target_type = ? AND NOT EXISTS (SELECT 1 FROM "+table+" WHERE "+table+".id = reports.target_id)", targetType).
+			Delete(&model.Report{})
+		if result.Error != nil {
+			return 0, fmt.Errorf("failed to delete orphaned reports: %w", result.Error)
+		}
+		deleted += int(result.RowsAffected)
+	}
+	return deleted, nil
+}
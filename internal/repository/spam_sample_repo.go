@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+type SpamSampleRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewSpamSampleRepo(db *database.DatabaseManager) *SpamSampleRepo {
+	return &SpamSampleRepo{db: db}
+}
+
+func (r *SpamSampleRepo) Create(ctx context.Context, sample *model.SpamSample) error {
+	if err := r.db.TxDB(ctx).Create(sample).Error; err != nil {
+		return fmt.Errorf("failed to create spam sample: %w", err)
+	}
+	return nil
+}
+
+// GetLatest returns up to limit samples, newest first
+func (r *SpamSampleRepo) GetLatest(ctx context.Context, limit int) ([]*model.SpamSample, error) {
+	var samples []*model.SpamSample
+	db := r.db.TxDB(ctx).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if err := db.Find(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to get spam samples: %w", err)
+	}
+	return samples, nil
+}
@@ -90,3 +90,34 @@ func (r *UserRepo) Delete(ctx context.Context, id int) error {
 func (r *UserRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	return r.db.WithinTransaction(ctx, fn)
 }
+
+// userSummaries fetches the public profiles of the given users with a single IN query
+func userSummaries(db *gorm.DB, ids []int) (map[int]*model.UserSummary, error) {
+	summaries := make(map[int]*model.UserSummary)
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	var rows []*model.UserSummary
+	if err := db.Model(&model.User{}).Select("id, username").Where("id IN ?", ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		summaries[row.ID] = row
+	}
+	return summaries, nil
+}
+
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]struct{}, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookDeliveryFilter defines optional filters for fetching the deliveries of a webhook.
+type WebhookDeliveryFilter struct {
+	WebhookID int
+	Status    *string
+}
+
+type WebhookDeliveryRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewWebhookDeliveryRepo(db *database.DatabaseManager) *WebhookDeliveryRepo {
+	return &WebhookDeliveryRepo{db: db}
+}
+
+func (r *WebhookDeliveryRepo) Create(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := r.db.TxDB(ctx).Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) GetByID(ctx context.Context, id int) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.TxDB(ctx).First(&delivery, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery by ID: %w", err)
+	}
+	return &delivery, nil
+}
+
+// GetDeliveries returns the matching deliveries, newest first
+func (r *WebhookDeliveryRepo) GetDeliveries(ctx context.Context, filter *WebhookDeliveryFilter, limit, offset int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	db := r.applyFilters(r.db.TxDB(ctx), filter).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookDeliveryRepo) GetDeliveriesCount(ctx context.Context, filter *WebhookDeliveryFilter) (int, error) {
+	var count int64
+	err := r.applyFilters(r.db.TxDB(ctx), filter).Model(&model.WebhookDelivery{}).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+	return int(count), nil
+}
+
+/*
+Claim takes up to limit pending deliveries due at now and hides them from other dispatchers until leaseUntil.
+SKIP LOCKED lets several API replicas claim disjoint batches; a dispatcher that dies mid-batch
+leaves its deliveries to be claimed again once the lease is over.
+*/
+func (r *WebhookDeliveryRepo) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := r.db.TxDB(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		leaseUntil, model.WebhookDeliveryPending, now, limit,
+	).Scan(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Update saves the outcome of a delivery attempt
+func (r *WebhookDeliveryRepo) Update(ctx context.Context, delivery *model.WebhookDelivery) error {
+	result := r.db.TxDB(ctx).Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "response_code", "last_error", "delivered_at").
+		Updates(delivery)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepo) applyFilters(db *gorm.DB, filter *WebhookDeliveryFilter) *gorm.DB {
+	db = db.Where("webhook_id = ?", filter.WebhookID)
+	if filter.Status != nil {
+		db = db.Where("status = ?", *filter.Status)
+	}
+	return db
+}
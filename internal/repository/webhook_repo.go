@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"blog-api/internal/model"
+	"blog-api/pkg/database"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookRepo struct {
+	db *database.DatabaseManager
+}
+
+func NewWebhookRepo(db *database.DatabaseManager) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, webhook *model.Webhook) error {
+	if err := r.db.TxDB(ctx).Create(webhook).Error; err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) GetByID(ctx context.Context, id int) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := r.db.TxDB(ctx).First(&webhook, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook by ID: %w", err)
+	}
+	return &webhook, nil
+}
+
+// GetByOwnerID returns the webhooks of the user, newest first
+func (r *WebhookRepo) GetByOwnerID(ctx context.Context, ownerID int, limit, offset int) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	db := r.db.TxDB(ctx).Where("owner_id = ?", ownerID).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	if offset > 0 {
+		db = db.Offset(offset)
+	}
+	if err := db.Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) GetCountByOwnerID(ctx context.Context, ownerID int) (int, error) {
+	var count int64
+	err := r.db.TxDB(ctx).Model(&model.Webhook{}).Where("owner_id = ?", ownerID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetSubscribed returns the active webhooks listening to the event on the content of the owner
+func (r *WebhookRepo) GetSubscribed(ctx context.Context, event string, ownerID int) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	err := r.db.TxDB(ctx).
+		Where("active AND ? = ANY(events) AND (owner_id = ? OR all_users)", event, ownerID).
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribed webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Delete removes the webhook together with its deliveries
+func (r *WebhookRepo) Delete(ctx context.Context, id int) error {
+	result := r.db.TxDB(ctx).Delete(&model.Webhook{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
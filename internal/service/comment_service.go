@@ -2,31 +2,79 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"blog-api/internal/model"
 	"blog-api/internal/repository"
+	"blog-api/pkg/contentfilter"
+	"blog-api/pkg/markup"
+	"blog-api/pkg/settings"
 )
 
+// config
+type CommentConfig struct {
+	MaxDepth      int  // replies nesting limit, top level comments have depth 0
+	Premoderation bool // hold all new comments for review
+	AutoCloseDays int  // close comments this many days after publication unless the post overrides it, 0 keeps them open
+	EditWindow    int  // minutes after creation the author may edit a comment, 0 allows edits at any time
+}
+
+func (c *CommentConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "COMMENTS_MAX_DEPTH", Default: 5, Field: &c.MaxDepth},
+		settings.Item[bool]{Name: "COMMENTS_PREMODERATION", Default: false, Field: &c.Premoderation},
+		settings.Item[int]{Name: "COMMENTS_AUTO_CLOSE_DAYS", Default: 0, Field: &c.AutoCloseDays},
+		settings.Item[int]{Name: "COMMENTS_EDIT_WINDOW_MINUTES", Default: 0, Field: &c.EditWindow},
+	}
+}
+
 var (
-	ErrCommentNotFound = errors.New("comment not found")
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrInvalidParent      = errors.New("parent comment not found in this post")
+	ErrReplyDepthExceeded = errors.New("reply depth limit reached")
+	ErrCommentsClosed     = errors.New("comments are closed for this post")
+	ErrCommentsRestricted = errors.New("comments on this post are limited to established members")
+	ErrEditWindowExpired  = errors.New("comment can no longer be edited")
 )
 
 type CommentService struct {
-	commentRepo repository.CommentRepository
-	postRepo    repository.PostRepository
-	userRepo    repository.UserRepository
+	commentRepo   repository.CommentRepository
+	revisionRepo  repository.CommentRevisionRepository
+	postRepo      repository.PostRepository
+	userRepo      repository.UserRepository
+	eventRepo     repository.EventRepository
+	renderer      *markup.Renderer
+	config        *CommentConfig
+	filter        *contentfilter.Pipeline
+	notifications *NotificationService
+	webhooks      *WebhookService
 }
 
 func NewCommentService(
 	commentRepo repository.CommentRepository,
+	revisionRepo repository.CommentRevisionRepository,
 	postRepo repository.PostRepository,
 	userRepo repository.UserRepository,
+	eventRepo repository.EventRepository,
+	config *CommentConfig,
+	filter *contentfilter.Pipeline,
+	notifications *NotificationService,
+	webhooks *WebhookService,
 ) *CommentService {
 	return &CommentService{
-		commentRepo: commentRepo,
-		postRepo:    postRepo,
-		userRepo:    userRepo,
+		commentRepo:   commentRepo,
+		revisionRepo:  revisionRepo,
+		postRepo:      postRepo,
+		userRepo:      userRepo,
+		eventRepo:     eventRepo,
+		renderer:      markup.NewCommentRenderer(),
+		config:        config,
+		filter:        filter,
+		notifications: notifications,
+		webhooks:      webhooks,
 	}
 }
 
@@ -39,7 +87,7 @@ func (s *CommentService) Create(
 
 	published := true
 
-	_, err := s.postRepo.GetPost(
+	post, err := s.postRepo.GetPost(
 		ctx,
 		postID, &repository.PostFilter{Published: &published},
 	)
@@ -53,13 +101,60 @@ func (s *CommentService) Create(
 		return nil, ErrDatabase
 	}
 
+	if err := s.checkCommentsPolicy(ctx, post, userID); err != nil {
+		return nil, err
+	}
+
+	format := req.ContentFormat
+	if format == "" {
+		format = string(markup.FormatPlain)
+	}
+
+	contentHTML, err := s.renderer.Render(markup.Format(format), req.Content)
+	if err != nil {
+		logger.Info("failed to render comment content for user_id=%d: %v", userID, err)
+		return nil, ErrUnsupportedContentFormat
+	}
+
 	comment := &model.Comment{
-		Content:  req.Content,
-		PostID:   postID,
+		Content:       req.Content,
+		ContentFormat: format,
+		ContentHTML:   contentHTML,
+		PostID:        postID,
+		AuthorID:      userID,
+		Status:        s.initialStatus(ctx, post, userID),
+	}
+
+	if req.ParentID != nil {
+		if err := s.attachParent(ctx, comment, *req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	verdict, err := checkContent(ctx, s.filter, &contentfilter.Item{
+		Kind:     contentfilter.KindComment,
 		AuthorID: userID,
+		Text:     comment.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verdict == contentfilter.Hold {
+		comment.Status = model.CommentStatusPending
+	}
+
+	comment.Mentions, err = resolveMentions(ctx, s.userRepo, comment.Content)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.commentRepo.Create(ctx, comment); err != nil {
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.commentRepo.Create(ctx, comment); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.eventRepo, model.AggregateComment, comment.ID, model.EventCommentCreated, comment)
+	})
+	if err != nil {
 		logger.Error("failed to create comment for post_id=%d, user_id=%d: %v", postID, userID, err)
 		return nil, ErrDatabase
 	}
@@ -67,6 +162,34 @@ func (s *CommentService) Create(
 	return comment, nil
 }
 
+/*
+AnnounceCreated is the relay handler of comment.created and comment.approved: the author of the post and
+of the parent comment are notified and the webhooks of the post author receive the comment.
+Comments waiting for review are announced on approval.
+*/
+func (s *CommentService) AnnounceCreated(ctx context.Context, event *model.Event) error {
+	var comment model.Comment
+	if err := json.Unmarshal(event.Payload, &comment); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+	if comment.Status != model.CommentStatusApproved {
+		return nil
+	}
+
+	post, err := s.postRepo.GetPost(ctx, comment.PostID, nil)
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			logger.Info("post id=%d of comment id=%d is gone, not announcing it", comment.PostID, comment.ID)
+			return nil
+		}
+		return fmt.Errorf("failed to fetch post id=%d of comment id=%d: %w", comment.PostID, comment.ID, err)
+	}
+
+	s.notifications.NotifyComment(ctx, &comment)
+	s.webhooks.Emit(ctx, model.WebhookCommentCreated, post.AuthorID, &comment)
+	return nil
+}
+
 func (s *CommentService) GetByID(ctx context.Context, id int) (*model.Comment, error) {
 	comment, err := s.commentRepo.GetByID(ctx, id)
 	if err != nil {
@@ -80,23 +203,101 @@ func (s *CommentService) GetByID(ctx context.Context, id int) (*model.Comment, e
 	return comment, nil
 }
 
+// GetByPost returns the comments of a published post as a flat list, oldest first
 func (s *CommentService) GetByPost(
 	ctx context.Context,
 	postID int,
+	viewerID *int,
+	pagination *model.PaginationParams,
+) ([]*model.Comment, int, error) {
+	return s.listComments(ctx, postID, threadFilter(postID, viewerID, false), pagination)
+}
+
+// GetByPostByCursor returns the comments of a published post as a flat list using keyset pagination
+func (s *CommentService) GetByPostByCursor(
+	ctx context.Context,
+	postID int,
+	viewerID *int,
+	pagination *model.PaginationParams,
+) (*model.CursorPage[*model.Comment], error) {
+	return s.listCommentsByCursor(ctx, postID, threadFilter(postID, viewerID, false), pagination)
+}
+
+// GetTreeByPost pages over top level comments of a published post and nests their replies
+func (s *CommentService) GetTreeByPost(
+	ctx context.Context,
+	postID int,
+	viewerID *int,
 	pagination *model.PaginationParams,
 ) ([]*model.Comment, int, error) {
+	roots, total, err := s.listComments(ctx, postID, threadFilter(postID, viewerID, true), pagination)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	if err := s.ensurePostPublished(ctx, postID); err != nil {
+	roots, err = s.nestReplies(ctx, postID, viewerID, roots)
+	if err != nil {
 		return nil, 0, err
 	}
 
-	comments, err := s.commentRepo.GetByPostID(ctx, postID, *pagination.Limit, *pagination.Offset)
+	return roots, total, nil
+}
+
+// GetTreeByPostByCursor is the keyset paginated counterpart of GetTreeByPost
+func (s *CommentService) GetTreeByPostByCursor(
+	ctx context.Context,
+	postID int,
+	viewerID *int,
+	pagination *model.PaginationParams,
+) (*model.CursorPage[*model.Comment], error) {
+	page, err := s.listCommentsByCursor(ctx, postID, threadFilter(postID, viewerID, true), pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Items, err = s.nestReplies(ctx, postID, viewerID, page.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// threadFilter selects approved comments of the post along with the viewer's own pending ones
+func threadFilter(postID int, viewerID *int, topLevel bool) *repository.CommentFilter {
+	return &repository.CommentFilter{
+		PostID:         &postID,
+		Statuses:       []string{model.CommentStatusApproved},
+		PendingOf:      viewerID,
+		TopLevel:       topLevel,
+		WithTombstones: true,
+		OldestFirst:    true,
+	}
+}
+
+func (s *CommentService) listComments(
+	ctx context.Context,
+	postID int,
+	filter *repository.CommentFilter,
+	pagination *model.PaginationParams,
+) ([]*model.Comment, int, error) {
+
+	if _, err := s.publishedPost(ctx, postID); err != nil {
+		return nil, 0, err
+	}
+
+	comments, err := s.commentRepo.GetComments(ctx, filter, *pagination.Limit, *pagination.Offset)
 	if err != nil {
 		logger.Error("failed to fetch comments for post_id=%d: %v", postID, err)
 		return nil, 0, ErrDatabase
 	}
+	buryComments(comments, filter.PendingOf)
+
+	if !pagination.CountTotal() {
+		return comments, 0, nil
+	}
 
-	total, err := s.commentRepo.GetCountByPostID(ctx, postID)
+	total, err := s.commentRepo.GetCommentsCount(ctx, filter)
 	if err != nil {
 		logger.Error("failed to count comments for post_id=%d: %v", postID, err)
 		return nil, 0, ErrDatabase
@@ -105,6 +306,162 @@ func (s *CommentService) GetByPost(
 	return comments, total, nil
 }
 
+func (s *CommentService) listCommentsByCursor(
+	ctx context.Context,
+	postID int,
+	filter *repository.CommentFilter,
+	pagination *model.PaginationParams,
+) (*model.CursorPage[*model.Comment], error) {
+
+	cursor, err := decodeCursor(pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.publishedPost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.commentRepo.GetCommentsByCursor(ctx, filter, cursor, *pagination.Limit+1)
+	if err != nil {
+		logger.Error("failed to fetch comments by cursor for post_id=%d: %v", postID, err)
+		return nil, ErrDatabase
+	}
+	buryComments(comments, filter.PendingOf)
+
+	page := buildCursorPage(comments, cursor, *pagination.Limit, commentCursorKey)
+
+	if pagination.CountTotal() {
+		total, err := s.commentRepo.GetCommentsCount(ctx, filter)
+		if err != nil {
+			logger.Error("failed to count comments for post_id=%d: %v", postID, err)
+			return nil, ErrDatabase
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// nestReplies loads the threads of the given top level comments and arranges them into trees
+func (s *CommentService) nestReplies(
+	ctx context.Context,
+	postID int,
+	viewerID *int,
+	roots []*model.Comment,
+) ([]*model.Comment, error) {
+	if len(roots) == 0 {
+		return roots, nil
+	}
+
+	rootIDs := make([]int, 0, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+	}
+
+	filter := threadFilter(postID, viewerID, false)
+	filter.RootIDs = rootIDs
+
+	replies, err := s.commentRepo.GetComments(ctx, filter, 0, 0)
+	if err != nil {
+		logger.Error("failed to fetch replies for post_id=%d: %v", postID, err)
+		return nil, ErrDatabase
+	}
+	buryComments(replies, viewerID)
+
+	return buildCommentTree(roots, replies), nil
+}
+
+/*
+buildCommentTree hangs the replies (oldest first) under their parents.
+Tombstones left without any live descendant are dropped, a deleted branch has nothing to keep in shape.
+*/
+func buildCommentTree(roots, replies []*model.Comment) []*model.Comment {
+	nodes := make(map[int]*model.Comment, len(roots)+len(replies))
+	for _, c := range roots {
+		nodes[c.ID] = c
+	}
+	for _, c := range replies {
+		nodes[c.ID] = c
+	}
+
+	for _, c := range replies {
+		if c.ParentID == nil {
+			continue
+		}
+		if parent, ok := nodes[*c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+		}
+	}
+
+	return pruneTombstones(roots)
+}
+
+func pruneTombstones(comments []*model.Comment) []*model.Comment {
+	kept := make([]*model.Comment, 0, len(comments))
+	for _, c := range comments {
+		c.Replies = pruneTombstones(c.Replies)
+		if c.Tombstone && len(c.Replies) == 0 {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// buryComments turns removed or hidden comments into tombstones that keep only their place in the thread
+func buryComments(comments []*model.Comment, viewerID *int) {
+	for _, c := range comments {
+		if !c.DeletedAt.Valid && visibleTo(c, viewerID) {
+			continue
+		}
+		c.Tombstone = true
+		c.Content = ""
+		c.ContentHTML = ""
+		c.Mentions = nil
+		c.AuthorID = 0
+	}
+}
+
+func visibleTo(c *model.Comment, viewerID *int) bool {
+	if c.Status == model.CommentStatusApproved {
+		return true
+	}
+	return c.Status == model.CommentStatusPending && viewerID != nil && c.AuthorID == *viewerID
+}
+
+// flattenComments lists the comments along with all their nested replies
+func flattenComments(comments []*model.Comment) []*model.Comment {
+	var flat []*model.Comment
+	for _, c := range comments {
+		flat = append(flat, c)
+		flat = append(flat, flattenComments(c.Replies)...)
+	}
+	return flat
+}
+
+// ExpandComments embeds the requested related data into the comments using batched queries
+func (s *CommentService) ExpandComments(ctx context.Context, comments []*model.Comment, expand *model.ExpandParams) error {
+	if len(comments) == 0 || !expand.Has(model.ExpandAuthor) {
+		return nil
+	}
+
+	comments = flattenComments(comments)
+	if err := s.commentRepo.LoadAuthors(ctx, comments); err != nil {
+		logger.Error("failed to expand authors of %d comments: %v", len(comments), err)
+		return ErrDatabase
+	}
+
+	return nil
+}
+
+func commentCursorKey(comment *model.Comment) (time.Time, int) {
+	return comment.CreatedAt, comment.ID
+}
+
 func (s *CommentService) Update(ctx context.Context, id int, userID int, req *model.CommentUpdateRequest) (*model.Comment, error) {
 	comment, err := s.commentRepo.GetByID(ctx, id)
 	if err != nil {
@@ -114,7 +471,8 @@ func (s *CommentService) Update(ctx context.Context, id int, userID int, req *mo
 		return nil, ErrDatabase
 	}
 
-	if err := s.ensurePostPublished(ctx, comment.PostID); err != nil {
+	post, err := s.publishedPost(ctx, comment.PostID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -122,13 +480,134 @@ func (s *CommentService) Update(ctx context.Context, id int, userID int, req *mo
 		return nil, err
 	}
 
+	if err := s.checkCommentsPolicy(ctx, post, userID); err != nil {
+		return nil, err
+	}
+
+	if s.config.EditWindow > 0 && time.Since(comment.CreatedAt) > time.Duration(s.config.EditWindow)*time.Minute {
+		logger.Info("edit window of comment_id=%d is over for user_id=%d", id, userID)
+		return nil, fmt.Errorf("%w: edits are allowed for %d minutes after posting", ErrEditWindowExpired, s.config.EditWindow)
+	}
+
+	wasApproved := comment.Status == model.CommentStatusApproved
+	previousMentions := comment.Mentions
+
+	if req.Content != comment.Content {
+		verdict, err := checkContent(ctx, s.filter, &contentfilter.Item{
+			Kind:     contentfilter.KindComment,
+			ID:       comment.ID,
+			AuthorID: userID,
+			Text:     req.Content,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if verdict == contentfilter.Hold && comment.Status == model.CommentStatusApproved {
+			comment.Status = model.CommentStatusPending
+		}
+	}
+
+	previous := &model.CommentRevision{
+		CommentID:     comment.ID,
+		Revision:      comment.EditCount + 1,
+		Content:       comment.Content,
+		ContentFormat: comment.ContentFormat,
+	}
+
 	comment.Content = req.Content
-	if err := s.commentRepo.Update(ctx, comment); err != nil {
+	if req.ContentFormat != nil {
+		comment.ContentFormat = *req.ContentFormat
+	}
+	edited := comment.Content != previous.Content || comment.ContentFormat != previous.ContentFormat
+
+	contentHTML, err := s.renderer.Render(markup.Format(comment.ContentFormat), comment.Content)
+	if err != nil {
+		logger.Info("failed to render content of comment id=%d: %v", id, err)
+		return nil, ErrUnsupportedContentFormat
+	}
+	comment.ContentHTML = contentHTML
+
+	if edited {
+		now := time.Now()
+		comment.EditCount++
+		comment.EditedAt = &now
+		comment.Mentions, err = resolveMentions(ctx, s.userRepo, comment.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if edited {
+			if err := s.revisionRepo.Create(ctx, previous); err != nil {
+				return err
+			}
+		}
+		if err := s.commentRepo.Update(ctx, comment); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.eventRepo, model.AggregateComment, comment.ID, model.EventCommentUpdated, comment)
+	})
+	if err != nil {
+		logger.Error("failed to update comment id=%d: %v", id, err)
 		return nil, ErrDatabase
 	}
+
+	if comment.Status == model.CommentStatusApproved {
+		if !wasApproved {
+			previousMentions = nil
+		}
+		s.notifications.NotifyMentions(ctx, userID, comment.PostID, &comment.ID, previousMentions, comment.Mentions)
+	}
 	return comment, nil
 }
 
+// GetRevisions lists the replaced versions of a comment, newest first, to its author and moderators
+func (s *CommentService) GetRevisions(
+	ctx context.Context,
+	userID, postID, commentID int,
+	pagination *model.PaginationParams,
+) ([]*model.CommentRevision, int, error) {
+
+	if _, err := s.publishedPost(ctx, postID); err != nil {
+		return nil, 0, err
+	}
+
+	comment, err := s.commentRepo.GetComment(ctx, commentID, &repository.CommentFilter{PostID: &postID})
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			logger.Info("comment with id=%d not found in post_id=%d", commentID, postID)
+			return nil, 0, ErrCommentNotFound
+		}
+		logger.Error("failed to fetch comment id=%d: %v", commentID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if comment.AuthorID != userID {
+		if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	revisions, err := s.revisionRepo.GetByCommentID(ctx, commentID, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch revisions of comment id=%d: %v", commentID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return revisions, 0, nil
+	}
+
+	total, err := s.revisionRepo.GetCountByCommentID(ctx, commentID)
+	if err != nil {
+		logger.Error("failed to count revisions of comment id=%d: %v", commentID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return revisions, total, nil
+}
+
 func (s *CommentService) Delete(ctx context.Context, id int, userID int) error {
 	comment, err := s.commentRepo.GetByID(ctx, id)
 	if err != nil {
@@ -142,13 +621,87 @@ func (s *CommentService) Delete(ctx context.Context, id int, userID int) error {
 	if err := s.checkOwner(comment, userID); err != nil {
 		return err
 	}
-	if err := s.commentRepo.Delete(ctx, id); err != nil {
+
+	// comments of a trashed post are not visible, there is no policy to enforce
+	post, err := s.postRepo.GetPost(ctx, comment.PostID, nil)
+	if err != nil && !errors.Is(err, repository.ErrPostNotFound) {
+		logger.Error("failed to fetch post for post_id=%d: %v", comment.PostID, err)
+		return ErrDatabase
+	}
+	if post != nil {
+		if err := s.checkCommentsPolicy(ctx, post, userID); err != nil {
+			return err
+		}
+	}
+
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.commentRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.eventRepo, model.AggregateComment, id, model.EventCommentDeleted, comment)
+	})
+	if err != nil {
 		logger.Error("failed to delete comment id=%d: %v", id, err)
 		return ErrDatabase
 	}
 	return nil
 }
 
+// initialStatus holds the comment for review when premoderation applies to its author
+func (s *CommentService) initialStatus(ctx context.Context, post *model.Post, userID int) string {
+	if !s.config.Premoderation && !post.CommentsPremoderation {
+		return model.CommentStatusApproved
+	}
+	if post.AuthorID == userID {
+		return model.CommentStatusApproved
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			logger.Error("failed to fetch user_id=%d for premoderation: %v", userID, err)
+		}
+		return model.CommentStatusPending
+	}
+	if user.CanModerate() {
+		return model.CommentStatusApproved
+	}
+
+	return model.CommentStatusPending
+}
+
+// attachParent places the reply into the thread of its parent within the same post
+func (s *CommentService) attachParent(ctx context.Context, comment *model.Comment, parentID int) error {
+	parent, err := s.commentRepo.GetComment(ctx, parentID, &repository.CommentFilter{
+		PostID:    &comment.PostID,
+		Statuses:  []string{model.CommentStatusApproved},
+		PendingOf: &comment.AuthorID,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			logger.Info("parent comment id=%d not found in post_id=%d", parentID, comment.PostID)
+			return ErrInvalidParent
+		}
+		logger.Error("failed to fetch parent comment id=%d: %v", parentID, err)
+		return ErrDatabase
+	}
+
+	if parent.Depth+1 > s.config.MaxDepth {
+		logger.Info("reply to comment id=%d exceeds max depth=%d", parentID, s.config.MaxDepth)
+		return ErrReplyDepthExceeded
+	}
+
+	rootID := parent.ID
+	if parent.RootID != nil {
+		rootID = *parent.RootID
+	}
+
+	comment.ParentID = &parent.ID
+	comment.RootID = &rootID
+	comment.Depth = parent.Depth + 1
+	return nil
+}
+
 func (s *CommentService) checkOwner(comment *model.Comment, userID int) error {
 	if comment.AuthorID != userID {
 		logger.Info(
@@ -162,15 +715,57 @@ func (s *CommentService) checkOwner(comment *model.Comment, userID int) error {
 	return nil
 }
 
-func (s *CommentService) ensurePostPublished(ctx context.Context, postID int) error {
+func (s *CommentService) publishedPost(ctx context.Context, postID int) (*model.Post, error) {
 	published := true
-	_, err := s.postRepo.GetPost(ctx, postID, &repository.PostFilter{Published: &published})
+	post, err := s.postRepo.GetPost(ctx, postID, &repository.PostFilter{Published: &published})
 	if err != nil {
 		if errors.Is(err, repository.ErrPostNotFound) {
-			return ErrCommentNotFound
+			return nil, ErrCommentNotFound
 		}
 		logger.Error("failed to fetch post for post_id=%d: %v", postID, err)
+		return nil, ErrDatabase
+	}
+	return post, nil
+}
+
+// checkCommentsPolicy enforces the comment settings of the post, its author and moderators are exempt
+func (s *CommentService) checkCommentsPolicy(ctx context.Context, post *model.Post, userID int) error {
+	if post.AuthorID == userID {
+		return nil
+	}
+
+	now := time.Now()
+	closeAfter := s.config.AutoCloseDays
+	if post.CommentsCloseAfter != nil {
+		closeAfter = *post.CommentsCloseAfter
+	}
+	autoClosed := closeAfter > 0 && now.After(post.PublishedAt().AddDate(0, 0, closeAfter))
+
+	closed := autoClosed || post.CommentsPolicy == model.CommentsPolicyClosed
+	if !closed && post.CommentsPolicy != model.CommentsPolicyMembers {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		logger.Error("failed to fetch user_id=%d for comments policy: %v", userID, err)
 		return ErrDatabase
 	}
+	if user.CanModerate() {
+		return nil
+	}
+
+	if closed {
+		logger.Info("comments of post_id=%d are closed for user_id=%d (auto closed: %t)", post.ID, userID, autoClosed)
+		return ErrCommentsClosed
+	}
+
+	if now.Before(user.CreatedAt.AddDate(0, 0, post.CommentsMinAccountAge)) {
+		logger.Info("user_id=%d is too new to comment on post_id=%d", userID, post.ID)
+		return fmt.Errorf("%w: the account must be at least %d days old", ErrCommentsRestricted, post.CommentsMinAccountAge)
+	}
 	return nil
 }
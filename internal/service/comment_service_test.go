@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"blog-api/internal/model"
 	"blog-api/internal/repository"
@@ -17,7 +19,7 @@ func setupCommentServiceForTest() *CommentService {
 
 	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
 
-	return NewCommentService(commentRepo, postRepo, userRepo)
+	return NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, repository.NewInMemoryEventRepo(), &CommentConfig{MaxDepth: 5}, nil, nil, nil)
 }
 
 func TestCommentServiceCreate(t *testing.T) {
@@ -100,7 +102,7 @@ func TestCommentServiceGetByPost(t *testing.T) {
 	}
 
 	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
-	comments, total, err := svc.GetByPost(ctx, post.ID, pagination)
+	comments, total, err := svc.GetByPost(ctx, post.ID, nil, pagination)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -149,6 +151,58 @@ func TestCommentServiceUpdate(t *testing.T) {
 	}
 }
 
+func TestCommentServiceRevisions(t *testing.T) {
+	ctx := context.Background()
+	svc := setupCommentServiceForTest()
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	moderator := &model.User{Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator}
+	for _, u := range []*model.User{author, reader, moderator} {
+		svc.userRepo.Create(ctx, u)
+	}
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID}
+	svc.postRepo.Create(ctx, post)
+
+	comment, _ := svc.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "first"})
+	if comment.EditCount != 0 || comment.EditedAt != nil {
+		t.Fatalf("expected fresh comment to be unedited, got %d edits", comment.EditCount)
+	}
+
+	// saving the same content is not an edit
+	if _, err := svc.Update(ctx, comment.ID, reader.ID, &model.CommentUpdateRequest{Content: "first"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	svc.Update(ctx, comment.ID, reader.ID, &model.CommentUpdateRequest{Content: "second"})
+	updated, err := svc.Update(ctx, comment.ID, reader.ID, &model.CommentUpdateRequest{Content: "third"})
+	if err != nil || updated.EditCount != 2 || updated.EditedAt == nil {
+		t.Fatalf("expected 2 edits, got %+v err=%v", updated, err)
+	}
+
+	// author and moderators see the history, newest first
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	for _, userID := range []int{reader.ID, moderator.ID} {
+		revisions, total, err := svc.GetRevisions(ctx, userID, post.ID, comment.ID, pagination)
+		if err != nil || total != 2 {
+			t.Fatalf("expected 2 revisions for user_id=%d, got %d err=%v", userID, total, err)
+		}
+		if revisions[0].Content != "second" || revisions[0].Revision != 2 || revisions[1].Content != "first" {
+			t.Fatalf("unexpected revisions: %+v %+v", revisions[0], revisions[1])
+		}
+	}
+	if _, _, err := svc.GetRevisions(ctx, author.ID, post.ID, comment.ID, pagination); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden for the post author, got %v", err)
+	}
+
+	// edit window
+	svc.config.EditWindow = 15
+	updated.CreatedAt = time.Now().Add(-time.Hour) // the in-memory repo keeps the last saved record
+	if _, err := svc.Update(ctx, comment.ID, reader.ID, &model.CommentUpdateRequest{Content: "late"}); !errors.Is(err, ErrEditWindowExpired) {
+		t.Fatalf("expected ErrEditWindowExpired, got %v", err)
+	}
+}
+
 func TestCommentServiceDelete(t *testing.T) {
 	ctx := context.Background()
 	svc := setupCommentServiceForTest()
@@ -182,3 +236,231 @@ func TestCommentServiceDelete(t *testing.T) {
 		t.Fatalf("expected ErrForbidden, got %v", err)
 	}
 }
+
+func TestCommentServiceContentFormat(t *testing.T) {
+	ctx := context.Background()
+	svc := setupCommentServiceForTest()
+
+	userID := 1
+	post := &model.Post{
+		Title:     "Post",
+		Content:   "Content",
+		Published: true,
+		AuthorID:  userID,
+	}
+	if err := svc.postRepo.Create(ctx, post); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	// comments get a limited subset: no headings or images
+	comment, err := svc.Create(ctx, userID, post.ID, &model.CommentCreateRequest{
+		Content:       "# Heading\n\n*nice* ![img](https://example.com/a.png) <script>x()</script>",
+		ContentFormat: "markdown",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(comment.ContentHTML, "<em>nice</em>") {
+		t.Fatalf("expected emphasis in rendered comment, got %q", comment.ContentHTML)
+	}
+	for _, banned := range []string{"<h1", "<img", "<script"} {
+		if strings.Contains(comment.ContentHTML, banned) {
+			t.Fatalf("unexpected %q in rendered comment: %q", banned, comment.ContentHTML)
+		}
+	}
+
+	// update keeps the format and re-renders
+	updated, err := svc.Update(ctx, comment.ID, userID, &model.CommentUpdateRequest{Content: "**bold**"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.ContentHTML != "<p><strong>bold</strong></p>" {
+		t.Fatalf("unexpected rendered comment: %q", updated.ContentHTML)
+	}
+}
+
+func TestCommentServiceReplies(t *testing.T) {
+	ctx := context.Background()
+	svc := setupCommentServiceForTest()
+	svc.config.MaxDepth = 2
+
+	userID := 1
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: userID}
+	other := &model.Post{Title: "Other", Content: "Content", Published: true, AuthorID: userID}
+	svc.postRepo.Create(ctx, post)
+	svc.postRepo.Create(ctx, other)
+
+	reply := func(parentID *int, content string) (*model.Comment, error) {
+		return svc.Create(ctx, userID, post.ID, &model.CommentCreateRequest{Content: content, ParentID: parentID})
+	}
+
+	root, _ := reply(nil, "root")
+	child, err := reply(&root.ID, "child")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	grandchild, err := reply(&child.ID, "grandchild")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if grandchild.Depth != 2 || *grandchild.RootID != root.ID || *grandchild.ParentID != child.ID {
+		t.Fatalf("unexpected thread position: depth=%d root=%v parent=%v", grandchild.Depth, grandchild.RootID, grandchild.ParentID)
+	}
+
+	// depth limit
+	if _, err := reply(&grandchild.ID, "too deep"); !errors.Is(err, ErrReplyDepthExceeded) {
+		t.Fatalf("expected ErrReplyDepthExceeded, got %v", err)
+	}
+
+	// parent of another post
+	foreign, _ := svc.Create(ctx, userID, other.ID, &model.CommentCreateRequest{Content: "foreign"})
+	if _, err := reply(&foreign.ID, "cross post"); !errors.Is(err, ErrInvalidParent) {
+		t.Fatalf("expected ErrInvalidParent, got %v", err)
+	}
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+
+	// tree
+	tree, total, err := svc.GetTreeByPost(ctx, post.ID, nil, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 1 || len(tree) != 1 || len(tree[0].Replies) != 1 || tree[0].Replies[0].Replies[0].ID != grandchild.ID {
+		t.Fatalf("unexpected tree: total=%d %+v", total, tree)
+	}
+
+	// deleting a parent leaves a tombstone
+	if err := svc.Delete(ctx, child.ID, userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tree, _, _ = svc.GetTreeByPost(ctx, post.ID, nil, pagination)
+	tomb := tree[0].Replies[0]
+	if !tomb.Tombstone || tomb.Content != "" || tomb.AuthorID != 0 || tomb.Replies[0].ID != grandchild.ID {
+		t.Fatalf("expected tombstone keeping its reply, got %+v", tomb)
+	}
+	flat, total, _ := svc.GetByPost(ctx, post.ID, nil, pagination)
+	if total != 3 || len(flat) != 3 || !flat[1].Tombstone {
+		t.Fatalf("expected flat list with a tombstone, got total=%d", total)
+	}
+
+	// a branch without live comments disappears
+	if err := svc.Delete(ctx, grandchild.ID, userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tree, _, _ = svc.GetTreeByPost(ctx, post.ID, nil, pagination)
+	if len(tree) != 1 || len(tree[0].Replies) != 0 {
+		t.Fatalf("expected dead branch to be pruned, got %+v", tree[0].Replies)
+	}
+	// a tombstone whose only reply is deleted is not listed nor counted
+	flat, total, _ = svc.GetByPost(ctx, post.ID, nil, pagination)
+	if total != 1 || len(flat) != 1 || flat[0].ID != root.ID {
+		t.Fatalf("expected only the root to be listed, got total=%d %+v", total, flat)
+	}
+}
+
+func TestCommentServicePolicy(t *testing.T) {
+	ctx := context.Background()
+	svc := setupCommentServiceForTest()
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	veteran := &model.User{Username: "veteran", Email: "veteran@example.com"}
+	newcomer := &model.User{Username: "newcomer", Email: "newcomer@example.com"}
+	for _, u := range []*model.User{author, veteran, newcomer} {
+		svc.userRepo.Create(ctx, u)
+	}
+	veteran.CreatedAt = time.Now().AddDate(0, 0, -30)
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID}
+	svc.postRepo.Create(ctx, post)
+
+	comment, err := svc.Create(ctx, newcomer.ID, post.ID, &model.CommentCreateRequest{Content: "first"})
+	if err != nil {
+		t.Fatalf("expected open post to accept comments, got %v", err)
+	}
+
+	// members only
+	post.CommentsPolicy = model.CommentsPolicyMembers
+	post.CommentsMinAccountAge = 7
+	if _, err := svc.Create(ctx, newcomer.ID, post.ID, &model.CommentCreateRequest{Content: "hi"}); !errors.Is(err, ErrCommentsRestricted) {
+		t.Fatalf("expected ErrCommentsRestricted, got %v", err)
+	}
+	if _, err := svc.Create(ctx, veteran.ID, post.ID, &model.CommentCreateRequest{Content: "hi"}); err != nil {
+		t.Fatalf("expected veteran to comment, got %v", err)
+	}
+
+	// closed, the post author is exempt
+	post.CommentsPolicy = model.CommentsPolicyClosed
+	if _, err := svc.Create(ctx, veteran.ID, post.ID, &model.CommentCreateRequest{Content: "hi"}); !errors.Is(err, ErrCommentsClosed) {
+		t.Fatalf("expected ErrCommentsClosed, got %v", err)
+	}
+	if _, err := svc.Update(ctx, comment.ID, newcomer.ID, &model.CommentUpdateRequest{Content: "edit"}); !errors.Is(err, ErrCommentsClosed) {
+		t.Fatalf("expected ErrCommentsClosed on update, got %v", err)
+	}
+	if err := svc.Delete(ctx, comment.ID, newcomer.ID); !errors.Is(err, ErrCommentsClosed) {
+		t.Fatalf("expected ErrCommentsClosed on delete, got %v", err)
+	}
+	if _, err := svc.Create(ctx, author.ID, post.ID, &model.CommentCreateRequest{Content: "closing note"}); err != nil {
+		t.Fatalf("expected author to comment on closed post, got %v", err)
+	}
+
+	// auto close by age, the post setting overrides the global one
+	post.CommentsPolicy = model.CommentsPolicyOpen
+	publishAt := time.Now().AddDate(0, 0, -10)
+	post.PublishAt = &publishAt
+	svc.config.AutoCloseDays = 7
+	if _, err := svc.Create(ctx, veteran.ID, post.ID, &model.CommentCreateRequest{Content: "late"}); !errors.Is(err, ErrCommentsClosed) {
+		t.Fatalf("expected auto closed comments, got %v", err)
+	}
+	post.CommentsCloseAfter = ptr(0)
+	if _, err := svc.Create(ctx, veteran.ID, post.ID, &model.CommentCreateRequest{Content: "late"}); err != nil {
+		t.Fatalf("expected post override to keep comments open, got %v", err)
+	}
+}
+
+func TestCommentServiceMentions(t *testing.T) {
+	ctx := context.Background()
+	svc := setupCommentServiceForTest()
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+	svc.notifications = NewNotificationService(notificationRepo, repository.NewInMemoryNotificationPreferenceRepo(), svc.postRepo, svc.commentRepo, nil)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	for _, u := range []*model.User{author, reader} {
+		svc.userRepo.Create(ctx, u)
+	}
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID}
+	svc.postRepo.Create(ctx, post)
+
+	relay := NewEventRelay(svc.eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("comment_announcements", svc.AnnounceCreated, model.EventCommentCreated, model.EventCommentApproved)
+
+	comment, err := svc.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "hello"})
+	if err != nil || len(comment.Mentions) != 0 {
+		t.Fatalf("expected no mentions, got %+v err=%v", comment, err)
+	}
+	relay.Relay(ctx)
+
+	updated, err := svc.Update(ctx, comment.ID, reader.ID, &model.CommentUpdateRequest{Content: "hello @author"})
+	if err != nil || len(updated.Mentions) != 1 || updated.Mentions[0].Start != 6 {
+		t.Fatalf("expected a mention of the author, got %+v err=%v", updated, err)
+	}
+	n := notificationRepo.All()
+	if len(n) != 2 || n[1].UserID != author.ID || n[1].Kind != model.NotificationMention || *n[1].CommentID != comment.ID {
+		t.Fatalf("expected the author to be notified about the mention, got %+v", n)
+	}
+
+	// repeating an existing mention does not notify again
+	svc.Update(ctx, comment.ID, reader.ID, &model.CommentUpdateRequest{Content: "hello again @author"})
+	if len(notificationRepo.All()) != 2 {
+		t.Fatalf("expected no new notifications, got %d", len(notificationRepo.All()))
+	}
+
+	// held comments mention nobody until approved
+	post.CommentsPremoderation = true
+	pending, _ := svc.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "@author?"})
+	relay.Relay(ctx)
+	if pending.Status != model.CommentStatusPending || len(notificationRepo.All()) != 2 {
+		t.Fatalf("expected pending comment without notifications, got %s, %d", pending.Status, len(notificationRepo.All()))
+	}
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/settings"
+)
+
+const commentStreamChannelPrefix = "comments:post:"
+
+// config
+type CommentStreamConfig struct {
+	HeartbeatSeconds int
+	BufferSize       int
+	ReplayLimit      int
+	RetryMillis      int
+}
+
+func (c *CommentStreamConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "COMMENT_STREAM_HEARTBEAT_SECONDS", Default: 15, Field: &c.HeartbeatSeconds},
+		settings.Item[int]{Name: "COMMENT_STREAM_BUFFER_SIZE", Default: 64, Field: &c.BufferSize},
+		settings.Item[int]{Name: "COMMENT_STREAM_REPLAY_LIMIT", Default: 500, Field: &c.ReplayLimit},
+		settings.Item[int]{Name: "COMMENT_STREAM_RETRY_MS", Default: 3000, Field: &c.RetryMillis},
+	}
+}
+
+func (c *CommentStreamConfig) Heartbeat() time.Duration {
+	return time.Duration(c.HeartbeatSeconds) * time.Second
+}
+
+// StartCommentStream keeps the stream subscribed to the broker, readers reconnect and resume after a broker failure
+func StartCommentStream(ctx context.Context, svc *CommentStreamService) {
+	logger.Info("comment stream started")
+	for {
+		if err := svc.Run(ctx); err != nil {
+			logger.Error("comment stream failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("comment stream stopped")
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// CommentSubscription receives the comment events of a post, Events is closed when the subscription is dropped
+type CommentSubscription struct {
+	Events <-chan *model.CommentStreamEvent
+
+	postID int
+	events chan *model.CommentStreamEvent
+}
+
+/*
+CommentStreamService pushes the changes of public comments to the readers of a post. The event relay
+publishes the outbox events to the broker, every replica receives them and hands them to its own
+readers. Readers that fall behind are dropped and resume from the outbox with Last-Event-ID.
+*/
+type CommentStreamService struct {
+	eventRepo repository.EventRepository
+	postRepo  repository.PostRepository
+	broker    broker.Broker
+	config    *CommentStreamConfig
+
+	mu          sync.Mutex
+	subscribers map[int]map[*CommentSubscription]struct{}
+}
+
+func NewCommentStreamService(
+	eventRepo repository.EventRepository,
+	postRepo repository.PostRepository,
+	broker broker.Broker,
+	config *CommentStreamConfig,
+) *CommentStreamService {
+	return &CommentStreamService{
+		eventRepo:   eventRepo,
+		postRepo:    postRepo,
+		broker:      broker,
+		config:      config,
+		subscribers: map[int]map[*CommentSubscription]struct{}{},
+	}
+}
+
+func (s *CommentStreamService) Config() *CommentStreamConfig {
+	return s.config
+}
+
+// Publish is the event relay handler forwarding comment events to the broker
+func (s *CommentStreamService) Publish(ctx context.Context, event *model.Event) error {
+	streamEvent, postID, err := toCommentStreamEvent(event)
+	if err != nil {
+		return err
+	}
+	if streamEvent == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(streamEvent)
+	if err != nil {
+		return fmt.Errorf("failed to encode comment stream event: %w", err)
+	}
+	return s.broker.Publish(ctx, commentStreamChannelPrefix+strconv.Itoa(postID), payload)
+}
+
+// Run hands the events received from the broker to the local readers until ctx is done or the broker fails
+func (s *CommentStreamService) Run(ctx context.Context) error {
+	messages, err := s.broker.Subscribe(ctx, commentStreamChannelPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer s.dropAll()
+
+	for msg := range messages {
+		postID, err := strconv.Atoi(strings.TrimPrefix(msg.Channel, commentStreamChannelPrefix))
+		if err != nil {
+			logger.Warn("unexpected comment stream channel %q", msg.Channel)
+			continue
+		}
+		var event model.CommentStreamEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			logger.Warn("failed to decode comment stream event on %s: %v", msg.Channel, err)
+			continue
+		}
+		s.broadcast(postID, &event)
+	}
+
+	if ctx.Err() == nil {
+		return errors.New("broker subscription closed")
+	}
+	return nil
+}
+
+/*
+Subscribe registers a reader of the published post. A positive lastEventID replays the events the reader
+missed since then from the outbox, up to the replay limit; the reader is registered before the replay so
+that nothing falls in between, events delivered both ways should be skipped by ID.
+*/
+func (s *CommentStreamService) Subscribe(
+	ctx context.Context,
+	postID int,
+	lastEventID int64,
+) (*CommentSubscription, []*model.CommentStreamEvent, error) {
+
+	published := true
+	if _, err := s.postRepo.GetPost(ctx, postID, &repository.PostFilter{Published: &published}); err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			return nil, nil, ErrPostNotFound
+		}
+		logger.Error("failed to fetch post id=%d: %v", postID, err)
+		return nil, nil, ErrDatabase
+	}
+
+	events := make(chan *model.CommentStreamEvent, max(s.config.BufferSize, 1))
+	sub := &CommentSubscription{Events: events, postID: postID, events: events}
+	s.mu.Lock()
+	if s.subscribers[postID] == nil {
+		s.subscribers[postID] = map[*CommentSubscription]struct{}{}
+	}
+	s.subscribers[postID][sub] = struct{}{}
+	s.mu.Unlock()
+
+	if lastEventID <= 0 {
+		return sub, nil, nil
+	}
+
+	filter := &repository.EventFilter{AfterID: lastEventID, AggregateType: model.AggregateComment, PostID: &postID}
+	recorded, err := s.eventRepo.GetEvents(ctx, filter, s.config.ReplayLimit)
+	if err != nil {
+		s.Unsubscribe(sub)
+		logger.Error("failed to replay comment events of post id=%d after id=%d: %v", postID, lastEventID, err)
+		return nil, nil, ErrDatabase
+	}
+
+	var missed []*model.CommentStreamEvent
+	for _, event := range recorded {
+		streamEvent, _, err := toCommentStreamEvent(event)
+		if err != nil {
+			logger.Warn("skipped event id=%d: %v", event.ID, err)
+			continue
+		}
+		if streamEvent != nil {
+			missed = append(missed, streamEvent)
+		}
+	}
+	return sub, missed, nil
+}
+
+// Unsubscribe removes the reader, unsubscribing twice is safe
+func (s *CommentStreamService) Unsubscribe(sub *CommentSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(sub)
+}
+
+// broadcast hands the event to the readers of the post, readers with a full buffer are dropped
+func (s *CommentStreamService) broadcast(postID int, event *model.CommentStreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers[postID] {
+		select {
+		case sub.events <- event:
+		default:
+			logger.Info("dropped slow comment stream reader of post id=%d", postID)
+			s.drop(sub)
+		}
+	}
+}
+
+func (s *CommentStreamService) drop(sub *CommentSubscription) {
+	subs, ok := s.subscribers[sub.postID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.subscribers, sub.postID)
+	}
+	close(sub.events)
+}
+
+func (s *CommentStreamService) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, subs := range s.subscribers {
+		for sub := range subs {
+			s.drop(sub)
+		}
+	}
+}
+
+/*
+toCommentStreamEvent turns an outbox comment event into what the readers of the post see. Comments that
+are not approved stay invisible: creating them is skipped and hiding an approved one reads as a deletion.
+Returns nil for events the readers should not see.
+*/
+func toCommentStreamEvent(event *model.Event) (*model.CommentStreamEvent, int, error) {
+	var comment model.Comment
+	if err := json.Unmarshal(event.Payload, &comment); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+
+	streamEvent := &model.CommentStreamEvent{ID: event.ID, Type: event.Type, Data: event.Payload}
+	switch {
+	case event.Type == model.EventCommentCreated && comment.Status != model.CommentStatusApproved,
+		event.Type == model.EventCommentApproved:
+		return nil, comment.PostID, nil
+	case event.Type == model.EventCommentUpdated && comment.Status != model.CommentStatusApproved,
+		event.Type == model.EventCommentDeleted:
+		ref, err := json.Marshal(map[string]int{"id": comment.ID, "post_id": comment.PostID})
+		if err != nil {
+			return nil, 0, err
+		}
+		streamEvent.Type = model.EventCommentDeleted
+		streamEvent.Data = ref
+	}
+	return streamEvent, comment.PostID, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/logging"
+)
+
+func TestCommentStreamService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	commentRepo.LinkPosts(postRepo)
+	eventRepo := repository.NewInMemoryEventRepo()
+
+	comments := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, eventRepo, &CommentConfig{MaxDepth: 5}, nil, nil, nil)
+	moderation := NewModerationService(commentRepo, postRepo, userRepo, repository.NewInMemorySpamSampleRepo(), repository.NewInMemoryModerationLogRepo(), eventRepo, nil)
+	memoryBroker := broker.NewMemoryBroker()
+	stream := NewCommentStreamService(eventRepo, postRepo, memoryBroker, &CommentStreamConfig{BufferSize: 2, ReplayLimit: 100})
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("comment_stream", stream.Publish, model.EventCommentCreated, model.EventCommentUpdated, model.EventCommentDeleted)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	for _, u := range []*model.User{author, reader} {
+		userRepo.Create(ctx, u)
+	}
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID, CommentsPremoderation: true}
+	draft := &model.Post{Title: "Draft", Content: "Content", AuthorID: author.ID}
+	for _, p := range []*model.Post{post, draft} {
+		postRepo.Create(ctx, p)
+	}
+
+	// drafts have no readers
+	if _, _, err := stream.Subscribe(ctx, draft.ID, 0); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+
+	sub, missed, err := stream.Subscribe(ctx, post.ID, 0)
+	if err != nil || len(missed) != 0 {
+		t.Fatalf("expected a subscription without replay, got %v err=%v", missed, err)
+	}
+
+	go stream.Run(ctx)
+	// the broker subscription starts in the background, ping the reader until it is up
+	ping, _ := json.Marshal(&model.CommentStreamEvent{Type: "ping", Data: json.RawMessage(`{}`)})
+	for ready := false; !ready; {
+		memoryBroker.Publish(ctx, commentStreamChannelPrefix+strconv.Itoa(post.ID), ping)
+		select {
+		case <-sub.Events:
+			ready = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	next := func() *model.CommentStreamEvent {
+		t.Helper()
+		for {
+			select {
+			case event := <-sub.Events:
+				if event.Type != "ping" {
+					return event
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("expected a comment stream event")
+				return nil
+			}
+		}
+	}
+
+	// comments held for review are not streamed until approved
+	own, _ := comments.Create(ctx, author.ID, post.ID, &model.CommentCreateRequest{Content: "own"})
+	held, _ := comments.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "held"})
+	relay.Relay(ctx)
+	if event := next(); event.Type != model.EventCommentCreated || !json.Valid(event.Data) {
+		t.Fatalf("expected the approved comment to be created, got %+v", event)
+	}
+
+	moderation.Approve(ctx, author.ID, []int{held.ID})
+	relay.Relay(ctx)
+	if event := next(); event.Type != model.EventCommentUpdated {
+		t.Fatalf("expected the approved comment to be updated, got %+v", event)
+	}
+
+	// hiding an approved comment reads as a deletion
+	moderation.Reject(ctx, author.ID, []int{held.ID}, false)
+	relay.Relay(ctx)
+	event := next()
+	var ref map[string]int
+	json.Unmarshal(event.Data, &ref)
+	if event.Type != model.EventCommentDeleted || ref["id"] != held.ID || ref["post_id"] != post.ID {
+		t.Fatalf("expected the rejected comment to be deleted, got %+v", event)
+	}
+
+	// readers resume after the last seen event
+	events := eventRepo.All()
+	replayed, missed, err := stream.Subscribe(ctx, post.ID, events[0].ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var types []string
+	for _, e := range missed {
+		types = append(types, e.Type)
+	}
+	if want := []string{model.EventCommentUpdated, model.EventCommentDeleted}; !slices.Equal(types, want) {
+		t.Fatalf("expected replayed events %v, got %v", want, types)
+	}
+	stream.Unsubscribe(replayed)
+	stream.Unsubscribe(replayed)
+
+	// slow readers are dropped
+	slow, _, _ := stream.Subscribe(ctx, post.ID, 0)
+	for range 3 {
+		stream.broadcast(post.ID, &model.CommentStreamEvent{ID: int64(own.ID), Type: model.EventCommentUpdated})
+		next()
+	}
+	received := 0
+	for range slow.Events {
+		received++
+	}
+	if received != 2 {
+		t.Fatalf("expected the slow reader to be dropped after 2 events, got %d", received)
+	}
+
+	// stopping the stream closes the remaining readers
+	cancel()
+	for {
+		select {
+		case _, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected the reader to be closed")
+		}
+	}
+}
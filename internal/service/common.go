@@ -1,14 +1,155 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
 	"blog-api/pkg/logging"
 )
 
 var logger = logging.L()
 
 var (
-	ErrForbidden = errors.New("forbidden")
-	ErrDatabase  = errors.New("database error")
+	ErrForbidden                = errors.New("forbidden")
+	ErrDatabase                 = errors.New("database error")
+	ErrUnsupportedContentFormat = errors.New("unsupported content format")
+	ErrInvalidCursor            = errors.New("invalid cursor")
+	ErrCursorSortUnsupported    = errors.New("cursor pagination supports only sort=created_at with order=desc")
 )
+
+// Handle stops a background process and waits for it to finish the work in flight
+type Handle struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startHandle(name string, run func(ctx context.Context)) *Handle {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handle{name: name, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		run(ctx)
+	}()
+	return h
+}
+
+// Stop cancels the process and waits until it returns or the context is done
+func (h *Handle) Stop(ctx context.Context) error {
+	h.cancel()
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s did not stop in time: %w", h.name, ctx.Err())
+	}
+}
+
+// ensureModerator fails with ErrForbidden unless the user may moderate content of other users
+func ensureModerator(ctx context.Context, userRepo repository.UserRepository, userID int) error {
+	user, err := userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		logger.Error("failed to fetch moderator user_id=%d: %v", userID, err)
+		return ErrDatabase
+	}
+	if !user.CanModerate() {
+		logger.Info("user_id=%d is not a moderator", userID)
+		return ErrForbidden
+	}
+	return nil
+}
+
+// ensureAdmin fails with ErrForbidden unless the user is an admin
+func ensureAdmin(ctx context.Context, userRepo repository.UserRepository, userID int) error {
+	user, err := userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		logger.Error("failed to fetch admin user_id=%d: %v", userID, err)
+		return ErrDatabase
+	}
+	if user.Role != model.UserRoleAdmin {
+		logger.Info("user_id=%d is not an admin", userID)
+		return ErrForbidden
+	}
+	return nil
+}
+
+// recordDecision appends a moderation decision to the moderation log
+func recordDecision(ctx context.Context, logRepo repository.ModerationLogRepository, entry *model.ModerationLogEntry) error {
+	if err := logRepo.Create(ctx, entry); err != nil {
+		logger.Error("failed to record %s of %s id=%d: %v", entry.Action, entry.TargetType, entry.TargetID, err)
+		return ErrDatabase
+	}
+	return nil
+}
+
+// decodeCursor turns the pagination cursor into a keyset position, nil stands for the first page
+func decodeCursor(pagination *model.PaginationParams) (*model.Cursor, error) {
+	if pagination.Cursor == nil {
+		return nil, nil
+	}
+	cursor, err := model.DecodeCursor(*pagination.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+/*
+buildCursorPage expects the rows fetched with a look-ahead of one (limit+1) in listing order,
+trims the extra row and derives the cursors of the neighbouring pages.
+*/
+func buildCursorPage[T any](
+	items []T,
+	cursor *model.Cursor,
+	limit int,
+	key func(T) (time.Time, int),
+) *model.CursorPage[T] {
+	page := &model.CursorPage[T]{Items: items}
+	hasMore := len(items) > limit
+	backward := cursor != nil && cursor.Backward
+
+	if hasMore {
+		if backward {
+			page.Items = items[len(items)-limit:]
+		} else {
+			page.Items = items[:limit]
+		}
+	}
+
+	if len(page.Items) == 0 {
+		page.Items = []T{}
+		return page
+	}
+
+	encode := func(item T, backward bool) string {
+		createdAt, id := key(item)
+		return model.Cursor{CreatedAt: createdAt, ID: id, Backward: backward}.Encode()
+	}
+
+	first, last := page.Items[0], page.Items[len(page.Items)-1]
+	if backward {
+		page.NextCursor = encode(last, false)
+		if hasMore {
+			page.PrevCursor = encode(first, true)
+		}
+	} else {
+		if hasMore {
+			page.NextCursor = encode(last, false)
+		}
+		if cursor != nil {
+			page.PrevCursor = encode(first, true)
+		}
+	}
+
+	return page
+}
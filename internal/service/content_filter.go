@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/contentfilter"
+	"blog-api/pkg/settings"
+)
+
+// config
+type ContentFilterConfig struct {
+	Enabled           bool
+	BannedWords       string // comma separated
+	BannedPatterns    string // regular expressions separated by ";"
+	MaxLinks          int    // 0 disables the check
+	MaxRepeat         int    // longest run of a single character, 0 disables the check
+	DuplicateWindow   int    // recent items of the author compared with the new one, 0 disables the check
+	SpamHoldPercent   int
+	SpamRejectPercent int
+	SpamMinSamples    int // per class, the classifier stays silent until it has seen enough decisions
+	TrainingSamples   int // moderation decisions loaded into the classifier on startup
+}
+
+func (c *ContentFilterConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[bool]{Name: "CONTENT_FILTER_ENABLED", Default: true, Field: &c.Enabled},
+		settings.Item[string]{Name: "CONTENT_FILTER_BANNED_WORDS", Default: "", Field: &c.BannedWords},
+		settings.Item[string]{Name: "CONTENT_FILTER_BANNED_PATTERNS", Default: "", Field: &c.BannedPatterns},
+		settings.Item[int]{Name: "CONTENT_FILTER_MAX_LINKS", Default: 5, Field: &c.MaxLinks},
+		settings.Item[int]{Name: "CONTENT_FILTER_MAX_REPEAT", Default: 10, Field: &c.MaxRepeat},
+		settings.Item[int]{Name: "CONTENT_FILTER_DUPLICATE_WINDOW", Default: 20, Field: &c.DuplicateWindow},
+		settings.Item[int]{Name: "CONTENT_FILTER_SPAM_HOLD_PERCENT", Default: 90, Field: &c.SpamHoldPercent},
+		settings.Item[int]{Name: "CONTENT_FILTER_SPAM_REJECT_PERCENT", Default: 99, Field: &c.SpamRejectPercent},
+		settings.Item[int]{Name: "CONTENT_FILTER_SPAM_MIN_SAMPLES", Default: 20, Field: &c.SpamMinSamples},
+		settings.Item[int]{Name: "CONTENT_FILTER_TRAINING_SAMPLES", Default: 5000, Field: &c.TrainingSamples},
+	}
+}
+
+var ErrContentRejected = errors.New("content rejected")
+
+// NewContentFilter assembles the built-in checks, a disabled filter is nil and allows everything
+func NewContentFilter(
+	config *ContentFilterConfig,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	classifier *contentfilter.Bayes,
+) (*contentfilter.Pipeline, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	words, err := contentfilter.NewWordList(
+		strings.Split(config.BannedWords, ","),
+		strings.Split(config.BannedPatterns, ";"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return contentfilter.NewPipeline(
+		words,
+		contentfilter.LinkLimit{Max: config.MaxLinks},
+		contentfilter.RepeatLimit{Max: config.MaxRepeat},
+		contentfilter.Duplicate{
+			History: &contentHistory{postRepo: postRepo, commentRepo: commentRepo},
+			Window:  config.DuplicateWindow,
+		},
+		contentfilter.SpamFilter{
+			Classifier: classifier,
+			Hold:       float64(config.SpamHoldPercent) / 100,
+			Reject:     float64(config.SpamRejectPercent) / 100,
+			MinSamples: config.SpamMinSamples,
+		},
+	), nil
+}
+
+// TrainSpamClassifier replays the stored moderation decisions into a fresh classifier
+func TrainSpamClassifier(
+	ctx context.Context,
+	config *ContentFilterConfig,
+	sampleRepo repository.SpamSampleRepository,
+	classifier *contentfilter.Bayes,
+) error {
+	samples, err := sampleRepo.GetLatest(ctx, config.TrainingSamples)
+	if err != nil {
+		logger.Error("failed to load spam samples: %v", err)
+		return ErrDatabase
+	}
+	for _, sample := range samples {
+		classifier.Train(sample.Content, sample.Spam)
+	}
+
+	spam, ham := classifier.Samples()
+	logger.Info("spam classifier trained on %d spam and %d ham samples", spam, ham)
+	return nil
+}
+
+// checkContent runs the filter and logs every result, a rejection is returned as ErrContentRejected with the reasons
+func checkContent(ctx context.Context, filter *contentfilter.Pipeline, item *contentfilter.Item) (contentfilter.Verdict, error) {
+	decision, err := filter.Run(ctx, item)
+	if err != nil {
+		logger.Error("content filter failed for %s of user_id=%d: %v", item.Kind, item.AuthorID, err)
+		return contentfilter.Allow, ErrDatabase
+	}
+
+	for _, result := range decision.Results {
+		if result.Verdict == contentfilter.Allow {
+			logger.Debug("content check %s: allow %s id=%d of user_id=%d", result.Check, item.Kind, item.ID, item.AuthorID)
+			continue
+		}
+		logger.Info(
+			"content check %s: %s %s id=%d of user_id=%d: %s",
+			result.Check, result.Verdict, item.Kind, item.ID, item.AuthorID, strings.Join(result.Reasons, "; "),
+		)
+	}
+
+	if decision.Verdict == contentfilter.Reject {
+		return decision.Verdict, fmt.Errorf("%w: %s", ErrContentRejected, strings.Join(decision.Reasons(), "; "))
+	}
+	return decision.Verdict, nil
+}
+
+func postText(post *model.Post) string {
+	return post.Title + "\n" + post.Content
+}
+
+// contentHistory feeds the duplicate check with the latest posts and comments of the author
+type contentHistory struct {
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+}
+
+func (h *contentHistory) Recent(ctx context.Context, kind contentfilter.Kind, authorID int, limit int) ([]contentfilter.Sample, error) {
+	var samples []contentfilter.Sample
+
+	switch kind {
+	case contentfilter.KindPost:
+		posts, err := h.postRepo.GetPosts(ctx, &repository.PostFilter{AuthorID: &authorID}, limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range posts {
+			samples = append(samples, contentfilter.Sample{ID: p.ID, Text: postText(p)})
+		}
+	case contentfilter.KindComment:
+		comments, err := h.commentRepo.GetComments(ctx, &repository.CommentFilter{AuthorID: &authorID}, limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			samples = append(samples, contentfilter.Sample{ID: c.ID, Text: c.Content})
+		}
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/contentfilter"
+	"blog-api/pkg/logging"
+)
+
+func newContentFilterConfig() *ContentFilterConfig {
+	return &ContentFilterConfig{
+		Enabled:           true,
+		BannedWords:       "casino, viagra",
+		BannedPatterns:    `(?i)free\s+money`,
+		MaxLinks:          2,
+		MaxRepeat:         5,
+		DuplicateWindow:   10,
+		SpamHoldPercent:   90,
+		SpamRejectPercent: 99,
+		SpamMinSamples:    3,
+	}
+}
+
+func TestContentFilterComments(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+
+	filter, err := NewContentFilter(newContentFilterConfig(), postRepo, commentRepo, contentfilter.NewBayes())
+	if err != nil {
+		t.Fatalf("failed to build filter: %v", err)
+	}
+	svc := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, repository.NewInMemoryEventRepo(), &CommentConfig{MaxDepth: 5}, filter, nil, nil)
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: 1}
+	postRepo.Create(ctx, post)
+
+	create := func(content string) (*model.Comment, error) {
+		return svc.Create(ctx, 2, post.ID, &model.CommentCreateRequest{Content: content})
+	}
+
+	// banned words and patterns
+	for _, content := range []string{"Best CASINO in town", "get FREE   money now"} {
+		if _, err := create(content); !errors.Is(err, ErrContentRejected) {
+			t.Fatalf("expected ErrContentRejected for %q, got %v", content, err)
+		}
+	}
+
+	// limits hold the comment for review
+	for _, content := range []string{
+		"see https://a.example http://b.example www.c.example",
+		"wooooooow",
+	} {
+		comment, err := create(content)
+		if err != nil {
+			t.Fatalf("expected no error for %q, got %v", content, err)
+		}
+		if comment.Status != model.CommentStatusPending {
+			t.Fatalf("expected %q to be held, got %q", content, comment.Status)
+		}
+	}
+
+	// duplicates of recent comments
+	original, err := create("I really enjoyed reading this article")
+	if err != nil || original.Status != model.CommentStatusApproved {
+		t.Fatalf("expected approved comment, got %+v, err=%v", original, err)
+	}
+	_, err = create("i really enjoyed, reading this ARTICLE!")
+	if !errors.Is(err, ErrContentRejected) || !strings.Contains(err.Error(), "duplicates") {
+		t.Fatalf("expected duplicate rejection, got %v", err)
+	}
+
+	// an edit keeping the text is not a duplicate of itself, a held edit goes back to the queue
+	updated, err := svc.Update(ctx, original.ID, 2, &model.CommentUpdateRequest{Content: "I really enjoyed reading this article"})
+	if err != nil || updated.Status != model.CommentStatusApproved {
+		t.Fatalf("expected unchanged comment to stay approved, got err=%v", err)
+	}
+	updated, err = svc.Update(ctx, original.ID, 2, &model.CommentUpdateRequest{Content: "nooooooooo"})
+	if err != nil || updated.Status != model.CommentStatusPending {
+		t.Fatalf("expected held edit to be pending, got err=%v", err)
+	}
+}
+
+func TestContentFilterHeldPosts(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	sampleRepo := repository.NewInMemorySpamSampleRepo()
+	classifier := contentfilter.NewBayes()
+
+	filter, err := NewContentFilter(newContentFilterConfig(), postRepo, commentRepo, classifier)
+	if err != nil {
+		t.Fatalf("failed to build filter: %v", err)
+	}
+	eventRepo := repository.NewInMemoryEventRepo()
+	posts := NewPostService(postRepo, userRepo, eventRepo, filter, nil, nil, nil)
+	moderation := NewModerationService(commentRepo, postRepo, userRepo, sampleRepo, repository.NewInMemoryModerationLogRepo(), eventRepo, classifier)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	moderator := &model.User{Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator}
+	userRepo.Create(ctx, author)
+	userRepo.Create(ctx, moderator)
+
+	held, err := posts.Create(ctx, author.ID, &model.PostCreateRequest{
+		Title:   "Links",
+		Content: "https://a.example https://b.example https://c.example",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !held.Held || held.Published {
+		t.Fatalf("expected held unpublished post, got held=%v published=%v", held.Held, held.Published)
+	}
+
+	// only moderators see the queue
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	if _, _, err := moderation.GetHeldPosts(ctx, author.ID, pagination); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	list, total, err := moderation.GetHeldPosts(ctx, moderator.ID, pagination)
+	if err != nil || total != 1 || list[0].ID != held.ID {
+		t.Fatalf("expected held post in queue, got total=%d err=%v", total, err)
+	}
+
+	result, err := moderation.ApprovePosts(ctx, moderator.ID, []int{held.ID, 999})
+	if err != nil || len(result.Updated) != 1 || len(result.Skipped) != 1 {
+		t.Fatalf("unexpected approval result %+v, err=%v", result, err)
+	}
+	released, _ := postRepo.GetPost(ctx, held.ID, nil)
+	if released.Held || !released.Published {
+		t.Fatalf("expected released post to be published, got held=%v published=%v", released.Held, released.Published)
+	}
+	if _, ham := classifier.Samples(); ham != 1 {
+		t.Fatalf("expected approval to train the classifier, got %d ham samples", ham)
+	}
+
+	rejected, err := posts.Create(ctx, author.ID, &model.PostCreateRequest{
+		Title:   "More links",
+		Content: "https://d.example https://e.example https://f.example",
+	})
+	if err != nil || !rejected.Held {
+		t.Fatalf("expected a held post, got %+v, %v", rejected, err)
+	}
+	if _, err := moderation.RejectPosts(ctx, moderator.ID, []int{rejected.ID}, true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// the decisions reach the outbox like any other change of the posts
+	var types []string
+	for _, event := range eventRepo.All() {
+		if event.AggregateID == held.ID || event.AggregateID == rejected.ID {
+			types = append(types, event.Type)
+		}
+	}
+	expected := []string{
+		model.EventPostCreated, model.EventPostUpdated, model.EventPostPublished,
+		model.EventPostCreated, model.EventPostDeleted,
+	}
+	if !slices.Equal(types, expected) {
+		t.Fatalf("expected events %v, got %v", expected, types)
+	}
+}
+
+func TestContentFilterSpamClassifier(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	sampleRepo := repository.NewInMemorySpamSampleRepo()
+	for _, text := range []string{
+		"cheap pills online discount pharmacy",
+		"buy cheap pills without prescription",
+		"discount pharmacy cheap pills fast delivery",
+	} {
+		sampleRepo.Create(ctx, &model.SpamSample{Content: text, Spam: true})
+	}
+	for _, text := range []string{
+		"great article about goroutines and channels",
+		"thanks for explaining the scheduler internals",
+		"the benchmark section was really helpful",
+	} {
+		sampleRepo.Create(ctx, &model.SpamSample{Content: text, Spam: false})
+	}
+
+	config := newContentFilterConfig()
+	classifier := contentfilter.NewBayes()
+	if err := TrainSpamClassifier(ctx, config, sampleRepo, classifier); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	filter, _ := NewContentFilter(config, repository.NewInMemoryPostRepo(), repository.NewInMemoryCommentRepo(), classifier)
+	verdict, err := checkContent(ctx, filter, &contentfilter.Item{Kind: contentfilter.KindComment, AuthorID: 1, Text: "cheap pills discount pharmacy"})
+	if err == nil && verdict == contentfilter.Allow {
+		t.Fatalf("expected spam to be held or rejected")
+	}
+	verdict, err = checkContent(ctx, filter, &contentfilter.Item{Kind: contentfilter.KindComment, AuthorID: 1, Text: "helpful article about channels"})
+	if err != nil || verdict != contentfilter.Allow {
+		t.Fatalf("expected ham to be allowed, got %s err=%v", verdict, err)
+	}
+}
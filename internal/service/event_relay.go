@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/settings"
+)
+
+// config
+type EventsConfig struct {
+	RelayIntervalMillis   int
+	BatchSize             int
+	Workers               int
+	MaxAttempts           int
+	HandlerTimeoutSeconds int
+	LogSink               bool
+}
+
+func (c *EventsConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "EVENTS_RELAY_INTERVAL_MS", Default: 1000, Field: &c.RelayIntervalMillis},
+		settings.Item[int]{Name: "EVENTS_BATCH_SIZE", Default: 100, Field: &c.BatchSize},
+		settings.Item[int]{Name: "EVENTS_WORKERS", Default: 4, Field: &c.Workers},
+		settings.Item[int]{Name: "EVENTS_MAX_ATTEMPTS", Default: 10, Field: &c.MaxAttempts},
+		settings.Item[int]{Name: "EVENTS_HANDLER_TIMEOUT_SECONDS", Default: 10, Field: &c.HandlerTimeoutSeconds},
+		settings.Item[bool]{Name: "EVENTS_LOG_SINK", Default: false, Field: &c.LogSink},
+	}
+}
+
+// recordEvent appends a domain event to the outbox, call it inside the transaction of the change it describes
+func recordEvent(
+	ctx context.Context,
+	eventRepo repository.EventRepository,
+	aggregateType string,
+	aggregateID int,
+	eventType string,
+	data any,
+) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	return eventRepo.Create(ctx, &model.Event{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       payload,
+	})
+}
+
+// StartEventRelay launches a background loop that dispatches the outbox events
+func StartEventRelay(ctx context.Context, cfg *EventsConfig, relay *EventRelay) {
+	interval := time.Duration(cfg.RelayIntervalMillis) * time.Millisecond
+	logger.Info("event relay started, interval=%s, workers=%d", interval, cfg.Workers)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("event relay stopped")
+			return
+		case <-ticker.C:
+			if _, err := relay.Relay(ctx); err != nil {
+				logger.Error("event relay failed: %v", err)
+			}
+		}
+	}
+}
+
+// EventHandler processes a dispatched event. Events are delivered at least once, so handlers must be idempotent.
+type EventHandler func(ctx context.Context, event *model.Event) error
+
+// EventSink forwards the events out of the process, to a broker or another service
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, event *model.Event) error
+}
+
+// LogEventSink writes every event to the log
+type LogEventSink struct{}
+
+func (LogEventSink) Name() string {
+	return "log"
+}
+
+func (LogEventSink) Publish(ctx context.Context, event *model.Event) error {
+	logger.Info("event id=%d %s on %s id=%d: %s", event.ID, event.Type, event.AggregateType, event.AggregateID, event.Payload)
+	return nil
+}
+
+type eventSubscription struct {
+	name   string
+	types  []string
+	handle EventHandler
+}
+
+/*
+EventRelay moves the outbox events to the subscribers and sinks. An event is marked as dispatched
+once every matching handler succeeded; a failure leaves it, and the later events of its aggregate,
+to the next run, so events of one aggregate are always handled in the order they were recorded.
+*/
+type EventRelay struct {
+	eventRepo repository.EventRepository
+	config    *EventsConfig
+
+	mu            sync.RWMutex
+	subscriptions []eventSubscription
+}
+
+func NewEventRelay(eventRepo repository.EventRepository, config *EventsConfig) *EventRelay {
+	return &EventRelay{
+		eventRepo: eventRepo,
+		config:    config,
+	}
+}
+
+// Subscribe registers an in-process handler for the given event types, no types subscribe it to every event
+func (r *EventRelay) Subscribe(name string, handler EventHandler, types ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions = append(r.subscriptions, eventSubscription{name: name, types: types, handle: handler})
+}
+
+// AddSink registers a sink receiving every event
+func (r *EventRelay) AddSink(sink EventSink) {
+	r.Subscribe(sink.Name(), sink.Publish)
+}
+
+type relayOutcome struct {
+	dispatched []int64
+	failed     *model.Event
+	err        error
+}
+
+/*
+Relay dispatches one batch of pending events and returns the number of dispatched ones. The batch is
+read under a transaction scoped lock, so concurrent relays of other replicas skip the run instead of
+reordering events. Handlers run outside of the transaction, aggregates are processed concurrently.
+*/
+func (r *EventRelay) Relay(ctx context.Context) (int, error) {
+	dispatched := 0
+	err := r.eventRepo.WithinTransaction(ctx, func(txCtx context.Context) error {
+		locked, err := r.eventRepo.Lock(txCtx)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			logger.Debug("event relay is busy in another process")
+			return nil
+		}
+
+		events, err := r.eventRepo.GetPending(txCtx, r.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		groups := groupByAggregate(events)
+		outcomes := make([]relayOutcome, len(groups))
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for range max(r.config.Workers, 1) {
+			wg.Go(func() {
+				for i := range jobs {
+					outcomes[i] = r.dispatchGroup(ctx, groups[i])
+				}
+			})
+		}
+		for i := range groups {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+
+		now := time.Now()
+		var ids []int64
+		for _, outcome := range outcomes {
+			ids = append(ids, outcome.dispatched...)
+			if outcome.failed == nil {
+				continue
+			}
+
+			var giveUpAt *time.Time
+			if outcome.failed.Attempts+1 >= r.config.MaxAttempts {
+				giveUpAt = &now
+				logger.Warn("gave up on event id=%d %s after %d attempts: %v",
+					outcome.failed.ID, outcome.failed.Type, outcome.failed.Attempts+1, outcome.err)
+			} else {
+				logger.Info("event id=%d %s failed, will retry: %v", outcome.failed.ID, outcome.failed.Type, outcome.err)
+			}
+			if err := r.eventRepo.MarkFailed(txCtx, outcome.failed.ID, outcome.err.Error(), giveUpAt); err != nil {
+				return err
+			}
+		}
+
+		if err := r.eventRepo.MarkDispatched(txCtx, ids, now); err != nil {
+			return err
+		}
+		dispatched = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to relay events: %w", err)
+	}
+	return dispatched, nil
+}
+
+// dispatchGroup handles the events of one aggregate in order and stops at the first failure
+func (r *EventRelay) dispatchGroup(ctx context.Context, events []*model.Event) relayOutcome {
+	var outcome relayOutcome
+	for _, event := range events {
+		if err := r.dispatch(ctx, event); err != nil {
+			outcome.failed = event
+			outcome.err = err
+			return outcome
+		}
+		outcome.dispatched = append(outcome.dispatched, event.ID)
+	}
+	return outcome
+}
+
+func (r *EventRelay) dispatch(ctx context.Context, event *model.Event) error {
+	r.mu.RLock()
+	subscriptions := slices.Clone(r.subscriptions)
+	r.mu.RUnlock()
+
+	timeout := time.Duration(r.config.HandlerTimeoutSeconds) * time.Second
+	for _, sub := range subscriptions {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, event.Type) {
+			continue
+		}
+
+		handlerCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := sub.handle(handlerCtx, event)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%s: %w", sub.name, err)
+		}
+	}
+	return nil
+}
+
+// groupByAggregate splits the events by aggregate keeping their order
+func groupByAggregate(events []*model.Event) [][]*model.Event {
+	type key struct {
+		aggregateType string
+		aggregateID   int
+	}
+
+	index := map[key]int{}
+	var groups [][]*model.Event
+	for _, event := range events {
+		k := key{event.AggregateType, event.AggregateID}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], event)
+	}
+	return groups
+}
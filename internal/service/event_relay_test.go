@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestEventRelay(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	eventRepo := repository.NewInMemoryEventRepo()
+	posts := NewPostService(postRepo, userRepo, eventRepo, nil, nil, nil, nil)
+	comments := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, eventRepo, &CommentConfig{MaxDepth: 5}, nil, nil, nil)
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 4, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+
+	var (
+		mu      sync.Mutex
+		handled []*model.Event
+		failing = map[int64]bool{}
+	)
+	relay.Subscribe("recorder", func(ctx context.Context, event *model.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, event)
+		return nil
+	})
+	relay.Subscribe("flaky", func(ctx context.Context, event *model.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing[event.ID] {
+			return errors.New("sink is down")
+		}
+		return nil
+	}, model.EventPostUpdated)
+	handledIDs := func() []int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		var ids []int64
+		for _, e := range handled {
+			ids = append(ids, e.ID)
+		}
+		handled = nil
+		return ids
+	}
+
+	// changes are recorded in the outbox together with the change
+	post, _ := posts.Create(ctx, 1, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	comment, _ := comments.Create(ctx, 2, post.ID, &model.CommentCreateRequest{Content: "first"})
+	comments.Delete(ctx, comment.ID, 2)
+	var types []string
+	for _, e := range eventRepo.All() {
+		types = append(types, e.Type)
+	}
+	want := []string{model.EventPostCreated, model.EventPostPublished, model.EventCommentCreated, model.EventCommentDeleted}
+	if !slices.Equal(types, want) {
+		t.Fatalf("expected events %v, got %v", want, types)
+	}
+
+	if n, err := relay.Relay(ctx); err != nil || n != 4 {
+		t.Fatalf("expected 4 dispatched events, got %d err=%v", n, err)
+	}
+	if ids := handledIDs(); len(ids) != 4 {
+		t.Fatalf("expected every event to reach the subscriber, got %v", ids)
+	}
+	if n, _ := relay.Relay(ctx); n != 0 {
+		t.Fatalf("expected dispatched events to stay dispatched, got %d", n)
+	}
+
+	// a failure holds back the later events of the same aggregate only
+	title := "Updated"
+	posts.Update(ctx, post.ID, 1, &model.PostUpdateRequest{Title: &title})
+	posts.Delete(ctx, post.ID, 1)
+	other, _ := posts.Create(ctx, 3, &model.PostCreateRequest{Title: "Other", Content: "Content"})
+	events := eventRepo.All()
+	updated, deleted := events[4], events[5]
+	failing[updated.ID] = true
+
+	if n, _ := relay.Relay(ctx); n != 2 {
+		t.Fatalf("expected the events of the other post to be dispatched, got %d", n)
+	}
+	// aggregates are dispatched concurrently, only the order within one is guaranteed
+	ids := handledIDs()
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int64{updated.ID, events[6].ID, events[7].ID}) {
+		t.Fatalf("expected the failed event and the other post events, got %v", ids)
+	}
+	if e := eventRepo.All()[4]; e.Attempts != 1 || e.LastError == "" || e.DispatchedAt != nil {
+		t.Fatalf("expected the failed event to wait for a retry, got %+v", e)
+	}
+
+	// retries redeliver to every subscriber and keep the order of the aggregate
+	mu.Lock()
+	failing[updated.ID] = false
+	mu.Unlock()
+	if n, _ := relay.Relay(ctx); n != 2 {
+		t.Fatalf("expected the held events to be dispatched, got %d", n)
+	}
+	if ids := handledIDs(); !slices.Equal(ids, []int64{updated.ID, deleted.ID}) {
+		t.Fatalf("expected the held events in order, got %v", ids)
+	}
+
+	// events failing too many times are given up
+	posts.Update(ctx, other.ID, 3, &model.PostUpdateRequest{Title: &title})
+	stuck := eventRepo.All()[8]
+	failing[stuck.ID] = true
+	for range 3 {
+		relay.Relay(ctx)
+	}
+	if e := eventRepo.All()[8]; e.Attempts != 3 || e.DispatchedAt == nil {
+		t.Fatalf("expected the event to be given up after 3 attempts, got %+v", e)
+	}
+}
+
+func TestEventRelaySingleRunner(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	eventRepo := repository.NewInMemoryEventRepo()
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 10, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	eventRepo.Create(ctx, &model.Event{AggregateType: model.AggregateUser, AggregateID: 1, Type: model.EventUserRegistered})
+
+	// a relay started while another one holds the outbox skips the run
+	var nested int
+	relay.Subscribe("nested", func(ctx context.Context, event *model.Event) error {
+		n, err := relay.Relay(ctx)
+		nested = n
+		return err
+	})
+
+	if n, err := relay.Relay(ctx); err != nil || n != 1 || nested != 0 {
+		t.Fatalf("expected only the outer relay to dispatch, got outer=%d nested=%d err=%v", n, nested, err)
+	}
+}
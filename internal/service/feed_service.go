@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/feed"
+	"blog-api/pkg/markup"
+	"blog-api/pkg/settings"
+)
+
+// config
+type FeedConfig struct {
+	Title         string
+	Description   string
+	SiteURL       string
+	PostURL       string // {id} is replaced with the post ID
+	Size          int
+	FullContent   bool
+	ExcerptLength int
+}
+
+func (c *FeedConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[string]{Name: "FEEDS_TITLE", Default: "Blog", Field: &c.Title},
+		settings.Item[string]{Name: "FEEDS_DESCRIPTION", Default: "", Field: &c.Description},
+		settings.Item[string]{Name: "FEEDS_SITE_URL", Default: "http://localhost:8080", Field: &c.SiteURL},
+		settings.Item[string]{Name: "FEEDS_POST_URL", Default: "http://localhost:8080/api/posts/{id}", Field: &c.PostURL},
+		settings.Item[int]{Name: "FEEDS_SIZE", Default: 20, Field: &c.Size},
+		settings.Item[bool]{Name: "FEEDS_FULL_CONTENT", Default: false, Field: &c.FullContent},
+		settings.Item[int]{Name: "FEEDS_EXCERPT_LENGTH", Default: 300, Field: &c.ExcerptLength},
+	}
+}
+
+// FeedService builds the syndication feeds of the latest published posts
+type FeedService struct {
+	postService *PostService
+	userRepo    repository.UserRepository
+	eventRepo   repository.EventRepository
+	config      *FeedConfig
+}
+
+func NewFeedService(
+	postService *PostService,
+	userRepo repository.UserRepository,
+	eventRepo repository.EventRepository,
+	config *FeedConfig,
+) *FeedService {
+	return &FeedService{
+		postService: postService,
+		userRepo:    userRepo,
+		eventRepo:   eventRepo,
+		config:      config,
+	}
+}
+
+func (s *FeedService) Config() *FeedConfig {
+	return s.config
+}
+
+// GetSiteFeed lists the latest published posts of everyone
+func (s *FeedService) GetSiteFeed(ctx context.Context) (*feed.Feed, error) {
+	posts, _, err := s.postService.List(ctx, s.query(nil), s.pagination())
+	if err != nil {
+		return nil, err
+	}
+	return s.build(ctx, s.config.Title, s.config.Description, posts)
+}
+
+// GetAuthorFeed lists the latest published posts of the user
+func (s *FeedService) GetAuthorFeed(ctx context.Context, authorID int) (*feed.Feed, error) {
+	author, err := s.userRepo.GetByID(ctx, authorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			logger.Info("feed author with id=%d not found", authorID)
+			return nil, ErrUserNotFound
+		}
+		logger.Error("failed to fetch feed author id=%d: %v", authorID, err)
+		return nil, ErrDatabase
+	}
+
+	posts, _, err := s.postService.List(ctx, s.query([]int{authorID}), s.pagination())
+	if err != nil {
+		return nil, err
+	}
+	title := fmt.Sprintf("%s: %s", s.config.Title, author.Username)
+	return s.build(ctx, title, fmt.Sprintf("Posts by %s", author.Username), posts)
+}
+
+// query lists the posts by publication time, scheduled posts take their place when they go out
+func (s *FeedService) query(authorIDs []int) *model.PostListParams {
+	return &model.PostListParams{Sort: model.PostSortPublishedAt, Order: model.SortDesc, AuthorIDs: authorIDs}
+}
+
+func (s *FeedService) pagination() *model.PaginationParams {
+	withTotal := false
+	return &model.PaginationParams{Limit: &s.config.Size, Offset: new(int), WithTotal: &withTotal}
+}
+
+/*
+build turns the posts into a feed. The feed was last updated by the latest post event: the latest post update
+alone would move back once a post leaves the window by being deleted or expired, and answer 304 to a changed feed.
+*/
+func (s *FeedService) build(ctx context.Context, title, description string, posts []*model.Post) (*feed.Feed, error) {
+	if err := s.postService.ExpandPosts(ctx, posts, &model.ExpandParams{Fields: []string{model.ExpandAuthor}}); err != nil {
+		return nil, err
+	}
+
+	f := &feed.Feed{
+		Title:       title,
+		Description: description,
+		Link:        s.config.SiteURL,
+	}
+	for _, post := range posts {
+		link := strings.ReplaceAll(s.config.PostURL, "{id}", strconv.Itoa(post.ID))
+		item := &feed.Item{
+			ID:        link,
+			Title:     post.Title,
+			Link:      link,
+			Summary:   markup.Excerpt(post.ContentHTML, s.config.ExcerptLength),
+			Published: post.CreatedAt,
+			Updated:   post.UpdatedAt,
+		}
+		// scheduled posts went out at their publication time
+		if post.PublishAt != nil && post.PublishAt.After(post.CreatedAt) {
+			item.Published = *post.PublishAt
+		}
+		if s.config.FullContent {
+			item.Content = post.ContentHTML
+		}
+		if post.Author != nil {
+			item.Author = post.Author.Username
+		}
+		if post.UpdatedAt.After(f.Updated) {
+			f.Updated = post.UpdatedAt
+		}
+		f.Items = append(f.Items, item)
+	}
+
+	latest, err := s.eventRepo.GetLatest(ctx, model.AggregatePost)
+	if err != nil && !errors.Is(err, repository.ErrEventNotFound) {
+		logger.Error("failed to fetch the latest post event: %v", err)
+		return nil, ErrDatabase
+	}
+	if latest != nil && latest.CreatedAt.After(f.Updated) {
+		f.Updated = latest.CreatedAt
+	}
+	return f, nil
+}
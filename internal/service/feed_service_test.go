@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestFeedService(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo.LinkUsers(userRepo)
+	eventRepo := repository.NewInMemoryEventRepo()
+	posts := NewPostService(postRepo, userRepo, eventRepo, nil, nil, nil, nil)
+	cfg := &FeedConfig{Title: "Blog", SiteURL: "https://blog.example.com", PostURL: "https://blog.example.com/posts/{id}", Size: 2, ExcerptLength: 20}
+	feeds := NewFeedService(posts, userRepo, eventRepo, cfg)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	other := &model.User{Username: "other", Email: "other@example.com"}
+	for _, u := range []*model.User{author, other} {
+		userRepo.Create(ctx, u)
+	}
+
+	content := "The first paragraph of the post.\n\nThe second one."
+	first, _ := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "First", Content: content})
+	posts.Create(ctx, other.ID, &model.PostCreateRequest{Title: "Other", Content: "Content"})
+	draft, _ := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Draft", Content: "Content", PublishAt: ptr(time.Now().Add(time.Hour))})
+	last, _ := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Last", Content: "Content"})
+
+	// the site feed holds the latest published posts of everyone
+	site, err := feeds.GetSiteFeed(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(site.Items) != 2 || site.Items[0].Title != "Last" || site.Items[1].Title != "Other" {
+		t.Fatalf("expected the 2 latest published posts, got %+v", site.Items)
+	}
+	if site.Items[0].Link != "https://blog.example.com/posts/4" || site.Items[0].Author != "author" {
+		t.Fatalf("expected the item link and author, got %+v", site.Items[0])
+	}
+	if site.Updated.Before(last.UpdatedAt) {
+		t.Fatalf("expected the feed to be updated with its latest post, got %s", site.Updated)
+	}
+
+	// excerpts are plain text cut at a word boundary
+	authorFeed, err := feeds.GetAuthorFeed(ctx, author.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(authorFeed.Items) != 2 || authorFeed.Items[1].ID != "https://blog.example.com/posts/1" {
+		t.Fatalf("expected the published posts of the author, got %+v", authorFeed.Items)
+	}
+	if item := authorFeed.Items[1]; item.Summary != "The first paragraph…" || item.Content != "" {
+		t.Fatalf("expected an excerpt without content, got %q %q", item.Summary, item.Content)
+	}
+
+	// full content feeds carry the rendered post
+	cfg.FullContent = true
+	authorFeed, _ = feeds.GetAuthorFeed(ctx, author.ID)
+	if item := authorFeed.Items[1]; item.Content != first.ContentHTML || !strings.HasPrefix(item.Content, "<p>") {
+		t.Fatalf("expected the full content, got %q", item.Content)
+	}
+
+	if _, err := feeds.GetAuthorFeed(ctx, 999); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	// a scheduled post takes its place by publication time, not by creation time
+	scheduled, _ := postRepo.GetPost(ctx, draft.ID, nil)
+	scheduled.PublishAt = ptr(time.Now())
+	scheduled.Published = true
+	postRepo.Update(ctx, scheduled)
+	site, _ = feeds.GetSiteFeed(ctx)
+	if len(site.Items) != 2 || site.Items[0].Title != "Draft" || site.Items[1].Title != "Last" {
+		t.Fatalf("expected the posts by publication time, got %+v", site.Items)
+	}
+
+	// a post leaving the feed still moves its last modification forward
+	before := site.Updated
+	if err := posts.Delete(ctx, draft.ID, author.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	site, _ = feeds.GetSiteFeed(ctx)
+	if site.Items[0].Title != "Last" || site.Updated.Before(before) {
+		t.Fatalf("expected the feed to be updated after the delete, got %s before %s", site.Updated, before)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+)
+
+var ErrSelfFollow = errors.New("users cannot follow themselves")
+
+type FollowService struct {
+	followRepo repository.FollowRepository
+	userRepo   repository.UserRepository
+	postRepo   repository.PostRepository
+}
+
+func NewFollowService(
+	followRepo repository.FollowRepository,
+	userRepo repository.UserRepository,
+	postRepo repository.PostRepository,
+) *FollowService {
+	return &FollowService{
+		followRepo: followRepo,
+		userRepo:   userRepo,
+		postRepo:   postRepo,
+	}
+}
+
+// Follow subscribes the actor to the user, following twice is not an error
+func (s *FollowService) Follow(ctx context.Context, actorID, userID int) (*model.FollowStats, error) {
+	if actorID == userID {
+		logger.Info("user_id=%d tried to follow themselves", actorID)
+		return nil, ErrSelfFollow
+	}
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	created, err := s.followRepo.Create(ctx, &model.Follow{FollowerID: actorID, FolloweeID: userID})
+	if err != nil {
+		logger.Error("failed to follow user_id=%d by user_id=%d: %v", userID, actorID, err)
+		return nil, ErrDatabase
+	}
+	if created {
+		logger.Info("user_id=%d followed user_id=%d", actorID, userID)
+	}
+
+	return s.GetStats(ctx, actorID, userID)
+}
+
+// Unfollow removes the subscription of the actor, unfollowing a user that is not followed is not an error
+func (s *FollowService) Unfollow(ctx context.Context, actorID, userID int) (*model.FollowStats, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.followRepo.Delete(ctx, actorID, userID)
+	if err != nil {
+		logger.Error("failed to unfollow user_id=%d by user_id=%d: %v", userID, actorID, err)
+		return nil, ErrDatabase
+	}
+	if deleted {
+		logger.Info("user_id=%d unfollowed user_id=%d", actorID, userID)
+	}
+
+	return s.GetStats(ctx, actorID, userID)
+}
+
+// GetStats returns the follow counters of the user as seen by the actor
+func (s *FollowService) GetStats(ctx context.Context, actorID, userID int) (*model.FollowStats, error) {
+	followers, err := s.followRepo.GetFollowersCount(ctx, userID)
+	if err != nil {
+		logger.Error("failed to count followers of user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+
+	following, err := s.followRepo.GetFollowingCount(ctx, userID)
+	if err != nil {
+		logger.Error("failed to count followees of user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+
+	followed, err := s.followRepo.Exists(ctx, actorID, userID)
+	if err != nil {
+		logger.Error("failed to check follow of user_id=%d by user_id=%d: %v", userID, actorID, err)
+		return nil, ErrDatabase
+	}
+
+	return &model.FollowStats{
+		UserID:    userID,
+		Followers: followers,
+		Following: following,
+		Followed:  followed,
+	}, nil
+}
+
+// GetFollowers lists the users following the user, latest follows first
+func (s *FollowService) GetFollowers(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+) ([]*model.UserSummary, int, error) {
+	return s.list(ctx, userID, pagination, s.followRepo.GetFollowers, s.followRepo.GetFollowersCount)
+}
+
+// GetFollowing lists the users the user follows, latest follows first
+func (s *FollowService) GetFollowing(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+) ([]*model.UserSummary, int, error) {
+	return s.list(ctx, userID, pagination, s.followRepo.GetFollowing, s.followRepo.GetFollowingCount)
+}
+
+// GetFeed returns the published posts of the authors the user follows using keyset pagination
+func (s *FollowService) GetFeed(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+) (*model.CursorPage[*model.Post], error) {
+
+	cursor, err := decodeCursor(pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	authorIDs, err := s.followRepo.GetFolloweeIDs(ctx, userID)
+	if err != nil {
+		logger.Error("failed to fetch followees of user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+	if len(authorIDs) == 0 {
+		page := &model.CursorPage[*model.Post]{Items: []*model.Post{}}
+		if pagination.CountTotal() {
+			total := 0
+			page.Total = &total
+		}
+		return page, nil
+	}
+
+	published := true
+	filter := &repository.PostFilter{Published: &published, AuthorIDs: authorIDs}
+
+	posts, err := s.postRepo.GetPostsByCursor(ctx, filter, cursor, *pagination.Limit+1)
+	if err != nil {
+		logger.Error("failed to fetch feed of user_id=%d from %d authors: %v", userID, len(authorIDs), err)
+		return nil, ErrDatabase
+	}
+
+	page := buildCursorPage(posts, cursor, *pagination.Limit, postCursorKey)
+
+	if pagination.CountTotal() {
+		total, err := s.postRepo.GetPostsCount(ctx, filter)
+		if err != nil {
+			logger.Error("failed to count feed of user_id=%d: %v", userID, err)
+			return nil, ErrDatabase
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+func (s *FollowService) list(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+	get func(ctx context.Context, userID int, limit, offset int) ([]*model.UserSummary, error),
+	count func(ctx context.Context, userID int) (int, error),
+) ([]*model.UserSummary, int, error) {
+
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+
+	users, err := get(ctx, userID, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch follows of user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return users, 0, nil
+	}
+
+	total, err := count(ctx, userID)
+	if err != nil {
+		logger.Error("failed to count follows of user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return users, total, nil
+}
+
+func (s *FollowService) ensureUser(ctx context.Context, userID int) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			logger.Info("user_id=%d not found", userID)
+			return ErrUserNotFound
+		}
+		logger.Error("failed to fetch user_id=%d: %v", userID, err)
+		return ErrDatabase
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestFollowServiceFollow(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	svc := NewFollowService(repository.NewInMemoryFollowRepo(userRepo), userRepo, repository.NewInMemoryPostRepo())
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com"}
+	bob := &model.User{Username: "bob", Email: "bob@example.com"}
+	carol := &model.User{Username: "carol", Email: "carol@example.com"}
+	for _, u := range []*model.User{alice, bob, carol} {
+		userRepo.Create(ctx, u)
+	}
+
+	stats, err := svc.Follow(ctx, alice.ID, bob.ID)
+	if err != nil || stats.Followers != 1 || !stats.Followed {
+		t.Fatalf("expected alice to follow bob, got %+v err=%v", stats, err)
+	}
+	// following twice keeps a single follow
+	if stats, _ := svc.Follow(ctx, alice.ID, bob.ID); stats.Followers != 1 {
+		t.Fatalf("expected 1 follower after repeated follow, got %d", stats.Followers)
+	}
+	svc.Follow(ctx, carol.ID, bob.ID)
+	svc.Follow(ctx, alice.ID, carol.ID)
+
+	if _, err := svc.Follow(ctx, alice.ID, alice.ID); !errors.Is(err, ErrSelfFollow) {
+		t.Fatalf("expected ErrSelfFollow, got %v", err)
+	}
+	if _, err := svc.Follow(ctx, alice.ID, 999); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	followers, total, err := svc.GetFollowers(ctx, bob.ID, pagination)
+	if err != nil || total != 2 || followers[0].Username != "carol" || followers[1].Username != "alice" {
+		t.Fatalf("expected carol and alice to follow bob, got %+v total=%d err=%v", followers, total, err)
+	}
+	following, total, _ := svc.GetFollowing(ctx, alice.ID, pagination)
+	if total != 2 || following[0].ID != carol.ID {
+		t.Fatalf("expected alice to follow carol and bob, got %+v total=%d", following, total)
+	}
+
+	stats, err = svc.Unfollow(ctx, alice.ID, bob.ID)
+	if err != nil || stats.Followers != 1 || stats.Followed {
+		t.Fatalf("expected alice to unfollow bob, got %+v err=%v", stats, err)
+	}
+	if _, err := svc.Unfollow(ctx, alice.ID, bob.ID); err != nil {
+		t.Fatalf("expected repeated unfollow to succeed, got %v", err)
+	}
+}
+
+func TestFollowServiceFeed(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo := repository.NewInMemoryPostRepo()
+	svc := NewFollowService(repository.NewInMemoryFollowRepo(userRepo), userRepo, postRepo)
+
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	bob := &model.User{Username: "bob", Email: "bob@example.com"}
+	carol := &model.User{Username: "carol", Email: "carol@example.com"}
+	for _, u := range []*model.User{reader, bob, carol} {
+		userRepo.Create(ctx, u)
+	}
+
+	first := &model.PaginationParams{Limit: ptr(2), Cursor: ptr("")}
+	page, err := svc.GetFeed(ctx, reader.ID, first)
+	if err != nil || len(page.Items) != 0 || page.NextCursor != "" {
+		t.Fatalf("expected an empty feed without follows, got %+v err=%v", page, err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	for i, authorID := range []int{bob.ID, carol.ID, bob.ID, reader.ID, bob.ID} {
+		post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: authorID}
+		postRepo.Create(ctx, post)
+		post.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		postRepo.Update(ctx, post)
+	}
+	postRepo.Create(ctx, &model.Post{Title: "Draft", Content: "Content", AuthorID: bob.ID})
+
+	svc.Follow(ctx, reader.ID, bob.ID)
+
+	page, err = svc.GetFeed(ctx, reader.ID, first)
+	if err != nil || len(page.Items) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected a full first page, got %+v err=%v", page, err)
+	}
+	if page.Items[0].ID != 5 || page.Items[1].ID != 3 {
+		t.Fatalf("expected newest posts of bob first, got %d, %d", page.Items[0].ID, page.Items[1].ID)
+	}
+
+	page, err = svc.GetFeed(ctx, reader.ID, &model.PaginationParams{Limit: ptr(2), Cursor: &page.NextCursor})
+	if err != nil || len(page.Items) != 1 || page.Items[0].ID != 1 || page.NextCursor != "" {
+		t.Fatalf("expected the last post of bob on the second page, got %+v err=%v", page, err)
+	}
+}
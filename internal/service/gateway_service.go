@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/settings"
+)
+
+var (
+	ErrGatewayUnknownTopic         = errors.New("unknown topic")
+	ErrGatewayTooManySubscriptions = errors.New("too many subscriptions")
+)
+
+const gatewayChannelPrefix = "gateway:"
+
+// config
+type GatewayConfig struct {
+	SendBufferSize      int
+	WriteTimeoutSeconds int
+	HeartbeatSeconds    int
+	MaxSubscriptions    int
+	MaxMessageBytes     int
+}
+
+func (c *GatewayConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "GATEWAY_SEND_BUFFER_SIZE", Default: 64, Field: &c.SendBufferSize},
+		settings.Item[int]{Name: "GATEWAY_WRITE_TIMEOUT_SECONDS", Default: 10, Field: &c.WriteTimeoutSeconds},
+		settings.Item[int]{Name: "GATEWAY_HEARTBEAT_SECONDS", Default: 30, Field: &c.HeartbeatSeconds},
+		settings.Item[int]{Name: "GATEWAY_MAX_SUBSCRIPTIONS", Default: 50, Field: &c.MaxSubscriptions},
+		settings.Item[int]{Name: "GATEWAY_MAX_MESSAGE_BYTES", Default: 4096, Field: &c.MaxMessageBytes},
+	}
+}
+
+func (c *GatewayConfig) WriteTimeout() time.Duration {
+	return time.Duration(c.WriteTimeoutSeconds) * time.Second
+}
+
+func (c *GatewayConfig) Heartbeat() time.Duration {
+	return time.Duration(c.HeartbeatSeconds) * time.Second
+}
+
+// StartGateway keeps the gateway subscribed to the broker, clients reconnect after a broker failure
+func StartGateway(ctx context.Context, svc *GatewayService) {
+	logger.Info("gateway started")
+	for {
+		if err := svc.Run(ctx); err != nil {
+			logger.Error("gateway failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			logger.Info("gateway stopped")
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// GatewayClient is a connection of a user, Messages is closed when the client is dropped
+type GatewayClient struct {
+	UserID   int
+	Messages <-chan *model.GatewayMessage
+
+	messages chan *model.GatewayMessage
+	topics   map[string]string // topic -> broker channel
+	closed   bool
+}
+
+/*
+GatewayService pushes realtime events to the connected users over topics they subscribe to. Messages
+go through the broker so that every replica delivers them to its own clients. Each client has a bounded
+queue; a client whose queue is full is dropped instead of slowing the others down.
+*/
+type GatewayService struct {
+	userRepo repository.UserRepository
+	postRepo repository.PostRepository
+	broker   broker.Broker
+	config   *GatewayConfig
+
+	mu       sync.Mutex
+	clients  map[*GatewayClient]struct{}
+	channels map[string]map[*GatewayClient]struct{}
+}
+
+func NewGatewayService(
+	userRepo repository.UserRepository,
+	postRepo repository.PostRepository,
+	broker broker.Broker,
+	config *GatewayConfig,
+) *GatewayService {
+	return &GatewayService{
+		userRepo: userRepo,
+		postRepo: postRepo,
+		broker:   broker,
+		config:   config,
+		clients:  map[*GatewayClient]struct{}{},
+		channels: map[string]map[*GatewayClient]struct{}{},
+	}
+}
+
+func (s *GatewayService) Config() *GatewayConfig {
+	return s.config
+}
+
+// Connect registers a client of the authenticated user without subscriptions
+func (s *GatewayService) Connect(userID int) *GatewayClient {
+	messages := make(chan *model.GatewayMessage, max(s.config.SendBufferSize, 1))
+	client := &GatewayClient{UserID: userID, Messages: messages, messages: messages, topics: map[string]string{}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[client] = struct{}{}
+	logger.Debug("gateway client of user_id=%d connected", userID)
+	return client
+}
+
+// Disconnect removes the client with its subscriptions, disconnecting twice is safe
+func (s *GatewayService) Disconnect(client *GatewayClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(client)
+}
+
+// Subscribe checks that the user may read the topic and subscribes the client to it
+func (s *GatewayService) Subscribe(ctx context.Context, client *GatewayClient, topic string) error {
+	channel, err := s.authorize(ctx, client.UserID, topic)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if client.closed {
+		return nil
+	}
+	if _, ok := client.topics[topic]; ok {
+		return nil
+	}
+	if len(client.topics) >= s.config.MaxSubscriptions {
+		return ErrGatewayTooManySubscriptions
+	}
+
+	client.topics[topic] = channel
+	if s.channels[channel] == nil {
+		s.channels[channel] = map[*GatewayClient]struct{}{}
+	}
+	s.channels[channel][client] = struct{}{}
+	logger.Debug("user_id=%d subscribed to %s", client.UserID, topic)
+	return nil
+}
+
+// Unsubscribe removes the subscription of the client, unsubscribing from an unknown topic is a no-op
+func (s *GatewayService) Unsubscribe(client *GatewayClient, topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	channel, ok := client.topics[topic]
+	if !ok {
+		return
+	}
+	delete(client.topics, topic)
+	s.leave(channel, client)
+}
+
+// Send queues a message for the client, a client with a full queue is dropped
+func (s *GatewayService) Send(client *GatewayClient, message *model.GatewayMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliver(client, message)
+}
+
+// PushToUser sends a message on a topic of the user, a nil service drops it
+func (s *GatewayService) PushToUser(ctx context.Context, userID int, topic, messageType string, data any) {
+	if s == nil {
+		return
+	}
+	if err := s.publish(ctx, userChannel(userID, topic), topic, messageType, data); err != nil {
+		logger.Error("failed to push %s to user_id=%d: %v", messageType, userID, err)
+	}
+}
+
+/*
+HandleEvent is the event relay handler pushing the outbox events to the topics: published posts to their
+authors, held content to the moderators and the visible comment changes to the readers of the post.
+*/
+func (s *GatewayService) HandleEvent(ctx context.Context, event *model.Event) error {
+	switch event.AggregateType {
+	case model.AggregatePost:
+		var post model.Post
+		if err := json.Unmarshal(event.Payload, &post); err != nil {
+			return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+		}
+		switch {
+		case event.Type == model.EventPostPublished:
+			topic := model.GatewayTopicPosts
+			return s.publish(ctx, userChannel(post.AuthorID, topic), topic, event.Type, event.Payload)
+		case post.Held && (event.Type == model.EventPostCreated || event.Type == model.EventPostUpdated):
+			topic := model.GatewayTopicModeration
+			return s.publish(ctx, gatewayChannelPrefix+topic, topic, model.GatewayMessagePostHeld, event.Payload)
+		}
+
+	case model.AggregateComment:
+		var comment model.Comment
+		if err := json.Unmarshal(event.Payload, &comment); err != nil {
+			return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+		}
+		if comment.Status == model.CommentStatusPending && event.Type != model.EventCommentDeleted {
+			topic := model.GatewayTopicModeration
+			if err := s.publish(ctx, gatewayChannelPrefix+topic, topic, model.GatewayMessageCommentHeld, event.Payload); err != nil {
+				return err
+			}
+		}
+
+		streamEvent, postID, err := toCommentStreamEvent(event)
+		if err != nil || streamEvent == nil {
+			return err
+		}
+		topic := model.GatewayTopicCommentsPrefix + strconv.Itoa(postID)
+		return s.publish(ctx, gatewayChannelPrefix+"post:"+strconv.Itoa(postID)+":comments", topic, streamEvent.Type, streamEvent.Data)
+	}
+	return nil
+}
+
+// Run hands the messages received from the broker to the local clients until ctx is done or the broker fails
+func (s *GatewayService) Run(ctx context.Context) error {
+	messages, err := s.broker.Subscribe(ctx, gatewayChannelPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer s.dropAll()
+
+	for msg := range messages {
+		var message model.GatewayMessage
+		if err := json.Unmarshal(msg.Payload, &message); err != nil {
+			logger.Warn("failed to decode gateway message on %s: %v", msg.Channel, err)
+			continue
+		}
+		s.broadcast(msg.Channel, &message)
+	}
+
+	if ctx.Err() == nil {
+		return errors.New("broker subscription closed")
+	}
+	return nil
+}
+
+// authorize maps the topic to its broker channel if the user may read it
+func (s *GatewayService) authorize(ctx context.Context, userID int, topic string) (string, error) {
+	switch topic {
+	case model.GatewayTopicNotifications, model.GatewayTopicPosts:
+		return userChannel(userID, topic), nil
+
+	case model.GatewayTopicModeration:
+		if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+			return "", err
+		}
+		return gatewayChannelPrefix + topic, nil
+	}
+
+	postIDStr, ok := strings.CutPrefix(topic, model.GatewayTopicCommentsPrefix)
+	if !ok {
+		return "", ErrGatewayUnknownTopic
+	}
+	postID, err := strconv.Atoi(postIDStr)
+	if err != nil {
+		return "", ErrGatewayUnknownTopic
+	}
+
+	// comments of unpublished posts are seen by their authors and moderators only
+	post, err := s.postRepo.GetPost(ctx, postID, nil)
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			return "", ErrPostNotFound
+		}
+		logger.Error("failed to fetch post id=%d: %v", postID, err)
+		return "", ErrDatabase
+	}
+	if !post.Published && post.AuthorID != userID {
+		if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+			if errors.Is(err, ErrForbidden) {
+				return "", ErrPostNotFound
+			}
+			return "", err
+		}
+	}
+	return gatewayChannelPrefix + "post:" + postIDStr + ":comments", nil
+}
+
+func (s *GatewayService) publish(ctx context.Context, channel, topic, messageType string, data any) error {
+	raw, ok := data.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", messageType, err)
+		}
+	}
+	payload, err := json.Marshal(&model.GatewayMessage{Type: messageType, Topic: topic, Data: raw})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", messageType, err)
+	}
+	return s.broker.Publish(ctx, channel, payload)
+}
+
+func (s *GatewayService) broadcast(channel string, message *model.GatewayMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.channels[channel] {
+		s.deliver(client, message)
+	}
+}
+
+func (s *GatewayService) deliver(client *GatewayClient, message *model.GatewayMessage) {
+	if client.closed {
+		return
+	}
+	select {
+	case client.messages <- message:
+	default:
+		logger.Info("dropped slow gateway client of user_id=%d", client.UserID)
+		s.drop(client)
+	}
+}
+
+func (s *GatewayService) drop(client *GatewayClient) {
+	if client.closed {
+		return
+	}
+	client.closed = true
+	for _, channel := range client.topics {
+		s.leave(channel, client)
+	}
+	delete(s.clients, client)
+	close(client.messages)
+}
+
+func (s *GatewayService) leave(channel string, client *GatewayClient) {
+	delete(s.channels[channel], client)
+	if len(s.channels[channel]) == 0 {
+		delete(s.channels, channel)
+	}
+}
+
+func (s *GatewayService) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		s.drop(client)
+	}
+}
+
+func userChannel(userID int, topic string) string {
+	return gatewayChannelPrefix + "user:" + strconv.Itoa(userID) + ":" + topic
+}
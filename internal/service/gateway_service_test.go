@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/logging"
+)
+
+func TestGatewayService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	commentRepo.LinkPosts(postRepo)
+	eventRepo := repository.NewInMemoryEventRepo()
+
+	gateway := NewGatewayService(userRepo, postRepo, broker.NewMemoryBroker(), &GatewayConfig{SendBufferSize: 4, MaxSubscriptions: 4})
+	notifications := NewNotificationService(repository.NewInMemoryNotificationRepo(), repository.NewInMemoryNotificationPreferenceRepo(), postRepo, commentRepo, gateway)
+	comments := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, eventRepo, &CommentConfig{MaxDepth: 5}, nil, notifications, nil)
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("gateway", gateway.HandleEvent)
+	relay.Subscribe("comment_announcements", comments.AnnounceCreated, model.EventCommentCreated, model.EventCommentApproved)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	moderator := &model.User{Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator}
+	for _, u := range []*model.User{author, reader, moderator} {
+		userRepo.Create(ctx, u)
+	}
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID, CommentsPremoderation: true}
+	draft := &model.Post{Title: "Draft", Content: "Content", AuthorID: author.ID}
+	for _, p := range []*model.Post{post, draft} {
+		postRepo.Create(ctx, p)
+	}
+
+	authorClient := gateway.Connect(author.ID)
+	readerClient := gateway.Connect(reader.ID)
+	moderatorClient := gateway.Connect(moderator.ID)
+
+	// topics are authorized per user
+	tests := []struct {
+		client *GatewayClient
+		topic  string
+		want   error
+	}{
+		{authorClient, model.GatewayTopicNotifications, nil},
+		{authorClient, model.GatewayTopicPosts, nil},
+		{authorClient, "comments:2", nil},
+		{readerClient, "comments:1", nil},
+		{readerClient, "comments:2", ErrPostNotFound},
+		{readerClient, "comments:999", ErrPostNotFound},
+		{readerClient, model.GatewayTopicModeration, ErrForbidden},
+		{readerClient, "comments:abc", ErrGatewayUnknownTopic},
+		{readerClient, "feed", ErrGatewayUnknownTopic},
+		{moderatorClient, model.GatewayTopicModeration, nil},
+		{moderatorClient, "comments:2", nil},
+		{authorClient, "comments:1", nil},
+		{authorClient, model.GatewayTopicModeration, ErrForbidden},
+	}
+	for _, tt := range tests {
+		if err := gateway.Subscribe(ctx, tt.client, tt.topic); !errors.Is(err, tt.want) {
+			t.Fatalf("subscribe user_id=%d to %s: expected %v, got %v", tt.client.UserID, tt.topic, tt.want, err)
+		}
+	}
+	for _, topic := range []string{model.GatewayTopicNotifications, model.GatewayTopicPosts, model.GatewayTopicPosts} {
+		if err := gateway.Subscribe(ctx, moderatorClient, topic); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := gateway.Subscribe(ctx, moderatorClient, "comments:1"); !errors.Is(err, ErrGatewayTooManySubscriptions) {
+		t.Fatalf("expected ErrGatewayTooManySubscriptions, got %v", err)
+	}
+
+	go gateway.Run(ctx)
+	// the broker subscription starts in the background, ping the author until it is up
+	for ready := false; !ready; {
+		gateway.PushToUser(ctx, author.ID, model.GatewayTopicPosts, model.GatewayMessagePing, nil)
+		select {
+		case <-authorClient.Messages:
+			ready = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	next := func(client *GatewayClient) *model.GatewayMessage {
+		t.Helper()
+		for {
+			select {
+			case message := <-client.Messages:
+				if message.Type != model.GatewayMessagePing {
+					return message
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("expected a message for user_id=%d", client.UserID)
+				return nil
+			}
+		}
+	}
+	expect := func(client *GatewayClient, topic, messageType string) {
+		t.Helper()
+		if message := next(client); message.Topic != topic || message.Type != messageType {
+			t.Fatalf("expected %s on %s for user_id=%d, got %+v", messageType, topic, client.UserID, message)
+		}
+	}
+
+	// a held comment reaches the moderators, the approved one the readers and the notification the author
+	comments.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "held"})
+	relay.Relay(ctx)
+	expect(moderatorClient, model.GatewayTopicModeration, model.GatewayMessageCommentHeld)
+
+	comment, _ := comments.Create(ctx, moderator.ID, post.ID, &model.CommentCreateRequest{Content: "approved"})
+	relay.Relay(ctx)
+	expect(readerClient, "comments:1", model.EventCommentCreated)
+	expect(authorClient, "comments:1", model.EventCommentCreated)
+	expect(authorClient, model.GatewayTopicNotifications, model.GatewayMessageNotification)
+
+	// unsubscribed topics are not delivered
+	gateway.Unsubscribe(readerClient, "comments:1")
+	comments.Delete(ctx, comment.ID, moderator.ID)
+	relay.Relay(ctx)
+	expect(authorClient, "comments:1", model.EventCommentDeleted)
+	select {
+	case message := <-readerClient.Messages:
+		t.Fatalf("expected no message after unsubscribing, got %+v", message)
+	default:
+	}
+
+	// publication confirmations go to the author only
+	published, _ := json.Marshal(draft)
+	gateway.HandleEvent(ctx, &model.Event{AggregateType: model.AggregatePost, AggregateID: draft.ID, Type: model.EventPostPublished, Payload: published})
+	expect(authorClient, model.GatewayTopicPosts, model.EventPostPublished)
+
+	// clients that do not read are dropped
+	for range 5 {
+		gateway.Send(readerClient, &model.GatewayMessage{Type: model.GatewayMessagePong})
+	}
+	received := 0
+	for range readerClient.Messages {
+		received++
+	}
+	if received != 4 {
+		t.Fatalf("expected the slow client to be dropped after 4 messages, got %d", received)
+	}
+	if err := gateway.Subscribe(ctx, readerClient, model.GatewayTopicPosts); err != nil {
+		t.Fatalf("expected subscribing a dropped client to be a no-op, got %v", err)
+	}
+
+	// stopping the gateway closes the remaining clients
+	cancel()
+	for {
+		select {
+		case _, ok := <-authorClient.Messages:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected the client to be closed")
+		}
+	}
+}
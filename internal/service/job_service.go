@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/cron"
+	"blog-api/pkg/settings"
+)
+
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobRunning   = errors.New("job is already running")
+	ErrJobsStopped  = errors.New("jobs are stopped")
+	ErrJobQueueFull = errors.New("job queue is full")
+
+	ErrInvalidJobsConfig = errors.New("invalid jobs config")
+)
+
+// jobs
+const (
+	JobPublishScheduledPosts = "posts.publish_scheduled"
+	JobPurgeTrash            = "trash.purge"
+	JobCleanupRefreshTokens  = "refresh_tokens.cleanup"
+	JobPurgeJobRuns          = "job_runs.purge"
+)
+
+// abandonedRunError is the last error of the runs left behind by a stopped replica
+const abandonedRunError = "abandoned, the replica running it stopped"
+
+// config
+type JobsConfig struct {
+	Workers             int
+	QueueSize           int // bounds the runs waiting for a worker
+	MaxAttempts         int
+	RetryBaseSeconds    int
+	TimeoutSeconds      int
+	PostScheduler       string
+	TrashPurge          string
+	RefreshTokenCleanup string
+	RunPurge            string
+	RunRetentionHours   int
+}
+
+func (c *JobsConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "JOBS_WORKERS", Default: 4, Field: &c.Workers},
+		settings.Item[int]{Name: "JOBS_QUEUE_SIZE", Default: 100, Field: &c.QueueSize},
+		settings.Item[int]{Name: "JOBS_MAX_ATTEMPTS", Default: 3, Field: &c.MaxAttempts},
+		settings.Item[int]{Name: "JOBS_RETRY_BASE_SECONDS", Default: 10, Field: &c.RetryBaseSeconds},
+		settings.Item[int]{Name: "JOBS_TIMEOUT_SECONDS", Default: 300, Field: &c.TimeoutSeconds},
+		settings.Item[string]{Name: "JOBS_POST_SCHEDULER", Default: "@every 1m", Field: &c.PostScheduler},
+		settings.Item[string]{Name: "JOBS_TRASH_PURGE", Default: "@hourly", Field: &c.TrashPurge},
+		settings.Item[string]{Name: "JOBS_REFRESH_TOKEN_CLEANUP", Default: "@hourly", Field: &c.RefreshTokenCleanup},
+		settings.Item[string]{Name: "JOBS_RUN_PURGE", Default: "@daily", Field: &c.RunPurge},
+		settings.Item[int]{Name: "JOBS_RUN_RETENTION_HOURS", Default: 720, Field: &c.RunRetentionHours},
+	}
+}
+
+func (c *JobsConfig) Validate() error {
+	if c.Workers <= 0 || c.QueueSize <= 0 || c.MaxAttempts <= 0 {
+		return fmt.Errorf("%w: JOBS_WORKERS, JOBS_QUEUE_SIZE and JOBS_MAX_ATTEMPTS must be positive", ErrInvalidJobsConfig)
+	}
+	return nil
+}
+
+func (c *JobsConfig) Timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed ones
+func (c *JobsConfig) Backoff(attempts int) time.Duration {
+	return time.Duration(c.RetryBaseSeconds) * time.Second << min(attempts-1, 16)
+}
+
+// StaleAfter is how long a live replica may keep a run pending or running since it was scheduled or started
+func (c *JobsConfig) StaleAfter() time.Duration {
+	return c.Timeout() + c.Backoff(c.MaxAttempts)
+}
+
+func (c *JobsConfig) RunRetention() time.Duration {
+	return time.Duration(c.RunRetentionHours) * time.Hour
+}
+
+// JobFunc does the work of a job, an error is retried until the attempts run out
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name        string
+	description string
+	spec        string
+	schedule    cron.Schedule
+	fn          JobFunc
+	// runs of a job never overlap on a replica
+	mu      sync.Mutex
+	running atomic.Bool
+}
+
+type jobTask struct {
+	job *job
+	run *model.JobRun
+}
+
+/*
+JobService runs named background jobs on cron-like schedules. Every occurrence of a schedule is
+claimed as a job run keyed by the job and its scheduled time, so with several replicas
+the run is taken by one of them only. Claimed runs are executed by a worker pool and
+retried with an exponential backoff; their status is kept in the job_runs table.
+*/
+type JobService struct {
+	runRepo  repository.JobRunRepository
+	userRepo repository.UserRepository
+	config   *JobsConfig
+
+	mu      sync.RWMutex
+	jobs    map[string]*job
+	queue   chan *jobTask
+	stopped atomic.Bool
+}
+
+func NewJobService(
+	runRepo repository.JobRunRepository,
+	userRepo repository.UserRepository,
+	config *JobsConfig,
+) *JobService {
+	return &JobService{
+		runRepo:  runRepo,
+		userRepo: userRepo,
+		config:   config,
+		jobs:     map[string]*job{},
+		queue:    make(chan *jobTask, max(config.QueueSize, 1)),
+	}
+}
+
+// Register adds a job running on the schedule spec, an empty spec leaves it to manual triggers
+func (s *JobService) Register(name, description, spec string, fn JobFunc) error {
+	var schedule cron.Schedule
+	if spec != "" {
+		parsed, err := cron.Parse(spec)
+		if err != nil {
+			return fmt.Errorf("invalid schedule of job %s: %w", name, err)
+		}
+		schedule = parsed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+	s.jobs[name] = &job{name: name, description: description, spec: spec, schedule: schedule, fn: fn}
+	return nil
+}
+
+func (s *JobService) get(name string) (*job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.jobs[name]
+	return j, ok
+}
+
+func (s *JobService) all() []*job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].name < jobs[k].name })
+	return jobs
+}
+
+/*
+Enqueue claims the run of the job scheduled at the given time and queues it. Replicas asking for
+the same job and time share a single run: the one that lost the claim gets nil and no error.
+A run claimed while the queue is full is failed right away, it would block the occurrence otherwise.
+*/
+func (s *JobService) Enqueue(ctx context.Context, name, trigger string, scheduledAt time.Time) (*model.JobRun, error) {
+	j, ok := s.get(name)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	// a run claimed now would never be picked up
+	if s.stopped.Load() {
+		return nil, ErrJobsStopped
+	}
+
+	run := &model.JobRun{
+		Job:         name,
+		Trigger:     trigger,
+		ScheduledAt: scheduledAt.UTC(),
+		Status:      model.JobRunPending,
+	}
+	claimed, err := s.runRepo.Claim(ctx, run)
+	if err != nil {
+		logger.Error("failed to claim %s run at %s: %v", name, scheduledAt, err)
+		return nil, ErrDatabase
+	}
+	if !claimed {
+		logger.Debug("%s run at %s is taken by another replica", name, scheduledAt)
+		return nil, nil
+	}
+
+	// the worker updates the queued run, the caller gets a copy
+	queued := *run
+	select {
+	case s.queue <- &jobTask{job: j, run: &queued}:
+	default:
+		finished := time.Now()
+		run.Status = model.JobRunFailed
+		run.LastError = ErrJobQueueFull.Error()
+		run.FinishedAt = &finished
+		if err := s.runRepo.Update(context.WithoutCancel(ctx), run); err != nil {
+			logger.Error("failed to save %s run id=%d: %v", name, run.ID, err)
+		}
+		logger.Error("%s run id=%d dropped, the job queue is full", name, run.ID)
+		return nil, ErrJobQueueFull
+	}
+	if len(s.queue) > cap(s.queue)/2 {
+		logger.Warn("job queue is large: %d runs pending", len(s.queue))
+	}
+	return run, nil
+}
+
+/*
+StartJobs launches the worker pool and a loop that enqueues the jobs at their scheduled times.
+Stopping the jobs lets the runs in flight finish, the queued ones stay pending until a replica
+starting later or the job_runs.purge job marks them abandoned.
+*/
+func StartJobs(svc *JobService) *Handle {
+	return startHandle("jobs", svc.run)
+}
+
+func (s *JobService) run(ctx context.Context) {
+	logger.Info("jobs started, workers=%d", s.config.Workers)
+	if err := s.abandonStale(ctx); err != nil {
+		logger.Error("failed to abandon stale job runs: %v", err)
+	}
+
+	wg := &sync.WaitGroup{}
+	for range max(s.config.Workers, 1) {
+		wg.Go(func() {
+			for {
+				select {
+				case task := <-s.queue:
+					s.execute(ctx, task)
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+
+	// the next occurrence of every scheduled job
+	next := map[*job]time.Time{}
+	now := time.Now()
+	for _, j := range s.all() {
+		if j.schedule != nil {
+			next[j] = j.schedule.Next(now)
+		}
+	}
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		var earliest time.Time
+		for _, at := range next {
+			if !at.IsZero() && (earliest.IsZero() || at.Before(earliest)) {
+				earliest = at
+			}
+		}
+		if earliest.IsZero() {
+			timer.Reset(time.Hour)
+		} else {
+			timer.Reset(time.Until(earliest))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.stopped.Store(true)
+			wg.Wait()
+			logger.Info("jobs stopped, %d runs left pending", len(s.queue))
+			return
+		case <-timer.C:
+			now := time.Now()
+			for j, at := range next {
+				if at.IsZero() || at.After(now) {
+					continue
+				}
+				next[j] = j.schedule.Next(now)
+				// an occurrence overlapping a running job is skipped, the next one catches up
+				if j.running.Load() {
+					logger.Info("skipped %s run at %s, the job is still running", j.name, at)
+					continue
+				}
+				if _, err := s.Enqueue(ctx, j.name, model.JobTriggerSchedule, at); err != nil && !errors.Is(err, ErrJobsStopped) {
+					logger.Error("failed to enqueue %s: %v", j.name, err)
+				}
+			}
+		}
+	}
+}
+
+// execute makes an attempt of the run, an attempt in flight is not cancelled when the jobs are being stopped
+func (s *JobService) execute(ctx context.Context, task *jobTask) {
+	j, run := task.job, task.run
+	saveCtx := context.WithoutCancel(ctx)
+
+	j.mu.Lock()
+	j.running.Store(true)
+	defer func() {
+		j.running.Store(false)
+		j.mu.Unlock()
+	}()
+
+	started := time.Now()
+	run.Status = model.JobRunRunning
+	run.Attempts++
+	run.StartedAt = &started
+	if err := s.runRepo.Update(saveCtx, run); err != nil {
+		logger.Error("failed to mark %s run id=%d running: %v", j.name, run.ID, err)
+	}
+
+	runCtx, cancel := context.WithTimeout(saveCtx, s.config.Timeout())
+	err := j.fn(runCtx)
+	cancel()
+
+	finished := time.Now()
+	switch {
+	case err == nil:
+		run.Status = model.JobRunSucceeded
+		run.LastError = ""
+		run.FinishedAt = &finished
+		logger.Info("%s run id=%d succeeded in %s", j.name, run.ID, finished.Sub(started))
+	case run.Attempts >= s.config.MaxAttempts:
+		run.Status = model.JobRunFailed
+		run.LastError = err.Error()
+		run.FinishedAt = &finished
+		logger.Error("%s run id=%d failed after %d attempts: %v", j.name, run.ID, run.Attempts, err)
+	default:
+		run.Status = model.JobRunPending
+		run.LastError = err.Error()
+		delay := s.config.Backoff(run.Attempts)
+		logger.Warn("%s run id=%d attempt %d failed, retrying in %s: %v", j.name, run.ID, run.Attempts, delay, err)
+		time.AfterFunc(delay, func() {
+			select {
+			case s.queue <- task:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	if err := s.runRepo.Update(saveCtx, run); err != nil {
+		logger.Error("failed to save %s run id=%d: %v", j.name, run.ID, err)
+	}
+}
+
+// abandonStale fails the runs left pending or running by the replicas that stopped
+func (s *JobService) abandonStale(ctx context.Context) error {
+	now := time.Now()
+	count, err := s.runRepo.Abandon(ctx, now.Add(-s.config.StaleAfter()), now, abandonedRunError)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		logger.Warn("%d stale job runs marked failed", count)
+	}
+	return nil
+}
+
+// PurgeRuns abandons the stale runs and deletes the runs finished before the retention period
+func (s *JobService) PurgeRuns(ctx context.Context) error {
+	if err := s.abandonStale(ctx); err != nil {
+		logger.Error("failed to abandon stale job runs: %v", err)
+		return ErrDatabase
+	}
+
+	count, err := s.runRepo.Purge(ctx, time.Now().Add(-s.config.RunRetention()))
+	if err != nil {
+		logger.Error("failed to purge job runs: %v", err)
+		return ErrDatabase
+	}
+	if count > 0 {
+		logger.Info("job runs purged: %d", count)
+	}
+	return nil
+}
+
+// List returns the registered jobs along with their last runs
+func (s *JobService) List(ctx context.Context, actorID int) ([]*model.JobInfo, error) {
+	if err := ensureAdmin(ctx, s.userRepo, actorID); err != nil {
+		return nil, err
+	}
+
+	last, err := s.runRepo.GetLastRuns(ctx)
+	if err != nil {
+		logger.Error("failed to fetch last job runs: %v", err)
+		return nil, ErrDatabase
+	}
+
+	now := time.Now()
+	jobs := s.all()
+	infos := make([]*model.JobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		info := &model.JobInfo{
+			Name:        j.name,
+			Description: j.description,
+			Schedule:    j.spec,
+			Running:     j.running.Load(),
+			LastRun:     last[j.name],
+		}
+		if j.schedule != nil {
+			if next := j.schedule.Next(now); !next.IsZero() {
+				info.NextRunAt = &next
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Trigger queues a run of the job right away unless it is running on this replica
+func (s *JobService) Trigger(ctx context.Context, actorID int, name string) (*model.JobRun, error) {
+	if err := ensureAdmin(ctx, s.userRepo, actorID); err != nil {
+		return nil, err
+	}
+
+	j, ok := s.get(name)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if j.running.Load() {
+		return nil, ErrJobRunning
+	}
+
+	run, err := s.Enqueue(ctx, name, model.JobTriggerManual, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return nil, ErrJobRunning
+	}
+
+	logger.Info("user_id=%d triggered %s run id=%d", actorID, name, run.ID)
+	return run, nil
+}
+
+// GetRuns returns the run history of the job, newest first
+func (s *JobService) GetRuns(
+	ctx context.Context,
+	actorID int,
+	name string,
+	pagination *model.PaginationParams,
+) ([]*model.JobRun, int, error) {
+	if err := ensureAdmin(ctx, s.userRepo, actorID); err != nil {
+		return nil, 0, err
+	}
+	if _, ok := s.get(name); !ok {
+		return nil, 0, ErrJobNotFound
+	}
+
+	runs, err := s.runRepo.GetRuns(ctx, name, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch runs of %s: %v", name, err)
+		return nil, 0, ErrDatabase
+	}
+
+	var total int
+	if pagination.CountTotal() {
+		total, err = s.runRepo.GetRunsCount(ctx, name)
+		if err != nil {
+			logger.Error("failed to count runs of %s: %v", name, err)
+			return nil, 0, ErrDatabase
+		}
+	}
+
+	return runs, total, nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+// waitRun waits for the latest run of the job to reach the status
+func waitRun(t *testing.T, runs repository.JobRunRepository, job, status string) *model.JobRun {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		last, _ := runs.GetLastRuns(context.Background())
+		if run := last[job]; run != nil && run.Status == status {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the run of %s to be %s", job, status)
+	return nil
+}
+
+func TestJobServiceReplicas(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	runs := repository.NewInMemoryJobRunRepo()
+	var calls atomic.Int32
+
+	// every replica registers the same jobs against the same database
+	var handles []*Handle
+	for range 3 {
+		jobs := NewJobService(runs, repository.NewInMemoryUserRepo(), &JobsConfig{Workers: 2, QueueSize: 10, MaxAttempts: 1, TimeoutSeconds: 10})
+		if err := jobs.Register("tick", "", "@every 1s", func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		handles = append(handles, StartJobs(jobs))
+	}
+
+	time.Sleep(2500 * time.Millisecond)
+	for _, handle := range handles {
+		handle.Stop(ctx)
+	}
+
+	history, _ := runs.GetRuns(ctx, "tick", 0, 0)
+	if len(history) < 2 || int(calls.Load()) != len(history) {
+		t.Fatalf("expected every occurrence to run once, got %d runs and %d calls", len(history), calls.Load())
+	}
+	for _, run := range history {
+		if run.Trigger != model.JobTriggerSchedule || run.Status != model.JobRunSucceeded || !run.ScheduledAt.Equal(run.ScheduledAt.Truncate(time.Second)) {
+			t.Fatalf("expected a succeeded run at a whole second, got %+v", run)
+		}
+	}
+}
+
+func TestJobServiceRetries(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	runs := repository.NewInMemoryJobRunRepo()
+	jobs := NewJobService(runs, repository.NewInMemoryUserRepo(), &JobsConfig{Workers: 2, QueueSize: 10, MaxAttempts: 3, TimeoutSeconds: 10})
+
+	var flakyCalls atomic.Int32
+	jobs.Register("flaky", "", "", func(ctx context.Context) error {
+		if flakyCalls.Add(1) < 3 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	jobs.Register("broken", "", "", func(ctx context.Context) error {
+		return errors.New("permanent failure")
+	})
+
+	handle := StartJobs(jobs)
+	defer handle.Stop(ctx)
+
+	for _, name := range []string{"flaky", "broken"} {
+		if _, err := jobs.Enqueue(ctx, name, model.JobTriggerManual, time.Now()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if run := waitRun(t, runs, "flaky", model.JobRunSucceeded); run.Attempts != 3 || run.LastError != "" || run.FinishedAt == nil {
+		t.Fatalf("expected the run to succeed on the third attempt, got %+v", run)
+	}
+	if run := waitRun(t, runs, "broken", model.JobRunFailed); run.Attempts != 3 || run.LastError != "permanent failure" {
+		t.Fatalf("expected the run to give up after 3 attempts, got %+v", run)
+	}
+
+	if _, err := jobs.Enqueue(ctx, "missing", model.JobTriggerManual, time.Now()); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+	if err := jobs.Register("invalid", "", "* * *", nil); err == nil {
+		t.Fatalf("expected an invalid schedule to be rejected")
+	}
+}
+
+func TestJobServiceAdmin(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	userRepo := repository.NewInMemoryUserRepo()
+	admin := &model.User{Username: "admin", Email: "admin@example.com", Role: model.UserRoleAdmin}
+	user := &model.User{Username: "user", Email: "user@example.com"}
+	for _, u := range []*model.User{admin, user} {
+		userRepo.Create(ctx, u)
+	}
+
+	runs := repository.NewInMemoryJobRunRepo()
+	jobs := NewJobService(runs, userRepo, &JobsConfig{Workers: 1, QueueSize: 10, MaxAttempts: 1, TimeoutSeconds: 10})
+	release := make(chan struct{})
+	jobs.Register("slow", "waits to be released", "@daily", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	handle := StartJobs(jobs)
+	defer handle.Stop(ctx)
+
+	if _, err := jobs.List(ctx, user.ID); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if _, err := jobs.Trigger(ctx, user.ID, "slow"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if _, err := jobs.Trigger(ctx, admin.ID, "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+
+	run, err := jobs.Trigger(ctx, admin.ID, "slow")
+	if err != nil || run.Trigger != model.JobTriggerManual {
+		t.Fatalf("expected a manual run, got %+v, %v", run, err)
+	}
+	waitRun(t, runs, "slow", model.JobRunRunning)
+	if _, err := jobs.Trigger(ctx, admin.ID, "slow"); !errors.Is(err, ErrJobRunning) {
+		t.Fatalf("expected ErrJobRunning, got %v", err)
+	}
+
+	infos, err := jobs.List(ctx, admin.ID)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("expected one job, got %+v, %v", infos, err)
+	}
+	if info := infos[0]; !info.Running || info.NextRunAt == nil || info.LastRun == nil || info.LastRun.ID != run.ID {
+		t.Fatalf("expected the running job with its next and last runs, got %+v", info)
+	}
+
+	close(release)
+	waitRun(t, runs, "slow", model.JobRunSucceeded)
+
+	history, total, err := jobs.GetRuns(ctx, admin.ID, "slow", &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)})
+	if err != nil || total != 1 || len(history) != 1 || history[0].Status != model.JobRunSucceeded {
+		t.Fatalf("expected a succeeded run in the history, got %+v, %d, %v", history, total, err)
+	}
+	history, total, err = jobs.GetRuns(ctx, admin.ID, "slow", &model.PaginationParams{Limit: ptr(10), Offset: ptr(0), WithTotal: ptr(false)})
+	if err != nil || total != 0 || len(history) != 1 {
+		t.Fatalf("expected the history without the total, got %+v, %d, %v", history, total, err)
+	}
+}
+
+func TestJobServiceStop(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	runs := repository.NewInMemoryJobRunRepo()
+	jobs := NewJobService(runs, repository.NewInMemoryUserRepo(), &JobsConfig{Workers: 1, QueueSize: 10, MaxAttempts: 1, TimeoutSeconds: 10})
+	started, release := make(chan struct{}), make(chan struct{})
+	jobs.Register("slow", "", "", func(ctx context.Context) error {
+		close(started)
+		<-release
+		// the run in flight keeps a live context while the jobs stop
+		return ctx.Err()
+	})
+
+	handle := StartJobs(jobs)
+	if _, err := jobs.Enqueue(ctx, "slow", model.JobTriggerManual, time.Now()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	<-started
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := handle.Stop(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the stop to time out while the run is in flight, got %v", err)
+	}
+	if _, err := jobs.Enqueue(ctx, "slow", model.JobTriggerManual, time.Now()); !errors.Is(err, ErrJobsStopped) {
+		t.Fatalf("expected ErrJobsStopped, got %v", err)
+	}
+
+	close(release)
+	if err := handle.Stop(ctx); err != nil {
+		t.Fatalf("expected the jobs to stop, got %v", err)
+	}
+	if last, _ := runs.GetLastRuns(ctx); last["slow"].Status != model.JobRunSucceeded {
+		t.Fatalf("expected the run in flight to succeed, got %+v", last["slow"])
+	}
+}
+
+func TestJobServiceQueueFull(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	// no workers take the runs off the queue
+	runs := repository.NewInMemoryJobRunRepo()
+	config := &JobsConfig{Workers: 1, QueueSize: 3, MaxAttempts: 1, TimeoutSeconds: 10}
+	jobs := NewJobService(runs, repository.NewInMemoryUserRepo(), config)
+	jobs.Register("noop", "", "", func(ctx context.Context) error { return nil })
+
+	at := time.Now()
+	for i := range config.QueueSize {
+		if _, err := jobs.Enqueue(ctx, "noop", model.JobTriggerManual, at.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if _, err := jobs.Enqueue(ctx, "noop", model.JobTriggerManual, at.Add(-time.Second)); !errors.Is(err, ErrJobQueueFull) {
+		t.Fatalf("expected ErrJobQueueFull, got %v", err)
+	}
+	if run := waitRun(t, runs, "noop", model.JobRunFailed); run.LastError != ErrJobQueueFull.Error() || run.FinishedAt == nil {
+		t.Fatalf("expected the dropped run to fail, got %+v", run)
+	}
+}
+
+func TestJobServiceStaleRuns(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	runs := repository.NewInMemoryJobRunRepo()
+	now := time.Now()
+	hourAgo, monthAgo := now.Add(-time.Hour), now.Add(-31*24*time.Hour)
+
+	// runs left behind by a stopped replica, a recent one and a finished one past the retention
+	for _, run := range []*model.JobRun{
+		{Job: "noop", ScheduledAt: hourAgo, Status: model.JobRunPending},
+		{Job: "noop", ScheduledAt: hourAgo.Add(time.Second), Status: model.JobRunRunning, Attempts: 1, StartedAt: &hourAgo},
+		{Job: "noop", ScheduledAt: now, Status: model.JobRunRunning, Attempts: 1, StartedAt: &now},
+		{Job: "noop", ScheduledAt: monthAgo, Status: model.JobRunSucceeded, Attempts: 1, StartedAt: &monthAgo, FinishedAt: &monthAgo},
+	} {
+		runs.Claim(ctx, run)
+	}
+
+	config := &JobsConfig{Workers: 1, QueueSize: 10, MaxAttempts: 3, RetryBaseSeconds: 10, TimeoutSeconds: 300, RunRetentionHours: 720}
+	jobs := NewJobService(runs, repository.NewInMemoryUserRepo(), config)
+	jobs.Register("noop", "", "", func(ctx context.Context) error { return nil })
+
+	handle := StartJobs(jobs)
+	defer handle.Stop(ctx)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		history, _ := runs.GetRuns(ctx, "noop", 0, 0)
+		if history[2].Status == model.JobRunFailed && history[3].Status == model.JobRunFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the stale runs to be abandoned, got %+v, %+v", history[2], history[3])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := jobs.PurgeRuns(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	history, _ := runs.GetRuns(ctx, "noop", 0, 0)
+	if len(history) != 3 {
+		t.Fatalf("expected the old finished run to be purged, got %d runs", len(history))
+	}
+	for _, run := range history {
+		if run.ScheduledAt.Equal(now) {
+			if run.Status != model.JobRunRunning {
+				t.Fatalf("expected the recent run to be left running, got %+v", run)
+			}
+		} else if run.Status != model.JobRunFailed || run.LastError != abandonedRunError || run.FinishedAt == nil {
+			t.Fatalf("expected the stale run to be abandoned, got %+v", run)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/markup"
+)
+
+// MaxMentions limits the distinct usernames looked up per text, the rest stay plain text
+const MaxMentions = 20
+
+// resolveMentions finds the @username references of the text that name existing users
+func resolveMentions(ctx context.Context, userRepo repository.UserRepository, text string) (model.MentionRanges, error) {
+	users := make(map[string]*model.User)
+	ranges := model.MentionRanges{}
+
+	for _, mention := range markup.FindMentions(text) {
+		user, looked := users[mention.Username]
+		if !looked {
+			if len(users) >= MaxMentions {
+				continue
+			}
+			var err error
+			user, err = userRepo.GetByField(ctx, "username", mention.Username)
+			if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+				logger.Error("failed to resolve mention @%s: %v", mention.Username, err)
+				return nil, ErrDatabase
+			}
+			users[mention.Username] = user
+		}
+		if user == nil {
+			continue
+		}
+		ranges = append(ranges, model.MentionRange{
+			UserID:   user.ID,
+			Username: user.Username,
+			Start:    mention.Start,
+			End:      mention.End,
+		})
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/contentfilter"
+)
+
+// commentStatusActions names the moderation log action of each comment decision
+var commentStatusActions = map[string]string{
+	model.CommentStatusApproved: model.ModerationActionApprove,
+	model.CommentStatusRejected: model.ModerationActionReject,
+	model.CommentStatusSpam:     model.ModerationActionSpam,
+}
+
+type ModerationService struct {
+	commentRepo repository.CommentRepository
+	postRepo    repository.PostRepository
+	userRepo    repository.UserRepository
+	sampleRepo  repository.SpamSampleRepository
+	logRepo     repository.ModerationLogRepository
+	eventRepo   repository.EventRepository
+	classifier  *contentfilter.Bayes
+}
+
+func NewModerationService(
+	commentRepo repository.CommentRepository,
+	postRepo repository.PostRepository,
+	userRepo repository.UserRepository,
+	sampleRepo repository.SpamSampleRepository,
+	logRepo repository.ModerationLogRepository,
+	eventRepo repository.EventRepository,
+	classifier *contentfilter.Bayes,
+) *ModerationService {
+	return &ModerationService{
+		commentRepo: commentRepo,
+		postRepo:    postRepo,
+		userRepo:    userRepo,
+		sampleRepo:  sampleRepo,
+		logRepo:     logRepo,
+		eventRepo:   eventRepo,
+		classifier:  classifier,
+	}
+}
+
+// GetQueue lists comments in the requested status, moderators see every post and authors their own posts
+func (s *ModerationService) GetQueue(
+	ctx context.Context,
+	userID int,
+	query *model.CommentQueueParams,
+	pagination *model.PaginationParams,
+) ([]*model.Comment, int, error) {
+
+	filter, err := s.scopeFilter(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.Statuses = []string{query.Status}
+	filter.PostID = query.PostID
+	filter.OldestFirst = true
+
+	comments, err := s.commentRepo.GetComments(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch %s comments for moderator user_id=%d: %v", query.Status, userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return comments, 0, nil
+	}
+
+	total, err := s.commentRepo.GetCommentsCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count %s comments for moderator user_id=%d: %v", query.Status, userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return comments, total, nil
+}
+
+func (s *ModerationService) Approve(ctx context.Context, userID int, ids []int) (*model.ModerationResult, error) {
+	return s.setStatus(ctx, userID, ids, model.CommentStatusApproved)
+}
+
+func (s *ModerationService) Reject(ctx context.Context, userID int, ids []int, spam bool) (*model.ModerationResult, error) {
+	status := model.CommentStatusRejected
+	if spam {
+		status = model.CommentStatusSpam
+	}
+	return s.setStatus(ctx, userID, ids, status)
+}
+
+// setStatus applies the decision to the comments the user may moderate and reports the rest as skipped
+func (s *ModerationService) setStatus(
+	ctx context.Context,
+	userID int,
+	ids []int,
+	status string,
+) (*model.ModerationResult, error) {
+
+	filter, err := s.scopeFilter(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	filter.IDs = ids
+
+	comments, err := s.commentRepo.GetComments(ctx, filter, 0, 0)
+	if err != nil {
+		logger.Error("failed to fetch comments to moderate for user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+
+	result := &model.ModerationResult{Updated: []int{}, Skipped: []int{}}
+	for _, c := range comments {
+		result.Updated = append(result.Updated, c.ID)
+	}
+	for _, id := range ids {
+		if !slices.Contains(result.Updated, id) && !slices.Contains(result.Skipped, id) {
+			result.Skipped = append(result.Skipped, id)
+		}
+	}
+	slices.Sort(result.Updated)
+
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.commentRepo.SetStatus(ctx, result.Updated, status); err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if c.Status == status {
+				continue
+			}
+			changed := *c
+			changed.Status = status
+			if err := recordEvent(ctx, s.eventRepo, model.AggregateComment, c.ID, model.EventCommentUpdated, &changed); err != nil {
+				return err
+			}
+			// held comments were not announced yet, the relay announces them
+			if status == model.CommentStatusApproved {
+				if err := recordEvent(ctx, s.eventRepo, model.AggregateComment, c.ID, model.EventCommentApproved, &changed); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to set status=%s on comments %v: %v", status, result.Updated, err)
+		return nil, ErrDatabase
+	}
+
+	logger.Info("user_id=%d moved comments %v to status=%s", userID, result.Updated, status)
+
+	for _, id := range result.Updated {
+		entry := &model.ModerationLogEntry{
+			ModeratorID: &userID,
+			Action:      commentStatusActions[status],
+			TargetType:  model.ReportTargetComment,
+			TargetID:    id,
+		}
+		if err := recordDecision(ctx, s.logRepo, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if status == model.CommentStatusApproved || status == model.CommentStatusSpam {
+		for _, c := range comments {
+			s.learn(ctx, c.Content, status == model.CommentStatusSpam)
+		}
+	}
+
+	return result, nil
+}
+
+// GetHeldPosts lists posts held by the content filter, oldest first
+func (s *ModerationService) GetHeldPosts(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+) ([]*model.Post, int, error) {
+
+	if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+		return nil, 0, err
+	}
+
+	held := true
+	filter := &repository.PostFilter{
+		Held: &held,
+		Sort: &repository.PostSort{Field: model.PostSortCreatedAt},
+	}
+
+	posts, err := s.postRepo.GetPosts(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch held posts: %v", err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return posts, 0, nil
+	}
+
+	total, err := s.postRepo.GetPostsCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count held posts: %v", err)
+		return nil, 0, ErrDatabase
+	}
+
+	return posts, total, nil
+}
+
+// ApprovePosts releases held posts, the ones already due are published right away
+func (s *ModerationService) ApprovePosts(ctx context.Context, userID int, ids []int) (*model.ModerationResult, error) {
+	decide := func(ctx context.Context, post *model.Post) error {
+		post.Held = false
+		setPublicationState(post, time.Now())
+		if err := s.postRepo.Update(ctx, post); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostUpdated, post); err != nil {
+			return err
+		}
+		if post.Published {
+			return recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostPublished, post)
+		}
+		return nil
+	}
+	// the relay announces the published ones
+	return s.decidePosts(ctx, userID, ids, model.ModerationActionApprove, decide, func(post *model.Post) {
+		s.learn(ctx, postText(post), false)
+	})
+}
+
+// RejectPosts moves held posts to the trash of their authors
+func (s *ModerationService) RejectPosts(ctx context.Context, userID int, ids []int, spam bool) (*model.ModerationResult, error) {
+	action := model.ModerationActionReject
+	if spam {
+		action = model.ModerationActionSpam
+	}
+	decide := func(ctx context.Context, post *model.Post) error {
+		if err := s.postRepo.Delete(ctx, post.ID); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostDeleted, post)
+	}
+	// the relay announces the deletion
+	return s.decidePosts(ctx, userID, ids, action, decide, func(post *model.Post) {
+		if spam {
+			s.learn(ctx, postText(post), true)
+		}
+	})
+}
+
+// decidePosts applies the decision to each held post in a transaction with its outbox events,
+// the side effects run once the decision is committed
+func (s *ModerationService) decidePosts(
+	ctx context.Context,
+	userID int,
+	ids []int,
+	action string,
+	decide func(ctx context.Context, post *model.Post) error,
+	after func(post *model.Post),
+) (*model.ModerationResult, error) {
+
+	if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+		return nil, err
+	}
+
+	held := true
+	result := &model.ModerationResult{Updated: []int{}, Skipped: []int{}}
+	for _, id := range ids {
+		if slices.Contains(result.Updated, id) || slices.Contains(result.Skipped, id) {
+			continue
+		}
+
+		post, err := s.postRepo.GetPost(ctx, id, &repository.PostFilter{Held: &held})
+		if err != nil {
+			if errors.Is(err, repository.ErrPostNotFound) {
+				result.Skipped = append(result.Skipped, id)
+				continue
+			}
+			logger.Error("failed to fetch held post id=%d: %v", id, err)
+			return nil, ErrDatabase
+		}
+
+		err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+			return decide(ctx, post)
+		})
+		if err != nil {
+			logger.Error("failed to apply moderation decision to post id=%d: %v", id, err)
+			return nil, ErrDatabase
+		}
+		entry := &model.ModerationLogEntry{
+			ModeratorID: &userID,
+			Action:      action,
+			TargetType:  model.ReportTargetPost,
+			TargetID:    id,
+		}
+		if err := recordDecision(ctx, s.logRepo, entry); err != nil {
+			return nil, err
+		}
+		after(post)
+		result.Updated = append(result.Updated, id)
+	}
+
+	logger.Info("user_id=%d moderated held posts %v", userID, result.Updated)
+	return result, nil
+}
+
+// learn stores the decision for future restarts and trains the running classifier
+func (s *ModerationService) learn(ctx context.Context, text string, spam bool) {
+	if s.classifier == nil {
+		return
+	}
+	if err := s.sampleRepo.Create(ctx, &model.SpamSample{Content: text, Spam: spam}); err != nil {
+		logger.Error("failed to store spam sample: %v", err)
+	}
+	s.classifier.Train(text, spam)
+}
+
+// scopeFilter limits moderation to the posts of the user unless they are a moderator
+func (s *ModerationService) scopeFilter(ctx context.Context, userID int) (*repository.CommentFilter, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Error("failed to fetch moderator user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+
+	if user.CanModerate() {
+		return &repository.CommentFilter{}, nil
+	}
+	return &repository.CommentFilter{PostAuthorID: &userID}, nil
+}
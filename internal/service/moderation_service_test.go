@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestModerationServicePremoderation(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	commentRepo.LinkPosts(postRepo)
+
+	eventRepo := repository.NewInMemoryEventRepo()
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+	notifications := NewNotificationService(notificationRepo, repository.NewInMemoryNotificationPreferenceRepo(), postRepo, commentRepo, nil)
+	comments := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, eventRepo, &CommentConfig{MaxDepth: 5}, nil, notifications, nil)
+	moderation := NewModerationService(
+		commentRepo,
+		postRepo,
+		userRepo,
+		repository.NewInMemorySpamSampleRepo(),
+		repository.NewInMemoryModerationLogRepo(),
+		eventRepo,
+		nil,
+	)
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("comment_announcements", comments.AnnounceCreated, model.EventCommentCreated, model.EventCommentApproved)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	moderator := &model.User{Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator}
+	for _, u := range []*model.User{author, reader, moderator} {
+		userRepo.Create(ctx, u)
+	}
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID, CommentsPremoderation: true}
+	postRepo.Create(ctx, post)
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+
+	// author and moderators bypass the queue
+	own, _ := comments.Create(ctx, author.ID, post.ID, &model.CommentCreateRequest{Content: "own"})
+	if own.Status != model.CommentStatusApproved {
+		t.Fatalf("expected author comment to be approved, got %q", own.Status)
+	}
+	held, _ := comments.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "held, @author"})
+	if held.Status != model.CommentStatusPending {
+		t.Fatalf("expected reader comment to be pending, got %q", held.Status)
+	}
+
+	// pending comments are visible to their author only
+	if _, total, _ := comments.GetByPost(ctx, post.ID, nil, pagination); total != 1 {
+		t.Fatalf("expected 1 public comment, got %d", total)
+	}
+	if _, total, _ := comments.GetByPost(ctx, post.ID, &reader.ID, pagination); total != 2 {
+		t.Fatalf("expected reader to see own pending comment, got %d", total)
+	}
+
+	// queue
+	queue := &model.CommentQueueParams{Status: model.CommentStatusPending}
+	list, total, err := moderation.GetQueue(ctx, author.ID, queue, pagination)
+	if err != nil || total != 1 || list[0].ID != held.ID {
+		t.Fatalf("expected held comment in author queue, got total=%d err=%v", total, err)
+	}
+	if _, total, _ := moderation.GetQueue(ctx, reader.ID, queue, pagination); total != 0 {
+		t.Fatalf("expected empty queue for reader, got %d", total)
+	}
+
+	// only post authors and moderators can decide
+	result, err := moderation.Approve(ctx, reader.ID, []int{held.ID})
+	if err != nil || len(result.Updated) != 0 || len(result.Skipped) != 1 {
+		t.Fatalf("expected reader approval to be skipped, got %+v err=%v", result, err)
+	}
+	result, err = moderation.Approve(ctx, moderator.ID, []int{held.ID})
+	if err != nil || len(result.Updated) != 1 {
+		t.Fatalf("expected moderator approval, got %+v err=%v", result, err)
+	}
+	if _, total, _ := comments.GetByPost(ctx, post.ID, nil, pagination); total != 2 {
+		t.Fatalf("expected approved comment to be public, got %d", total)
+	}
+	if n := notificationRepo.All(); len(n) != 0 {
+		t.Fatalf("expected no notifications before the relay, got %+v", n)
+	}
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n := notificationRepo.All(); len(n) != 1 || n[0].UserID != author.ID || *n[0].ActorID != reader.ID {
+		t.Fatalf("expected approval to announce the mention, got %+v", n)
+	}
+
+	// rejected comments leave the public listing
+	if _, err := moderation.Reject(ctx, author.ID, []int{held.ID}, true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	spam := &model.CommentQueueParams{Status: model.CommentStatusSpam}
+	if _, total, _ := moderation.GetQueue(ctx, author.ID, spam, pagination); total != 1 {
+		t.Fatalf("expected comment in spam, got %d", total)
+	}
+	if _, total, _ := comments.GetByPost(ctx, post.ID, &reader.ID, pagination); total != 1 {
+		t.Fatalf("expected spam to be hidden even from its author, got %d", total)
+	}
+}
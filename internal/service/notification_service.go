@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationService delivers in-app notifications, a nil service drops them
+type NotificationService struct {
+	notificationRepo repository.NotificationRepository
+	preferenceRepo   repository.NotificationPreferenceRepository
+	postRepo         repository.PostRepository
+	commentRepo      repository.CommentRepository
+	gateway          *GatewayService
+}
+
+func NewNotificationService(
+	notificationRepo repository.NotificationRepository,
+	preferenceRepo repository.NotificationPreferenceRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	gateway *GatewayService,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		preferenceRepo:   preferenceRepo,
+		postRepo:         postRepo,
+		commentRepo:      commentRepo,
+		gateway:          gateway,
+	}
+}
+
+// Notify stores the notification unless the user turned its kind off, users are never notified about their own actions.
+// A failure is logged and does not affect the action that caused the notification.
+func (s *NotificationService) Notify(ctx context.Context, notification *model.Notification) {
+	if s == nil {
+		return
+	}
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return
+	}
+
+	preferences, err := s.preferences(ctx, notification.UserID)
+	if err != nil {
+		logger.Error("failed to fetch notification preferences of user_id=%d: %v", notification.UserID, err)
+		return
+	}
+	if !preferences.Enabled(notification.Kind) {
+		logger.Debug("user_id=%d turned %s notifications off", notification.UserID, notification.Kind)
+		return
+	}
+
+	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+		logger.Error("failed to notify user_id=%d about %s: %v", notification.UserID, notification.Kind, err)
+		return
+	}
+	logger.Debug("notified user_id=%d about %s on post_id=%d", notification.UserID, notification.Kind, notification.PostID)
+	s.gateway.PushToUser(ctx, notification.UserID, model.GatewayTopicNotifications, model.GatewayMessageNotification, notification)
+}
+
+// NotifyMentions notifies the users mentioned in current that were not mentioned in previous
+func (s *NotificationService) NotifyMentions(
+	ctx context.Context,
+	actorID, postID int,
+	commentID *int,
+	previous, current model.MentionRanges,
+) {
+	known := previous.UserIDs()
+	for _, userID := range current.UserIDs() {
+		if slices.Contains(known, userID) {
+			continue
+		}
+		s.Notify(ctx, &model.Notification{
+			UserID:    userID,
+			Kind:      model.NotificationMention,
+			ActorID:   &actorID,
+			PostID:    postID,
+			CommentID: commentID,
+		})
+	}
+}
+
+// NotifyComment announces a comment that became visible to readers.
+// Each user hears about the comment once: a reply beats a mention and a mention beats a comment on their post.
+func (s *NotificationService) NotifyComment(ctx context.Context, comment *model.Comment) {
+	if s == nil {
+		return
+	}
+
+	notified := []int{comment.AuthorID}
+	notify := func(userID int, kind string) {
+		if slices.Contains(notified, userID) {
+			return
+		}
+		notified = append(notified, userID)
+		s.Notify(ctx, &model.Notification{
+			UserID:    userID,
+			Kind:      kind,
+			ActorID:   &comment.AuthorID,
+			PostID:    comment.PostID,
+			CommentID: &comment.ID,
+		})
+	}
+
+	if comment.ParentID != nil {
+		parent, err := s.commentRepo.GetByID(ctx, *comment.ParentID)
+		switch {
+		case err == nil:
+			notify(parent.AuthorID, model.NotificationReply)
+		case !errors.Is(err, repository.ErrCommentNotFound):
+			logger.Error("failed to fetch parent comment id=%d to notify: %v", *comment.ParentID, err)
+		}
+	}
+
+	for _, userID := range comment.Mentions.UserIDs() {
+		notify(userID, model.NotificationMention)
+	}
+
+	post, err := s.postRepo.GetPost(ctx, comment.PostID, nil)
+	if err != nil {
+		logger.Error("failed to fetch post id=%d to notify: %v", comment.PostID, err)
+		return
+	}
+	notify(post.AuthorID, model.NotificationComment)
+}
+
+// NotifyPublished tells the author that the scheduled post went live and announces its mentions
+func (s *NotificationService) NotifyPublished(ctx context.Context, post *model.Post) {
+	if s == nil {
+		return
+	}
+	s.Notify(ctx, &model.Notification{
+		UserID: post.AuthorID,
+		Kind:   model.NotificationPostPublished,
+		PostID: post.ID,
+	})
+	s.NotifyMentions(ctx, post.AuthorID, post.ID, nil, nil, post.Mentions)
+}
+
+// GetNotifications lists the notifications of the user, newest first
+func (s *NotificationService) GetNotifications(
+	ctx context.Context,
+	userID int,
+	query *model.NotificationListParams,
+	pagination *model.PaginationParams,
+) ([]*model.Notification, int, error) {
+
+	filter := &repository.NotificationFilter{UserID: userID, Unread: query.Unread}
+
+	notifications, err := s.notificationRepo.GetNotifications(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch notifications of user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return notifications, 0, nil
+	}
+
+	total, err := s.notificationRepo.GetNotificationsCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count notifications of user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return notifications, total, nil
+}
+
+// MarkRead marks a notification of the user as read, notifications of other users are not found
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id int) (*model.Notification, error) {
+	notification, err := s.notificationRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotificationNotFound) {
+			return nil, ErrNotificationNotFound
+		}
+		logger.Error("failed to fetch notification id=%d: %v", id, err)
+		return nil, ErrDatabase
+	}
+	if notification.UserID != userID {
+		logger.Info("user_id=%d tried to read notification id=%d of user_id=%d", userID, id, notification.UserID)
+		return nil, ErrNotificationNotFound
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	now := time.Now()
+	if _, err := s.notificationRepo.MarkRead(ctx, userID, []int{id}, now); err != nil {
+		logger.Error("failed to mark notification id=%d as read: %v", id, err)
+		return nil, ErrDatabase
+	}
+	notification.ReadAt = &now
+
+	return notification, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int) (*model.NotificationsReadResult, error) {
+	updated, err := s.notificationRepo.MarkRead(ctx, userID, nil, time.Now())
+	if err != nil {
+		logger.Error("failed to mark notifications of user_id=%d as read: %v", userID, err)
+		return nil, ErrDatabase
+	}
+
+	logger.Info("user_id=%d marked %d notifications as read", userID, updated)
+	return &model.NotificationsReadResult{Updated: updated}, nil
+}
+
+func (s *NotificationService) GetPreferences(ctx context.Context, userID int) (*model.NotificationPreferences, error) {
+	preferences, err := s.preferences(ctx, userID)
+	if err != nil {
+		logger.Error("failed to fetch notification preferences of user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences changes the kinds given in the request, the rest keep their values
+func (s *NotificationService) UpdatePreferences(
+	ctx context.Context,
+	userID int,
+	req *model.NotificationPreferencesRequest,
+) (*model.NotificationPreferences, error) {
+
+	preferences, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Comment != nil {
+		preferences.Comment = *req.Comment
+	}
+	if req.Reply != nil {
+		preferences.Reply = *req.Reply
+	}
+	if req.Mention != nil {
+		preferences.Mention = *req.Mention
+	}
+	if req.PostPublished != nil {
+		preferences.PostPublished = *req.PostPublished
+	}
+
+	if err := s.preferenceRepo.Save(ctx, preferences); err != nil {
+		logger.Error("failed to save notification preferences of user_id=%d: %v", userID, err)
+		return nil, ErrDatabase
+	}
+
+	return preferences, nil
+}
+
+// preferences returns the stored preferences of the user or the defaults
+func (s *NotificationService) preferences(ctx context.Context, userID int) (*model.NotificationPreferences, error) {
+	preferences, err := s.preferenceRepo.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotificationPreferencesNotFound) {
+		return model.DefaultNotificationPreferences(userID), nil
+	}
+	return preferences, err
+}
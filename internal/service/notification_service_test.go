@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestNotificationServiceEvents(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+	notifications := NewNotificationService(notificationRepo, repository.NewInMemoryNotificationPreferenceRepo(), postRepo, commentRepo, nil)
+	eventRepo := repository.NewInMemoryEventRepo()
+	comments := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, eventRepo, &CommentConfig{MaxDepth: 5}, nil, notifications, nil)
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("comment_announcements", comments.AnnounceCreated, model.EventCommentCreated, model.EventCommentApproved)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	alice := &model.User{Username: "alice", Email: "alice@example.com"}
+	bob := &model.User{Username: "bob", Email: "bob@example.com"}
+	for _, u := range []*model.User{author, alice, bob} {
+		userRepo.Create(ctx, u)
+	}
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID}
+	postRepo.Create(ctx, post)
+
+	// create announces the comment through the relay
+	create := func(userID int, req *model.CommentCreateRequest) *model.Comment {
+		t.Helper()
+		comment, err := comments.Create(ctx, userID, post.ID, req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := relay.Relay(ctx); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return comment
+	}
+
+	// the post author hears about a new comment
+	first := create(alice.ID, &model.CommentCreateRequest{Content: "first"})
+	n := notificationRepo.All()
+	if len(n) != 1 || n[0].UserID != author.ID || n[0].Kind != model.NotificationComment || *n[0].ActorID != alice.ID {
+		t.Fatalf("expected a comment notification for the post author, got %+v", n)
+	}
+
+	// a reply notifies the parent author, the post author hears about it once
+	create(bob.ID, &model.CommentCreateRequest{Content: "reply to @author", ParentID: &first.ID})
+	n = notificationRepo.All()[1:]
+	if len(n) != 2 {
+		t.Fatalf("expected 2 new notifications, got %+v", n)
+	}
+	if n[0].UserID != alice.ID || n[0].Kind != model.NotificationReply {
+		t.Fatalf("expected a reply notification for alice, got %+v", n[0])
+	}
+	if n[1].UserID != author.ID || n[1].Kind != model.NotificationMention {
+		t.Fatalf("expected a mention notification for the author, got %+v", n[1])
+	}
+
+	// replying to yourself on your own post is silent
+	create(alice.ID, &model.CommentCreateRequest{Content: "me again", ParentID: &first.ID})
+	if len(notificationRepo.All()) != 4 {
+		t.Fatalf("expected only the post author to be notified, got %+v", notificationRepo.All()[3:])
+	}
+
+	// preferences
+	off := false
+	preferences, err := notifications.UpdatePreferences(ctx, author.ID, &model.NotificationPreferencesRequest{Comment: &off})
+	if err != nil || preferences.Comment || !preferences.Reply || !preferences.PostPublished {
+		t.Fatalf("expected comment notifications off only, got %+v err=%v", preferences, err)
+	}
+	create(bob.ID, &model.CommentCreateRequest{Content: "muted"})
+	if len(notificationRepo.All()) != 4 {
+		t.Fatalf("expected muted comment notification, got %+v", notificationRepo.All()[4:])
+	}
+
+	// scheduled post went live
+	notifications.NotifyPublished(ctx, &model.Post{ID: post.ID, AuthorID: author.ID})
+	n = notificationRepo.All()
+	if len(n) != 5 || n[4].Kind != model.NotificationPostPublished || n[4].ActorID != nil {
+		t.Fatalf("expected a post_published notification, got %+v", n[4:])
+	}
+}
+
+func TestNotificationServiceRead(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+	svc := NewNotificationService(
+		notificationRepo,
+		repository.NewInMemoryNotificationPreferenceRepo(),
+		repository.NewInMemoryPostRepo(),
+		repository.NewInMemoryCommentRepo(),
+		nil,
+	)
+
+	for postID := 1; postID <= 3; postID++ {
+		svc.Notify(ctx, &model.Notification{UserID: 1, Kind: model.NotificationPostPublished, PostID: postID})
+	}
+	svc.Notify(ctx, &model.Notification{UserID: 2, Kind: model.NotificationPostPublished, PostID: 4})
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	unread := &model.NotificationListParams{Unread: true}
+
+	list, total, err := svc.GetNotifications(ctx, 1, unread, pagination)
+	if err != nil || total != 3 || list[0].PostID != 3 {
+		t.Fatalf("expected 3 unread notifications newest first, got total=%d err=%v", total, err)
+	}
+
+	read, err := svc.MarkRead(ctx, 1, list[0].ID)
+	if err != nil || read.ReadAt == nil {
+		t.Fatalf("expected notification to be read, got %+v err=%v", read, err)
+	}
+	if _, total, _ := svc.GetNotifications(ctx, 1, unread, pagination); total != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d", total)
+	}
+
+	// notifications of other users are not found
+	if _, err := svc.MarkRead(ctx, 2, list[1].ID); !errors.Is(err, ErrNotificationNotFound) {
+		t.Fatalf("expected ErrNotificationNotFound, got %v", err)
+	}
+
+	result, err := svc.MarkAllRead(ctx, 1)
+	if err != nil || result.Updated != 2 {
+		t.Fatalf("expected 2 notifications marked as read, got %+v err=%v", result, err)
+	}
+	if _, total, _ := svc.GetNotifications(ctx, 1, &model.NotificationListParams{}, pagination); total != 3 {
+		t.Fatalf("expected read notifications to stay listed, got %d", total)
+	}
+	if _, total, _ := svc.GetNotifications(ctx, 2, unread, pagination); total != 1 {
+		t.Fatalf("expected notifications of other users to stay unread, got %d", total)
+	}
+}
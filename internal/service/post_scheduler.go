@@ -0,0 +1,360 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/clock"
+	"blog-api/pkg/settings"
+)
+
+var ErrInvalidSchedulerConfig = errors.New("invalid scheduler config")
+
+// config
+type SchedulerConfig struct {
+	IntervalSeconds int // reloads the timers, the scheduled job publishes whatever they missed
+	FetchLimit      int
+}
+
+func (c *SchedulerConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "SCHEDULER_INTERVAL_SECONDS", Default: 60, Field: &c.IntervalSeconds},
+		settings.Item[int]{Name: "SCHEDULER_FETCH_LIMIT", Default: 100, Field: &c.FetchLimit},
+	}
+}
+
+func (c *SchedulerConfig) Validate() error {
+	if c.IntervalSeconds <= 0 || c.FetchLimit <= 0 {
+		return fmt.Errorf("%w: SCHEDULER_INTERVAL_SECONDS and SCHEDULER_FETCH_LIMIT must be positive", ErrInvalidSchedulerConfig)
+	}
+	return nil
+}
+
+func (c *SchedulerConfig) Interval() time.Duration {
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+// Lookahead is how far ahead the timers are loaded, twice the interval so that no post slips between two loads
+func (c *SchedulerConfig) Lookahead() time.Duration {
+	return 2 * c.Interval()
+}
+
+// postScheduleChannel announces the schedule changes of posts to the schedulers of every replica
+const postScheduleChannel = "post_schedule"
+
+// postScheduleMessage carries the new publication window of a post, nil times are no longer scheduled
+type postScheduleMessage struct {
+	PostID      int        `json:"post_id"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+/*
+PostScheduler publishes posts at their publish_at and takes them down at their unpublish_at.
+
+The work is done by PublishDue, which runs as the JobPublishScheduledPosts job on its own schedule
+as a safety net. To get the posts out on time the scheduler keeps the posts due or expiring within
+the lookahead in a timer heap, loaded from the repository every interval and refreshed by the schedule
+messages PostService sends through the broker, and calls PublishDue when a timer fires. Only the
+scheduled job is recorded as a job run, the timers would leave a run behind for every publish time.
+
+Every replica runs a scheduler: their timers fire at the same times, and the claiming batches of
+PublishDue hand every post to one of them only. The published posts are announced by the event relay.
+*/
+type PostScheduler struct {
+	repo      repository.PostRepository
+	eventRepo repository.EventRepository
+	schedule  broker.Broker
+	config    *SchedulerConfig
+	clock     clock.Clock
+}
+
+func NewPostScheduler(
+	repo repository.PostRepository,
+	eventRepo repository.EventRepository,
+	schedule broker.Broker,
+	config *SchedulerConfig,
+) *PostScheduler {
+	return &PostScheduler{
+		repo:      repo,
+		eventRepo: eventRepo,
+		schedule:  schedule,
+		config:    config,
+		clock:     clock.Real(),
+	}
+}
+
+/*
+PublishDue is the JobPublishScheduledPosts job. It takes down the posts whose unpublish_at has come,
+their authors find them among the delayed posts, and publishes the due ones in claiming batches,
+so the schedulers of other replicas never publish a post twice. Every batch records its post.unpublished
+or post.published events in the same transaction, a crash never loses the announcement of a change.
+*/
+func (s *PostScheduler) PublishDue(ctx context.Context) error {
+	for {
+		now := s.clock.Now()
+		var expired []*model.Post
+		err := s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			expired, err = s.repo.ExpireDue(ctx, now, s.config.FetchLimit)
+			if err != nil {
+				return err
+			}
+			for _, post := range expired {
+				if err := recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostUnpublished, post); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to expire due posts: %w", err)
+		}
+		for _, post := range expired {
+			logger.Info("scheduler expired post id=%d", post.ID)
+		}
+
+		var posts []*model.Post
+		err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			posts, err = s.repo.PublishDue(ctx, now, s.config.FetchLimit)
+			if err != nil {
+				return err
+			}
+			for _, post := range posts {
+				if err := recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostPublished, post); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish due posts: %w", err)
+		}
+		for _, post := range posts {
+			logger.Info("scheduler published post id=%d", post.ID)
+		}
+
+		// full batches leave a backlog behind
+		if len(expired) < s.config.FetchLimit && len(posts) < s.config.FetchLimit {
+			return nil
+		}
+	}
+}
+
+// StartPostScheduler runs the timers until the handle is stopped, stopping waits for the publication in flight
+func StartPostScheduler(scheduler *PostScheduler) *Handle {
+	return startHandle("post scheduler", scheduler.run)
+}
+
+func (s *PostScheduler) run(ctx context.Context) {
+	logger.Info("post scheduler started, interval=%s", s.config.Interval())
+
+	messages := subscribeSchedule(ctx, s.schedule)
+	timers := newPostTimers()
+
+	// load puts the posts due or expiring soon on the timers, overdue ones fire at once
+	load := func() {
+		until := s.clock.Now().Add(s.config.Lookahead())
+		posts, err := s.repo.GetPosts(
+			ctx,
+			&repository.PostFilter{
+				Published: func(b bool) *bool { return &b }(false),
+				Held:      func(b bool) *bool { return &b }(false),
+				Expired:   func(b bool) *bool { return &b }(false),
+				DueBefore: &until,
+				Sort:      &repository.PostSort{Field: model.PostSortPublishAt},
+			},
+			s.config.FetchLimit, 0,
+		)
+		if err != nil {
+			logger.Error("scheduler failed to fetch upcoming posts: %v", err)
+			return
+		}
+		for _, post := range posts {
+			if post.PublishAt != nil {
+				timers.schedule(timerKey{postID: post.ID}, *post.PublishAt)
+			}
+		}
+
+		expiring, err := s.repo.GetPosts(
+			ctx,
+			&repository.PostFilter{
+				Expired:       func(b bool) *bool { return &b }(false),
+				ExpiresBefore: &until,
+				Sort:          &repository.PostSort{Field: model.PostSortUnpublishAt},
+			},
+			s.config.FetchLimit, 0,
+		)
+		if err != nil {
+			logger.Error("scheduler failed to fetch expiring posts: %v", err)
+			return
+		}
+		for _, post := range expiring {
+			timers.schedule(timerKey{postID: post.ID, expire: true}, *post.UnpublishAt)
+		}
+	}
+
+	load()
+
+	// armed once the first load is on the timers
+	ticker := s.clock.NewTicker(s.config.Interval())
+	defer ticker.Stop()
+	timer := s.clock.NewTimer(s.config.Interval())
+	defer timer.Stop()
+
+	resetTimer := func() {
+		next, ok := timers.next()
+		if !ok {
+			timer.Reset(s.config.Interval())
+			return
+		}
+		timer.Reset(next.Sub(s.clock.Now()))
+	}
+
+	resetTimer()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("post scheduler stopped")
+			return
+		case <-ticker.C():
+			if messages == nil {
+				messages = subscribeSchedule(ctx, s.schedule)
+			}
+			load()
+			resetTimer()
+		case msg, ok := <-messages:
+			if !ok {
+				logger.Warn("post schedule subscription closed, polling every %s until it is back", s.config.Interval())
+				messages = nil
+				continue
+			}
+			var change postScheduleMessage
+			if err := json.Unmarshal(msg.Payload, &change); err != nil {
+				logger.Warn("failed to decode post schedule message: %v", err)
+				continue
+			}
+			until := s.clock.Now().Add(s.config.Lookahead())
+			timers.update(timerKey{postID: change.PostID}, change.PublishAt, until)
+			timers.update(timerKey{postID: change.PostID, expire: true}, change.UnpublishAt, until)
+			resetTimer()
+		case <-timer.C():
+			// the replicas fire at the same time and claim disjoint batches,
+			// a publication in flight is not cancelled when the scheduler is being stopped
+			if _, ok := timers.popDue(s.clock.Now()); ok {
+				if err := s.PublishDue(context.WithoutCancel(ctx)); err != nil {
+					logger.Error("scheduler failed to publish due posts: %v", err)
+				}
+			}
+			resetTimer()
+		}
+	}
+}
+
+// subscribeSchedule returns nil when the broker is unavailable, the scheduler keeps polling meanwhile
+func subscribeSchedule(ctx context.Context, schedule broker.Broker) <-chan *broker.Message {
+	if schedule == nil {
+		return nil
+	}
+	messages, err := schedule.Subscribe(ctx, postScheduleChannel)
+	if err != nil {
+		logger.Error("scheduler failed to subscribe to post schedule changes: %v", err)
+		return nil
+	}
+	return messages
+}
+
+// postTimers is a min-heap of the upcoming publications and expiries, a rescheduled post leaves
+// a stale entry behind which is skipped when it pops
+type postTimers struct {
+	entries scheduleEntries
+	due     map[timerKey]time.Time
+}
+
+// timerKey tells the publication and the expiry timers of a post apart
+type timerKey struct {
+	postID int
+	expire bool
+}
+
+type scheduleEntry struct {
+	key timerKey
+	at  time.Time
+}
+
+func newPostTimers() *postTimers {
+	return &postTimers{due: map[timerKey]time.Time{}}
+}
+
+func (t *postTimers) schedule(key timerKey, at time.Time) {
+	if current, ok := t.due[key]; ok && current.Equal(at) {
+		return
+	}
+	t.due[key] = at
+	heap.Push(&t.entries, scheduleEntry{key: key, at: at})
+}
+
+func (t *postTimers) cancel(key timerKey) {
+	delete(t.due, key)
+}
+
+// update follows a schedule message, times after until are left to the next load
+func (t *postTimers) update(key timerKey, at *time.Time, until time.Time) {
+	if at != nil && at.Before(until) {
+		t.schedule(key, *at)
+	} else {
+		t.cancel(key)
+	}
+}
+
+// next is the earliest time a timer fires at
+func (t *postTimers) next() (time.Time, bool) {
+	for len(t.entries) > 0 {
+		entry := t.entries[0]
+		if at, ok := t.due[entry.key]; ok && at.Equal(entry.at) {
+			return entry.at, true
+		}
+		heap.Pop(&t.entries)
+	}
+	return time.Time{}, false
+}
+
+// popDue removes the timers fired by now and returns the latest time among them
+func (t *postTimers) popDue(now time.Time) (time.Time, bool) {
+	var fired time.Time
+	for len(t.entries) > 0 && !t.entries[0].at.After(now) {
+		entry := heap.Pop(&t.entries).(scheduleEntry)
+		if at, ok := t.due[entry.key]; ok && at.Equal(entry.at) {
+			delete(t.due, entry.key)
+			fired = entry.at
+		}
+	}
+	return fired, !fired.IsZero()
+}
+
+// scheduleEntries implements heap.Interface, earliest first
+type scheduleEntries []scheduleEntry
+
+func (e scheduleEntries) Len() int           { return len(e) }
+func (e scheduleEntries) Less(i, j int) bool { return e[i].at.Before(e[j].at) }
+func (e scheduleEntries) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+func (e *scheduleEntries) Push(x any) {
+	*e = append(*e, x.(scheduleEntry))
+}
+
+func (e *scheduleEntries) Pop() any {
+	old := *e
+	entry := old[len(old)-1]
+	*e = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/clock"
+	"blog-api/pkg/logging"
+)
+
+// newTestPostScheduler builds the scheduler of a replica, with no jobs the posts go out only when the timers fire
+func newTestPostScheduler(repo repository.PostRepository, eventRepo repository.EventRepository, schedule broker.Broker) *PostScheduler {
+	return NewPostScheduler(repo, eventRepo, schedule, &SchedulerConfig{
+		IntervalSeconds: 60,
+		FetchLimit:      100,
+	})
+}
+
+// startPostScheduler runs the scheduler of a replica, stop waits for it to return
+func startPostScheduler(repo repository.PostRepository, eventRepo repository.EventRepository, schedule broker.Broker) (stop func()) {
+	handle := StartPostScheduler(newTestPostScheduler(repo, eventRepo, schedule))
+	return func() {
+		handle.Stop(context.Background())
+	}
+}
+
+func TestPostScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	schedule := broker.NewMemoryBroker()
+	posts := NewPostService(postRepo, repository.NewInMemoryUserRepo(), repository.NewInMemoryEventRepo(), nil, nil, nil, schedule)
+
+	create := func(title string, publishAt time.Time) *model.Post {
+		t.Helper()
+		post, err := posts.Create(ctx, 1, &model.PostCreateRequest{Title: title, Content: "Content", PublishAt: &publishAt})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return post
+	}
+	// waitPublished reports how late the post went out
+	waitPublished := func(post *model.Post) time.Duration {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			stored, _ := postRepo.GetPost(ctx, post.ID, nil)
+			if stored.Published {
+				return time.Since(*stored.PublishAt)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected post id=%d to be published", post.ID)
+		return 0
+	}
+
+	soon := create("Soon", time.Now().Add(300*time.Millisecond))
+	later := create("Later", time.Now().Add(time.Hour))
+	postponed := create("Postponed", time.Now().Add(400*time.Millisecond))
+
+	stop := startPostScheduler(postRepo, repository.NewInMemoryEventRepo(), schedule)
+	defer stop()
+
+	// loaded on start, the scheduler subscribes before it loads
+	if late := waitPublished(soon); late > time.Second {
+		t.Fatalf("expected the post to be published on time, it was %s late", late)
+	}
+
+	// a post out of the lookahead comes in with its schedule message
+	if _, err := posts.Update(ctx, later.ID, 1, &model.PostUpdateRequest{PublishAt: ptr(time.Now().Add(200 * time.Millisecond))}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if late := waitPublished(later); late > time.Second {
+		t.Fatalf("expected the rescheduled post to be published on time, it was %s late", late)
+	}
+
+	// a published post is taken down at its unpublish_at
+	expiring, err := posts.Create(ctx, 1, &model.PostCreateRequest{Title: "Expiring", Content: "Content", UnpublishAt: ptr(time.Now().Add(200 * time.Millisecond))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for stored, _ := postRepo.GetPost(ctx, expiring.ID, nil); !stored.Expired; stored, _ = postRepo.GetPost(ctx, expiring.ID, nil) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the post to expire")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stored, _ := postRepo.GetPost(ctx, expiring.ID, nil); stored.Published || time.Since(*stored.UnpublishAt) > time.Second {
+		t.Fatalf("expected the post to be taken down on time")
+	}
+
+	// postponing a post cancels its timer
+	if _, err := posts.Update(ctx, postponed.ID, 1, &model.PostUpdateRequest{PublishAt: ptr(time.Now().Add(time.Hour))}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if stored, _ := postRepo.GetPost(ctx, postponed.ID, nil); stored.Published {
+		t.Fatalf("expected the postponed post to stay scheduled")
+	}
+}
+
+// countingPostRepo records the posts every scheduler publishes
+type countingPostRepo struct {
+	*repository.InMemoryPostRepo
+	mu        sync.Mutex
+	published map[int]int
+}
+
+func (r *countingPostRepo) PublishDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error) {
+	posts, err := r.InMemoryPostRepo.PublishDue(ctx, now, limit)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, post := range posts {
+		r.published[post.ID]++
+	}
+	return posts, err
+}
+
+func (r *countingPostRepo) count() (posts, publications int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range r.published {
+		publications += n
+	}
+	return len(r.published), publications
+}
+
+func TestPostSchedulerReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := &countingPostRepo{InMemoryPostRepo: repository.NewInMemoryPostRepo(), published: map[int]int{}}
+	eventRepo := repository.NewInMemoryEventRepo()
+	schedule := broker.NewMemoryBroker()
+	posts := NewPostService(postRepo, repository.NewInMemoryUserRepo(), eventRepo, nil, nil, nil, schedule)
+
+	const total = 60
+	due := time.Now().Add(300 * time.Millisecond)
+	for i := range total {
+		// a few share a publication time, the rest are spread over the next half a second
+		publishAt := due.Add(time.Duration(i/3) * 25 * time.Millisecond)
+		if _, err := posts.Create(ctx, 1, &model.PostCreateRequest{Title: "Post", Content: "Content", PublishAt: &publishAt}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// every replica runs a scheduler against the same database
+	for range 4 {
+		stop := startPostScheduler(postRepo, eventRepo, schedule)
+		defer stop()
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if published, _ := postRepo.count(); published == total {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	published, publications := postRepo.count()
+	if published != total || publications != total {
+		t.Fatalf("expected %d posts published once, got %d posts published %d times", total, published, publications)
+	}
+	remaining, _ := postRepo.GetPostsCount(ctx, &repository.PostFilter{Published: ptr(false)})
+	if remaining != 0 {
+		t.Fatalf("expected no scheduled posts left, got %d", remaining)
+	}
+	announced := 0
+	for _, event := range eventRepo.All() {
+		if event.Type == model.EventPostPublished {
+			announced++
+		}
+	}
+	if announced != total {
+		t.Fatalf("expected a post.published event per post, got %d", announced)
+	}
+}
+
+func TestPostTimers(t *testing.T) {
+	now := time.Now()
+	timers := newPostTimers()
+
+	timers.schedule(timerKey{postID: 1}, now.Add(3*time.Second))
+	timers.schedule(timerKey{postID: 2}, now.Add(time.Second))
+	timers.schedule(timerKey{postID: 3}, now.Add(2*time.Second))
+	timers.schedule(timerKey{postID: 2}, now.Add(4*time.Second)) // rescheduled
+	timers.cancel(timerKey{postID: 3})
+
+	if next, ok := timers.next(); !ok || !next.Equal(now.Add(3*time.Second)) {
+		t.Fatalf("expected the timer of post 1 to fire next, got %s", next)
+	}
+	if _, fired := timers.popDue(now.Add(2 * time.Second)); fired {
+		t.Fatalf("expected stale and cancelled timers not to fire")
+	}
+	if at, fired := timers.popDue(now.Add(5 * time.Second)); !fired || !at.Equal(now.Add(4*time.Second)) {
+		t.Fatalf("expected timers to fire at the latest due time, got %s", at)
+	}
+	if _, ok := timers.next(); ok {
+		t.Fatalf("expected no timers left")
+	}
+}
+
+func TestPostSchedulerFakeClock(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	schedule := broker.NewMemoryBroker()
+	posts := NewPostService(postRepo, repository.NewInMemoryUserRepo(), repository.NewInMemoryEventRepo(), nil, nil, nil, schedule)
+
+	fake := clock.NewFake(time.Now())
+	start := fake.Now()
+	soon, err := posts.Create(ctx, 1, &model.PostCreateRequest{Title: "Soon", Content: "Content", PublishAt: ptr(start.Add(30 * time.Second))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// out of the two minutes lookahead, picked up by a reload
+	later, err := posts.Create(ctx, 1, &model.PostCreateRequest{Title: "Later", Content: "Content", PublishAt: ptr(start.Add(5 * time.Minute))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	scheduler := newTestPostScheduler(postRepo, repository.NewInMemoryEventRepo(), schedule)
+	scheduler.clock = fake
+	handle := StartPostScheduler(scheduler)
+	defer handle.Stop(ctx)
+
+	published := func(post *model.Post) bool {
+		stored, _ := postRepo.GetPost(ctx, post.ID, nil)
+		return stored.Published
+	}
+
+	// the ticker and the timer are armed once the posts are loaded
+	fake.BlockUntil(2)
+	fake.Advance(29 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if published(soon) {
+		t.Fatalf("expected the post to wait for its publish_at")
+	}
+
+	fake.Advance(time.Second)
+	deadline := time.Now().Add(2 * time.Second)
+	for !published(soon) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the post to be published when its timer fires")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the clock moves in steps of ten seconds, a reload puts the later post on the timers on the way
+	for !published(later) {
+		if fake.Now().Sub(*later.PublishAt) > time.Minute {
+			t.Fatalf("expected the later post to be published on time, the clock is at %s", fake.Now().Sub(start))
+		}
+		fake.Advance(10 * time.Second)
+		time.Sleep(20 * time.Millisecond)
+	}
+	if fake.Now().Before(*later.PublishAt) {
+		t.Fatalf("expected the later post to be published at its publish_at, the clock is at %s", fake.Now().Sub(start))
+	}
+}
+
+// blockingPostRepo holds the publication of the due posts until released and fails it on a done context,
+// as a database would
+type blockingPostRepo struct {
+	*repository.InMemoryPostRepo
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingPostRepo) PublishDue(ctx context.Context, now time.Time, limit int) ([]*model.Post, error) {
+	select {
+	case r.entered <- struct{}{}:
+	default:
+	}
+	<-r.release
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.InMemoryPostRepo.PublishDue(ctx, now, limit)
+}
+
+func TestPostSchedulerStop(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := &blockingPostRepo{
+		InMemoryPostRepo: repository.NewInMemoryPostRepo(),
+		entered:          make(chan struct{}, 1),
+		release:          make(chan struct{}),
+	}
+	eventRepo := repository.NewInMemoryEventRepo()
+	posts := NewPostService(postRepo, repository.NewInMemoryUserRepo(), eventRepo, nil, nil, nil, nil)
+
+	fake := clock.NewFake(time.Now())
+	post, err := posts.Create(ctx, 1, &model.PostCreateRequest{Title: "Soon", Content: "Content", PublishAt: ptr(fake.Now().Add(30 * time.Second))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	scheduler := newTestPostScheduler(postRepo, eventRepo, nil)
+	scheduler.clock = fake
+	handle := StartPostScheduler(scheduler)
+
+	fake.BlockUntil(2)
+	fake.Advance(30 * time.Second)
+	select {
+	case <-postRepo.entered:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the timer to start the publication")
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		stopped <- handle.Stop(stopCtx)
+	}()
+	select {
+	case err := <-stopped:
+		t.Fatalf("expected the stop to wait for the publication in flight, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(postRepo.release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("expected the scheduler to stop, got %v", err)
+	}
+	if stored, _ := postRepo.GetPost(ctx, post.ID, nil); !stored.Published {
+		t.Fatalf("expected the post in flight to be published")
+	}
+	published := 0
+	for _, event := range eventRepo.All() {
+		if event.Type == model.EventPostPublished && event.AggregateID == post.ID {
+			published++
+		}
+	}
+	if published != 1 {
+		t.Fatalf("expected a post.published event for the post, got %d", published)
+	}
+}
+
+func TestPostSchedulerAnnouncements(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	eventRepo := repository.NewInMemoryEventRepo()
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+	notifications := NewNotificationService(notificationRepo, repository.NewInMemoryNotificationPreferenceRepo(), postRepo, repository.NewInMemoryCommentRepo(), nil)
+	posts := NewPostService(postRepo, userRepo, eventRepo, nil, notifications, nil, nil)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	bob := &model.User{Username: "bob", Email: "bob@example.com"}
+	for _, u := range []*model.User{author, bob} {
+		userRepo.Create(ctx, u)
+	}
+
+	fake := clock.NewFake(time.Now())
+	scheduled, err := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Later", Content: "@bob", PublishAt: ptr(fake.Now().Add(time.Minute))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	now, err := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Now", Content: "@bob", UnpublishAt: ptr(fake.Now().Add(time.Minute))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	scheduler := newTestPostScheduler(postRepo, eventRepo, nil)
+	scheduler.clock = fake
+	fake.Advance(time.Minute)
+	if err := scheduler.PublishDue(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// nothing is announced until the relay hands the events over
+	if n := notificationRepo.All(); len(n) != 0 {
+		t.Fatalf("expected no notifications before the relay, got %+v", n)
+	}
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("post_publications", posts.AnnouncePublished, model.EventPostPublished)
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// both posts notify their mentions, only the scheduled one tells its author it went live;
+	// the events of the scheduled post come first, it was created first
+	var kinds []string
+	for _, n := range notificationRepo.All() {
+		kinds = append(kinds, fmt.Sprintf("%d:%s:%d", n.UserID, n.Kind, n.PostID))
+	}
+	expected := []string{
+		fmt.Sprintf("%d:%s:%d", author.ID, model.NotificationPostPublished, scheduled.ID),
+		fmt.Sprintf("%d:%s:%d", bob.ID, model.NotificationMention, scheduled.ID),
+		fmt.Sprintf("%d:%s:%d", bob.ID, model.NotificationMention, now.ID),
+	}
+	if !slices.Equal(kinds, expected) {
+		t.Fatalf("expected notifications %v, got %v", expected, kinds)
+	}
+
+	// the expiry is recorded along with the publication
+	var types []string
+	for _, event := range eventRepo.All() {
+		if event.AggregateID == now.ID {
+			types = append(types, event.Type)
+		}
+	}
+	if want := []string{model.EventPostCreated, model.EventPostPublished, model.EventPostUnpublished}; !slices.Equal(types, want) {
+		t.Fatalf("expected events %v, got %v", want, types)
+	}
+}
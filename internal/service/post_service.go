@@ -2,131 +2,130 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"sync"
+	"fmt"
 	"time"
 
 	"blog-api/internal/model"
 	"blog-api/internal/repository"
+	"blog-api/pkg/broker"
+	"blog-api/pkg/contentfilter"
+	"blog-api/pkg/markup"
 )
 
-const (
-	SchedulerInterval        = 1 * time.Minute
-	SchedulerWorkers         = 5
-	SchedulerBufferSize      = 1000
-	SchedulerMaxJobQueueSize = 500
-	SchedulerFetchLimit      = 100
-)
-
-// StartPostScheduler launches a background scheduler to publish posts at their scheduled time
-func StartPostScheduler(ctx context.Context, repo repository.PostRepository) {
-	logger.Info("post scheduler started, interval=%s, workers=%d", SchedulerInterval, SchedulerWorkers)
-
-	ticker := time.NewTicker(SchedulerInterval)
-	defer ticker.Stop()
-
-	jobs := make(chan *model.Post, SchedulerBufferSize)
-	wg := &sync.WaitGroup{}
-
-	// worker pool
-	for i := range SchedulerWorkers {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for {
-				select {
-				case post, ok := <-jobs:
-					if !ok {
-						return
-					}
-					post.Published = true
-					if err := repo.Update(ctx, post); err != nil {
-						logger.Error("worker %d failed to publish post id=%d: %v", workerID, post.ID, err)
-					} else {
-						logger.Info("worker %d published post id=%d", workerID, post.ID)
-					}
-				case <-ctx.Done():
-					return
-				}
-			}
-		}(i + 1)
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			close(jobs)
-			wg.Wait()
-			logger.Info("post scheduler stopped")
-			return
-		case <-ticker.C:
-			now := time.Now()
-			posts, err := repo.GetPosts(
-				ctx,
-				&repository.PostFilter{
-					Published: func(b bool) *bool { return &b }(false),
-					DueBefore: &now,
-				},
-				SchedulerFetchLimit, 0,
-			)
-			if err != nil {
-				logger.Error("scheduler failed to fetch due posts: %v", err)
-				continue
-			}
-
-		PushJobs:
-			for _, post := range posts {
-				select {
-				case jobs <- post:
-					if len(jobs) > SchedulerMaxJobQueueSize {
-						logger.Warn("post scheduler backlog is large: %d jobs pending", len(jobs))
-					}
-				case <-ctx.Done():
-					break PushJobs
-				}
-			}
-		}
-	}
-}
-
 var (
-	ErrPostNotFound = errors.New("post not found")
+	ErrPostNotFound       = errors.New("post not found")
+	ErrInvalidUnpublishAt = errors.New("unpublish_at must be in the future and after publish_at")
 )
 
 type PostService struct {
-	postRepo repository.PostRepository
-	userRepo repository.UserRepository
+	postRepo      repository.PostRepository
+	userRepo      repository.UserRepository
+	eventRepo     repository.EventRepository
+	renderer      *markup.Renderer
+	filter        *contentfilter.Pipeline
+	notifications *NotificationService
+	webhooks      *WebhookService
+	schedule      broker.Broker
 }
 
 func NewPostService(
 	postRepo repository.PostRepository,
 	userRepo repository.UserRepository,
+	eventRepo repository.EventRepository,
+	filter *contentfilter.Pipeline,
+	notifications *NotificationService,
+	webhooks *WebhookService,
+	schedule broker.Broker,
 ) *PostService {
 	return &PostService{
-		postRepo: postRepo,
-		userRepo: userRepo,
+		postRepo:      postRepo,
+		userRepo:      userRepo,
+		eventRepo:     eventRepo,
+		renderer:      markup.NewPostRenderer(),
+		filter:        filter,
+		notifications: notifications,
+		webhooks:      webhooks,
+		schedule:      schedule,
 	}
 }
 
 func (s *PostService) Create(ctx context.Context, userID int, req *model.PostCreateRequest) (*model.Post, error) {
+	format := req.ContentFormat
+	if format == "" {
+		format = string(markup.FormatPlain)
+	}
+
+	contentHTML, err := s.renderer.Render(markup.Format(format), req.Content)
+	if err != nil {
+		logger.Info("failed to render post content for user_id=%d: %v", userID, err)
+		return nil, ErrUnsupportedContentFormat
+	}
+
 	post := &model.Post{
-		Title:     req.Title,
-		Content:   req.Content,
-		AuthorID:  userID,
-		PublishAt: req.PublishAt,
+		Title:         req.Title,
+		Content:       req.Content,
+		ContentFormat: format,
+		ContentHTML:   contentHTML,
+		AuthorID:      userID,
+		PublishAt:     req.PublishAt,
+		UnpublishAt:   req.UnpublishAt,
+
+		CommentsPremoderation: req.CommentsPremoderation,
+		CommentsPolicy:        req.CommentsPolicy,
+		CommentsMinAccountAge: req.CommentsMinAccountAge,
+		CommentsCloseAfter:    req.CommentsCloseAfter,
+	}
+	if post.CommentsPolicy == "" {
+		post.CommentsPolicy = model.CommentsPolicyOpen
 	}
 
-	if post.PublishAt == nil || post.PublishAt.IsZero() || !post.PublishAt.After(time.Now()) {
-		post.Published = true
-	} else {
+	now := time.Now()
+	if err := checkUnpublishAt(post, true, now); err != nil {
+		return nil, err
+	}
+	setPublicationState(post, now)
+
+	verdict, err := checkContent(ctx, s.filter, &contentfilter.Item{
+		Kind:     contentfilter.KindPost,
+		AuthorID: userID,
+		Text:     postText(post),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verdict == contentfilter.Hold {
+		post.Held = true
 		post.Published = false
 	}
 
-	if err := s.postRepo.Create(ctx, post); err != nil {
+	post.Mentions, err = resolveMentions(ctx, s.userRepo, post.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.postRepo.Create(ctx, post); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostCreated, post); err != nil {
+			return err
+		}
+		if post.Published {
+			return recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostPublished, post)
+		}
+		return nil
+	})
+	if err != nil {
 		logger.Error("failed to create post for user_id=%d: %v", userID, err)
 		return nil, ErrDatabase
 	}
 
+	if post.PublishAt != nil || post.UnpublishAt != nil {
+		s.announceSchedule(ctx, post)
+	}
+
 	return post, nil
 }
 
@@ -149,25 +148,21 @@ func (s *PostService) GetByID(ctx context.Context, id int) (*model.Post, error)
 	return post, nil
 }
 
-func (s *PostService) GetAll(
+// List returns published posts narrowed and ordered by the listing query
+func (s *PostService) List(
 	ctx context.Context,
+	query *model.PostListParams,
 	pagination *model.PaginationParams,
 ) ([]*model.Post, int, error) {
 
-	published := true
-	filter := &repository.PostFilter{
-		Published: &published,
-	}
+	filter := postListFilter(query)
 
-	posts, err := s.postRepo.GetPosts(
-		ctx,
-		filter,
-		*pagination.Limit,
-		*pagination.Offset,
-	)
+	posts, err := s.postRepo.GetPosts(ctx, filter, *pagination.Limit, *pagination.Offset)
 	if err != nil {
 		logger.Error(
-			"failed to fetch posts with limit=%d offset=%d: %v",
+			"failed to list posts sorted by %s %s with limit=%d offset=%d: %v",
+			query.Sort,
+			query.Order,
 			*pagination.Limit,
 			*pagination.Offset,
 			err,
@@ -175,15 +170,100 @@ func (s *PostService) GetAll(
 		return nil, 0, ErrDatabase
 	}
 
+	if !pagination.CountTotal() {
+		return posts, 0, nil
+	}
+
 	total, err := s.postRepo.GetPostsCount(ctx, filter)
 	if err != nil {
-		logger.Error("failed to count total posts: %v", err)
+		logger.Error("failed to count listed posts: %v", err)
 		return nil, 0, ErrDatabase
 	}
 
 	return posts, total, nil
 }
 
+// GetAllByCursor returns published posts matching the listing query using keyset pagination
+func (s *PostService) GetAllByCursor(
+	ctx context.Context,
+	query *model.PostListParams,
+	pagination *model.PaginationParams,
+) (*model.CursorPage[*model.Post], error) {
+
+	if !query.IsDefaultSort() {
+		logger.Info("cursor pagination requested with sort=%s order=%s", query.Sort, query.Order)
+		return nil, ErrCursorSortUnsupported
+	}
+
+	cursor, err := decodeCursor(pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := postListFilter(query)
+
+	posts, err := s.postRepo.GetPostsByCursor(ctx, filter, cursor, *pagination.Limit+1)
+	if err != nil {
+		logger.Error("failed to fetch posts by cursor with limit=%d: %v", *pagination.Limit, err)
+		return nil, ErrDatabase
+	}
+
+	page := buildCursorPage(posts, cursor, *pagination.Limit, postCursorKey)
+
+	if pagination.CountTotal() {
+		total, err := s.postRepo.GetPostsCount(ctx, filter)
+		if err != nil {
+			logger.Error("failed to count total posts: %v", err)
+			return nil, ErrDatabase
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// ExpandPosts embeds the requested related data into the posts using batched queries
+func (s *PostService) ExpandPosts(ctx context.Context, posts []*model.Post, expand *model.ExpandParams) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	if expand.Has(model.ExpandAuthor) {
+		if err := s.postRepo.LoadAuthors(ctx, posts); err != nil {
+			logger.Error("failed to expand authors of %d posts: %v", len(posts), err)
+			return ErrDatabase
+		}
+	}
+
+	if expand.Has(model.ExpandCommentCount) {
+		if err := s.postRepo.LoadCommentCounts(ctx, posts); err != nil {
+			logger.Error("failed to expand comment counts of %d posts: %v", len(posts), err)
+			return ErrDatabase
+		}
+	}
+
+	return nil
+}
+
+func postListFilter(query *model.PostListParams) *repository.PostFilter {
+	published := true
+	return &repository.PostFilter{
+		Published:   &published,
+		AuthorIDs:   query.AuthorIDs,
+		CreatedFrom: query.CreatedFrom,
+		CreatedTo:   query.CreatedTo,
+		HasComments: query.HasComments,
+		Sort: &repository.PostSort{
+			Field: query.Sort,
+			Desc:  query.Order == model.SortDesc,
+		},
+	}
+}
+
+func postCursorKey(post *model.Post) (time.Time, int) {
+	return post.CreatedAt, post.ID
+}
+
 func (s *PostService) Update(
 	ctx context.Context,
 	id int,
@@ -205,33 +285,120 @@ func (s *PostService) Update(
 		return nil, err
 	}
 
+	wasPublished := post.Published
+	previousMentions := post.Mentions
+
 	updated := false
+	textChanged := false
 
 	if req.Title != nil && *req.Title != post.Title {
 		post.Title = *req.Title
 		updated = true
+		textChanged = true
 	}
 
+	contentChanged := false
+
 	if req.Content != nil && *req.Content != post.Content {
 		post.Content = *req.Content
+		contentChanged = true
+	}
+
+	if req.ContentFormat != nil && *req.ContentFormat != post.ContentFormat {
+		post.ContentFormat = *req.ContentFormat
+		contentChanged = true
+	}
+
+	if contentChanged {
+		contentHTML, err := s.renderer.Render(markup.Format(post.ContentFormat), post.Content)
+		if err != nil {
+			logger.Info("failed to render content of post id=%d: %v", id, err)
+			return nil, ErrUnsupportedContentFormat
+		}
+		post.ContentHTML = contentHTML
+		post.Mentions, err = resolveMentions(ctx, s.userRepo, post.Content)
+		if err != nil {
+			return nil, err
+		}
 		updated = true
+		textChanged = true
+	}
+
+	// an edit can put the post on hold, only a moderator releases it
+	if textChanged {
+		verdict, err := checkContent(ctx, s.filter, &contentfilter.Item{
+			Kind:     contentfilter.KindPost,
+			ID:       post.ID,
+			AuthorID: userID,
+			Text:     postText(post),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if verdict == contentfilter.Hold {
+			post.Held = true
+		}
 	}
 
 	if req.PublishAt != nil {
 		post.PublishAt = req.PublishAt
-		if post.PublishAt != nil && post.PublishAt.After(time.Now()) {
-			post.Published = false
-		} else {
-			post.Published = true
+		updated = true
+	}
+
+	if req.UnpublishAt != nil {
+		post.UnpublishAt = req.UnpublishAt
+		updated = true
+	}
+
+	if req.PublishAt != nil || req.UnpublishAt != nil {
+		now := time.Now()
+		if err := checkUnpublishAt(post, req.UnpublishAt != nil, now); err != nil {
+			return nil, err
 		}
+		setPublicationState(post, now)
+	}
+
+	if req.CommentsPremoderation != nil && *req.CommentsPremoderation != post.CommentsPremoderation {
+		post.CommentsPremoderation = *req.CommentsPremoderation
+		updated = true
+	}
+
+	if req.CommentsPolicy != nil && *req.CommentsPolicy != post.CommentsPolicy {
+		post.CommentsPolicy = *req.CommentsPolicy
 		updated = true
 	}
 
+	if req.CommentsMinAccountAge != nil && *req.CommentsMinAccountAge != post.CommentsMinAccountAge {
+		post.CommentsMinAccountAge = *req.CommentsMinAccountAge
+		updated = true
+	}
+
+	if req.CommentsCloseAfter != nil {
+		post.CommentsCloseAfter = req.CommentsCloseAfter
+		updated = true
+	}
+
+	if post.Held {
+		post.Published = false
+	}
+
 	if !updated {
 		return post, nil
 	}
 
-	if err := s.postRepo.Update(ctx, post); err != nil {
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.postRepo.Update(ctx, post); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostUpdated, post); err != nil {
+			return err
+		}
+		if post.Published && !wasPublished {
+			return recordEvent(ctx, s.eventRepo, model.AggregatePost, post.ID, model.EventPostPublished, post)
+		}
+		return nil
+	})
+	if err != nil {
 		if errors.Is(err, repository.ErrPostNotFound) {
 			return nil, ErrPostNotFound
 		}
@@ -239,6 +406,15 @@ func (s *PostService) Update(
 		return nil, ErrDatabase
 	}
 
+	if req.PublishAt != nil || req.UnpublishAt != nil {
+		s.announceSchedule(ctx, post)
+	}
+
+	// a newly published post is announced by the relay
+	if post.Published && wasPublished {
+		s.notifications.NotifyMentions(ctx, userID, post.ID, nil, previousMentions, post.Mentions)
+	}
+
 	return post, nil
 }
 
@@ -257,7 +433,13 @@ func (s *PostService) Delete(ctx context.Context, id int, userID int) error {
 		return err
 	}
 
-	if err := s.postRepo.Delete(ctx, id); err != nil {
+	err = s.eventRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.postRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.eventRepo, model.AggregatePost, id, model.EventPostDeleted, post)
+	})
+	if err != nil {
 		if errors.Is(err, repository.ErrPostNotFound) {
 			return ErrPostNotFound
 		}
@@ -284,6 +466,10 @@ func (s *PostService) GetByAuthor(
 		return nil, 0, ErrDatabase
 	}
 
+	if !pagination.CountTotal() {
+		return posts, 0, nil
+	}
+
 	total, err := s.postRepo.GetPostsCount(ctx, filter)
 	if err != nil {
 		logger.Error("failed to count posts for author_id=%d: %v", authorID, err)
@@ -293,6 +479,96 @@ func (s *PostService) GetByAuthor(
 	return posts, total, nil
 }
 
+// setPublicationState derives the visibility of the post from its publication window, held posts stay hidden
+func setPublicationState(post *model.Post, now time.Time) {
+	post.Expired = post.UnpublishAt != nil && !post.UnpublishAt.After(now)
+	post.Published = !post.Held && !post.Expired && (post.PublishAt == nil || !post.PublishAt.After(now))
+}
+
+// checkUnpublishAt keeps the post from expiring before it is published, a newly set unpublish_at must be in the future
+func checkUnpublishAt(post *model.Post, changed bool, now time.Time) error {
+	if post.UnpublishAt == nil {
+		return nil
+	}
+	if (changed && !post.UnpublishAt.After(now)) || (post.PublishAt != nil && !post.UnpublishAt.After(*post.PublishAt)) {
+		logger.Info("invalid unpublish_at %s of post id=%d", post.UnpublishAt.Format(time.RFC3339), post.ID)
+		return ErrInvalidUnpublishAt
+	}
+	return nil
+}
+
+// AnnounceCreated is the relay handler of post.created, the webhooks receive the new post
+func (s *PostService) AnnounceCreated(ctx context.Context, event *model.Event) error {
+	var post model.Post
+	if err := json.Unmarshal(event.Payload, &post); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+	s.webhooks.Emit(ctx, model.WebhookPostCreated, post.AuthorID, &post)
+	return nil
+}
+
+/*
+AnnouncePublished is the relay handler of post.published, whoever published the post: the mentioned users
+are notified and the webhooks receive the post, mentions in drafts stay silent until the post goes public.
+The author of a post that went out at its publish_at is told it went live.
+*/
+func (s *PostService) AnnouncePublished(ctx context.Context, event *model.Event) error {
+	var post model.Post
+	if err := json.Unmarshal(event.Payload, &post); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+	if post.PublishAt != nil && post.PublishAt.After(post.CreatedAt) {
+		s.notifications.NotifyPublished(ctx, &post)
+	} else {
+		s.notifications.NotifyMentions(ctx, post.AuthorID, post.ID, nil, nil, post.Mentions)
+	}
+	s.webhooks.Emit(ctx, model.WebhookPostPublished, post.AuthorID, &post)
+	return nil
+}
+
+// AnnounceUnpublished is the relay handler of post.unpublished, the webhooks receive the post taken down
+func (s *PostService) AnnounceUnpublished(ctx context.Context, event *model.Event) error {
+	var post model.Post
+	if err := json.Unmarshal(event.Payload, &post); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+	s.webhooks.Emit(ctx, model.WebhookPostUnpublished, post.AuthorID, &post)
+	return nil
+}
+
+// AnnounceDeleted is the relay handler of post.deleted, whoever deleted the post: the webhooks receive it
+func (s *PostService) AnnounceDeleted(ctx context.Context, event *model.Event) error {
+	var post model.Post
+	if err := json.Unmarshal(event.Payload, &post); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+	s.webhooks.Emit(ctx, model.WebhookPostDeleted, post.AuthorID, &post)
+	return nil
+}
+
+// announceSchedule wakes the schedulers up for the new publication time of the post,
+// a lost message only delays the post until the scheduler polls
+func (s *PostService) announceSchedule(ctx context.Context, post *model.Post) {
+	if s.schedule == nil {
+		return
+	}
+	change := &postScheduleMessage{PostID: post.ID}
+	if !post.Published && !post.Held && !post.Expired {
+		change.PublishAt = post.PublishAt
+	}
+	if !post.Expired {
+		change.UnpublishAt = post.UnpublishAt
+	}
+	payload, err := json.Marshal(change)
+	if err != nil {
+		logger.Error("failed to encode schedule of post id=%d: %v", post.ID, err)
+		return
+	}
+	if err := s.schedule.Publish(ctx, postScheduleChannel, payload); err != nil {
+		logger.Warn("failed to announce schedule of post id=%d: %v", post.ID, err)
+	}
+}
+
 func (s *PostService) checkPostOwner(post *model.Post, userID int) error {
 	if post.AuthorID != userID {
 		logger.Info("user_id=%d is not the author of post_id=%d", userID, post.ID)
@@ -313,6 +589,10 @@ func (s *PostService) GetDelayedPosts(ctx context.Context, userID int, paginatio
 		return nil, 0, ErrDatabase
 	}
 
+	if !pagination.CountTotal() {
+		return posts, 0, nil
+	}
+
 	total, err := s.postRepo.GetPostsCount(ctx, filter)
 	if err != nil {
 		logger.Error("failed to count delayed posts for user_id=%d: %v", userID, err)
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,7 +21,7 @@ func setupPostServiceForTest() *PostService {
 
 	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
 
-	return NewPostService(postRepo, userRepo)
+	return NewPostService(postRepo, userRepo, repository.NewInMemoryEventRepo(), nil, nil, nil, nil)
 }
 func TestPostServiceCreate(t *testing.T) {
 	ctx := context.Background()
@@ -142,31 +144,6 @@ func TestPostServiceDelete(t *testing.T) {
 	}
 }
 
-func TestPostServiceGetAll(t *testing.T) {
-	ctx := context.Background()
-	svc := setupPostServiceForTest()
-
-	userID := 1
-	for i := range 5 {
-		svc.Create(ctx, userID, &model.PostCreateRequest{
-			Title:   "Post " + string(rune(i)),
-			Content: "Content",
-		})
-	}
-
-	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
-	posts, total, err := svc.GetAll(ctx, pagination)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
-	if total != 5 {
-		t.Fatalf("expected total 5, got %d", total)
-	}
-	if len(posts) != 5 {
-		t.Fatalf("expected 5 posts returned, got %d", len(posts))
-	}
-}
-
 func TestPostServiceGetByAuthor(t *testing.T) {
 	ctx := context.Background()
 	svc := setupPostServiceForTest()
@@ -221,3 +198,342 @@ func TestPostServiceGetDelayedPosts(t *testing.T) {
 		t.Fatalf("mismatch delayed post ID")
 	}
 }
+
+func TestPostServiceUnpublishAt(t *testing.T) {
+	ctx := context.Background()
+	postRepo := repository.NewInMemoryPostRepo()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+	svc := NewPostService(postRepo, repository.NewInMemoryUserRepo(), repository.NewInMemoryEventRepo(), nil, nil, nil, nil)
+
+	userID := 1
+	now := time.Now()
+
+	invalid := []struct {
+		name string
+		req  *model.PostCreateRequest
+	}{
+		{"in the past", &model.PostCreateRequest{Title: "Post", Content: "Content", UnpublishAt: ptr(now.Add(-time.Minute))}},
+		{"before publish_at", &model.PostCreateRequest{Title: "Post", Content: "Content", PublishAt: ptr(now.Add(2 * time.Hour)), UnpublishAt: ptr(now.Add(time.Hour))}},
+	}
+	for _, tt := range invalid {
+		if _, err := svc.Create(ctx, userID, tt.req); !errors.Is(err, ErrInvalidUnpublishAt) {
+			t.Fatalf("%s: expected ErrInvalidUnpublishAt, got %v", tt.name, err)
+		}
+	}
+
+	post, err := svc.Create(ctx, userID, &model.PostCreateRequest{Title: "Sale", Content: "Content", UnpublishAt: ptr(now.Add(time.Hour))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !post.Published || post.Expired {
+		t.Fatalf("expected the post to be published until it expires")
+	}
+	if _, err := svc.Update(ctx, post.ID, userID, &model.PostUpdateRequest{PublishAt: ptr(now.Add(2 * time.Hour))}); !errors.Is(err, ErrInvalidUnpublishAt) {
+		t.Fatalf("expected ErrInvalidUnpublishAt, got %v", err)
+	}
+
+	// the scheduler takes the post down, its author still sees it
+	expired, _ := postRepo.ExpireDue(ctx, now.Add(2*time.Hour), 10)
+	if len(expired) != 1 || !expired[0].Expired || expired[0].Published {
+		t.Fatalf("expected the post to expire, got %+v", expired)
+	}
+	if _, err := svc.GetByID(ctx, post.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+	if delayed, err := svc.GetDelayedPostByID(ctx, userID, post.ID); err != nil || !delayed.Expired {
+		t.Fatalf("expected the expired post among the delayed posts, got %v", err)
+	}
+
+	// a later unpublish_at brings it back
+	post, err = svc.Update(ctx, post.ID, userID, &model.PostUpdateRequest{UnpublishAt: ptr(now.Add(3 * time.Hour))})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !post.Published || post.Expired {
+		t.Fatalf("expected the post to be published again")
+	}
+}
+
+func TestPostServiceContentFormat(t *testing.T) {
+	ctx := context.Background()
+	svc := setupPostServiceForTest()
+
+	userID := 1
+
+	// plain is escaped
+	post, err := svc.Create(ctx, userID, &model.PostCreateRequest{
+		Title:   "Plain",
+		Content: "<b>not bold</b>",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if post.ContentFormat != "plain" || post.ContentHTML != "<p>&lt;b&gt;not bold&lt;/b&gt;</p>" {
+		t.Fatalf("unexpected plain rendering: %q (%s)", post.ContentHTML, post.ContentFormat)
+	}
+
+	// markdown is rendered and sanitized
+	post, err = svc.Create(ctx, userID, &model.PostCreateRequest{
+		Title:         "Markdown",
+		Content:       "# Title\n\n**bold** <script>alert(1)</script> [x](javascript:alert(1))",
+		ContentFormat: "markdown",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, want := range []string{"<h1>Title</h1>", "<strong>bold</strong>"} {
+		if !strings.Contains(post.ContentHTML, want) {
+			t.Fatalf("expected %q in rendered markdown, got %q", want, post.ContentHTML)
+		}
+	}
+	for _, banned := range []string{"<script", "javascript:"} {
+		if strings.Contains(post.ContentHTML, banned) {
+			t.Fatalf("unexpected %q in rendered markdown: %q", banned, post.ContentHTML)
+		}
+	}
+
+	// html is sanitized and re-rendered on format change
+	format := "html"
+	updated, err := svc.Update(ctx, post.ID, userID, &model.PostUpdateRequest{
+		Content:       ptr(`<p onclick="x()">hi</p><img src="https://example.com/a.png" onerror="x()"><iframe src="https://evil"></iframe>`),
+		ContentFormat: &format,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.ContentHTML != `<p>hi</p><img src="https://example.com/a.png">` {
+		t.Fatalf("unexpected sanitized html: %q", updated.ContentHTML)
+	}
+}
+
+func TestPostServiceGetAllByCursor(t *testing.T) {
+	ctx := context.Background()
+	svc := setupPostServiceForTest()
+
+	userID := 1
+	var created []*model.Post
+	for i := range 5 {
+		post, _ := svc.Create(ctx, userID, &model.PostCreateRequest{
+			Title:   "Post " + string(rune('A'+i)),
+			Content: "Content",
+		})
+		created = append(created, post)
+	}
+
+	newest := &model.PostListParams{Sort: model.PostSortCreatedAt, Order: model.SortDesc}
+
+	// first page
+	pagination := &model.PaginationParams{Limit: ptr(2), Cursor: ptr(""), WithTotal: ptr(false)}
+	page, err := svc.GetAllByCursor(ctx, newest, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != created[4].ID || page.Items[1].ID != created[3].ID {
+		t.Fatalf("unexpected first page: %+v", page.Items)
+	}
+	if page.NextCursor == "" || page.PrevCursor != "" || page.Total != nil {
+		t.Fatalf("unexpected first page cursors: next=%q prev=%q", page.NextCursor, page.PrevCursor)
+	}
+
+	// new posts arriving while paging do not shift the next page
+	svc.Create(ctx, userID, &model.PostCreateRequest{Title: "Late", Content: "Content"})
+
+	pagination = &model.PaginationParams{Limit: ptr(2), Cursor: &page.NextCursor, WithTotal: ptr(true)}
+	page, err = svc.GetAllByCursor(ctx, newest, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != created[2].ID || page.Items[1].ID != created[1].ID {
+		t.Fatalf("unexpected second page: %+v", page.Items)
+	}
+	if page.Total == nil || *page.Total != 6 {
+		t.Fatalf("expected requested total of 6, got %v", page.Total)
+	}
+
+	// last page
+	pagination = &model.PaginationParams{Limit: ptr(2), Cursor: &page.NextCursor, WithTotal: ptr(false)}
+	last, err := svc.GetAllByCursor(ctx, newest, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(last.Items) != 1 || last.Items[0].ID != created[0].ID || last.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v next=%q", last.Items, last.NextCursor)
+	}
+
+	// back to the second page
+	pagination = &model.PaginationParams{Limit: ptr(2), Cursor: &last.PrevCursor, WithTotal: ptr(false)}
+	back, err := svc.GetAllByCursor(ctx, newest, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(back.Items) != 2 || back.Items[0].ID != created[2].ID || back.Items[1].ID != created[1].ID {
+		t.Fatalf("unexpected page walking backward: %+v", back.Items)
+	}
+	if back.PrevCursor == "" || back.NextCursor == "" {
+		t.Fatalf("expected both cursors on a middle page")
+	}
+
+	// garbage cursor
+	_, err = svc.GetAllByCursor(ctx, newest, &model.PaginationParams{Limit: ptr(2), Cursor: ptr("garbage")})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestPostServiceList(t *testing.T) {
+	ctx := context.Background()
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	postRepo.LinkComments(commentRepo)
+
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+	svc := NewPostService(postRepo, repository.NewInMemoryUserRepo(), repository.NewInMemoryEventRepo(), nil, nil, nil, nil)
+
+	alpha, _ := svc.Create(ctx, 1, &model.PostCreateRequest{Title: "Alpha", Content: "Content"})
+	gamma, _ := svc.Create(ctx, 2, &model.PostCreateRequest{Title: "Gamma", Content: "Content"})
+	beta, _ := svc.Create(ctx, 3, &model.PostCreateRequest{Title: "Beta", Content: "Content"})
+
+	commentRepo.Create(ctx, &model.Comment{PostID: gamma.ID, AuthorID: 1, Content: "first"})
+	commentRepo.Create(ctx, &model.Comment{PostID: gamma.ID, AuthorID: 1, Content: "second"})
+	commentRepo.Create(ctx, &model.Comment{PostID: beta.ID, AuthorID: 1, Content: "only"})
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	ids := func(posts []*model.Post) []int {
+		var res []int
+		for _, p := range posts {
+			res = append(res, p.ID)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name  string
+		query model.PostListParams
+		want  []int
+	}{
+		{"title asc", model.PostListParams{Sort: model.PostSortTitle, Order: model.SortAsc}, []int{alpha.ID, beta.ID, gamma.ID}},
+		{"comment count desc", model.PostListParams{Sort: model.PostSortCommentCount, Order: model.SortDesc}, []int{gamma.ID, beta.ID, alpha.ID}},
+		{"multiple authors", model.PostListParams{Sort: model.PostSortCreatedAt, Order: model.SortAsc, AuthorIDs: []int{1, 3}}, []int{alpha.ID, beta.ID}},
+		{"has comments", model.PostListParams{Sort: model.PostSortTitle, Order: model.SortAsc, HasComments: ptr(true)}, []int{beta.ID, gamma.ID}},
+		{"without comments", model.PostListParams{Sort: model.PostSortTitle, Order: model.SortAsc, HasComments: ptr(false)}, []int{alpha.ID}},
+		{"created range", model.PostListParams{Sort: model.PostSortCreatedAt, Order: model.SortAsc, CreatedFrom: &gamma.CreatedAt, CreatedTo: &beta.CreatedAt}, []int{gamma.ID, beta.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, total, err := svc.List(ctx, &tt.query, pagination)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := ids(posts); !slices.Equal(got, tt.want) || total != len(tt.want) {
+				t.Fatalf("expected %v (total %d), got %v (total %d)", tt.want, len(tt.want), got, total)
+			}
+		})
+	}
+
+	// keyset paging is bound to the natural order
+	sorted := &model.PostListParams{Sort: model.PostSortTitle, Order: model.SortAsc}
+	if _, err := svc.GetAllByCursor(ctx, sorted, &model.PaginationParams{Limit: ptr(2), Cursor: ptr("")}); !errors.Is(err, ErrCursorSortUnsupported) {
+		t.Fatalf("expected ErrCursorSortUnsupported, got %v", err)
+	}
+}
+
+func TestPostServiceExpandPosts(t *testing.T) {
+	ctx := context.Background()
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	postRepo.LinkComments(commentRepo)
+	postRepo.LinkUsers(userRepo)
+
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+	svc := NewPostService(postRepo, userRepo, repository.NewInMemoryEventRepo(), nil, nil, nil, nil)
+
+	author := &model.User{Username: "writer", Email: "writer@example.com"}
+	userRepo.Create(ctx, author)
+
+	commented, _ := svc.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Commented", Content: "Content"})
+	quiet, _ := svc.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Quiet", Content: "Content"})
+	commentRepo.Create(ctx, &model.Comment{PostID: commented.ID, AuthorID: author.ID, Content: "first"})
+	commentRepo.Create(ctx, &model.Comment{PostID: commented.ID, AuthorID: author.ID, Content: "second"})
+
+	posts := []*model.Post{commented, quiet}
+
+	// nothing requested
+	if err := svc.ExpandPosts(ctx, posts, &model.ExpandParams{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if commented.Author != nil || commented.CommentCount != nil {
+		t.Fatalf("expected no expansion, got %+v", commented)
+	}
+
+	expand := &model.ExpandParams{Fields: []string{model.ExpandAuthor, model.ExpandCommentCount}}
+	if err := svc.ExpandPosts(ctx, posts, expand); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, post := range posts {
+		if post.Author == nil || post.Author.Username != "writer" {
+			t.Fatalf("expected author summary, got %+v", post.Author)
+		}
+	}
+	if *commented.CommentCount != 2 || *quiet.CommentCount != 0 {
+		t.Fatalf("expected comment counts 2 and 0, got %d and %d", *commented.CommentCount, *quiet.CommentCount)
+	}
+}
+
+func TestPostServiceMentions(t *testing.T) {
+	ctx := context.Background()
+	svc := setupPostServiceForTest()
+	notificationRepo := repository.NewInMemoryNotificationRepo()
+	svc.notifications = NewNotificationService(notificationRepo, repository.NewInMemoryNotificationPreferenceRepo(), svc.postRepo, repository.NewInMemoryCommentRepo(), nil)
+	// new publications are announced by the relay
+	relay := NewEventRelay(svc.eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("post_publications", svc.AnnouncePublished, model.EventPostPublished)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	bob := &model.User{Username: "bob", Email: "bob@example.com"}
+	carol := &model.User{Username: "carol", Email: "carol@example.com"}
+	for _, u := range []*model.User{author, bob, carol} {
+		svc.userRepo.Create(ctx, u)
+	}
+
+	post, err := svc.Create(ctx, author.ID, &model.PostCreateRequest{
+		Title:   "Post",
+		Content: "Привет, @bob и @ghost! Пишите на me@bob.dev, @author",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(post.Mentions) != 2 {
+		t.Fatalf("expected 2 resolved mentions, got %+v", post.Mentions)
+	}
+	if m := post.Mentions[0]; m.UserID != bob.ID || m.Start != 8 || m.End != 12 {
+		t.Fatalf("unexpected mention range: %+v", m)
+	}
+	relay.Relay(ctx)
+	// the author mentioning themselves is not notified
+	if n := notificationRepo.All(); len(n) != 1 || n[0].UserID != bob.ID || n[0].Kind != model.NotificationMention {
+		t.Fatalf("expected a single notification for bob, got %+v", n)
+	}
+
+	// only newly added mentions are notified on edit
+	content := "@carol, @bob, взгляните"
+	updated, err := svc.Update(ctx, post.ID, author.ID, &model.PostUpdateRequest{Content: &content})
+	if err != nil || len(updated.Mentions) != 2 {
+		t.Fatalf("expected 2 mentions after update, got %+v err=%v", updated, err)
+	}
+	if n := notificationRepo.All(); len(n) != 2 || n[1].UserID != carol.ID {
+		t.Fatalf("expected carol to be notified, got %+v", n)
+	}
+
+	// scheduled posts notify once they are published
+	scheduled, _ := svc.Create(ctx, author.ID, &model.PostCreateRequest{
+		Title:     "Later",
+		Content:   "@bob",
+		PublishAt: ptr(time.Now().Add(time.Hour)),
+	})
+	relay.Relay(ctx)
+	if len(scheduled.Mentions) != 1 || len(notificationRepo.All()) != 2 {
+		t.Fatalf("expected scheduled post mentions to stay silent, got %d notifications", len(notificationRepo.All()))
+	}
+}
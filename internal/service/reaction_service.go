@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+)
+
+var (
+	ErrReactionNotFound = errors.New("reaction not found")
+)
+
+type ReactionService struct {
+	reactionRepo repository.ReactionRepository
+	postRepo     repository.PostRepository
+	commentRepo  repository.CommentRepository
+}
+
+func NewReactionService(
+	reactionRepo repository.ReactionRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+) *ReactionService {
+	return &ReactionService{
+		reactionRepo: reactionRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+	}
+}
+
+// SetPostReaction puts or replaces the reaction of the user on a published post
+func (s *ReactionService) SetPostReaction(ctx context.Context, userID, postID int, kind string) (*model.ReactionSummary, error) {
+	if err := s.ensurePostVisible(ctx, postID); err != nil {
+		return nil, err
+	}
+	return s.set(ctx, userID, model.ReactionTargetPost, postID, kind)
+}
+
+func (s *ReactionService) RemovePostReaction(ctx context.Context, userID, postID int) (*model.ReactionSummary, error) {
+	if err := s.ensurePostVisible(ctx, postID); err != nil {
+		return nil, err
+	}
+	return s.remove(ctx, userID, model.ReactionTargetPost, postID)
+}
+
+// SetCommentReaction puts or replaces the reaction of the user on an approved comment of a published post
+func (s *ReactionService) SetCommentReaction(
+	ctx context.Context,
+	userID, postID, commentID int,
+	kind string,
+) (*model.ReactionSummary, error) {
+	if err := s.ensureCommentVisible(ctx, postID, commentID); err != nil {
+		return nil, err
+	}
+	return s.set(ctx, userID, model.ReactionTargetComment, commentID, kind)
+}
+
+func (s *ReactionService) RemoveCommentReaction(ctx context.Context, userID, postID, commentID int) (*model.ReactionSummary, error) {
+	if err := s.ensureCommentVisible(ctx, postID, commentID); err != nil {
+		return nil, err
+	}
+	return s.remove(ctx, userID, model.ReactionTargetComment, commentID)
+}
+
+func (s *ReactionService) set(
+	ctx context.Context,
+	userID int,
+	targetType string,
+	targetID int,
+	kind string,
+) (*model.ReactionSummary, error) {
+
+	reaction := &model.Reaction{TargetType: targetType, TargetID: targetID, UserID: userID, Kind: kind}
+	counts, err := s.reactionRepo.Set(ctx, reaction)
+	if err != nil {
+		return nil, s.mapTargetError(err, targetType, targetID)
+	}
+
+	logger.Info("user_id=%d reacted %s to %s id=%d", userID, kind, targetType, targetID)
+	return newReactionSummary(targetType, targetID, counts, kind), nil
+}
+
+func (s *ReactionService) remove(ctx context.Context, userID int, targetType string, targetID int) (*model.ReactionSummary, error) {
+	counts, err := s.reactionRepo.Delete(ctx, targetType, targetID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrReactionNotFound) {
+			logger.Info("user_id=%d has no reaction on %s id=%d", userID, targetType, targetID)
+			return nil, ErrReactionNotFound
+		}
+		return nil, s.mapTargetError(err, targetType, targetID)
+	}
+
+	logger.Info("user_id=%d removed reaction from %s id=%d", userID, targetType, targetID)
+	return newReactionSummary(targetType, targetID, counts, ""), nil
+}
+
+func (s *ReactionService) mapTargetError(err error, targetType string, targetID int) error {
+	if errors.Is(err, repository.ErrReactionTargetNotFound) {
+		if targetType == model.ReactionTargetComment {
+			return ErrCommentNotFound
+		}
+		return ErrPostNotFound
+	}
+	logger.Error("failed to update reactions of %s id=%d: %v", targetType, targetID, err)
+	return ErrDatabase
+}
+
+func (s *ReactionService) ensurePostVisible(ctx context.Context, postID int) error {
+	published := true
+	if _, err := s.postRepo.GetPost(ctx, postID, &repository.PostFilter{Published: &published}); err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			logger.Info("post with id=%d not found or not published", postID)
+			return ErrPostNotFound
+		}
+		logger.Error("failed to fetch post id=%d: %v", postID, err)
+		return ErrDatabase
+	}
+	return nil
+}
+
+func (s *ReactionService) ensureCommentVisible(ctx context.Context, postID, commentID int) error {
+	if err := s.ensurePostVisible(ctx, postID); err != nil {
+		return err
+	}
+
+	_, err := s.commentRepo.GetComment(ctx, commentID, &repository.CommentFilter{
+		PostID:   &postID,
+		Statuses: []string{model.CommentStatusApproved},
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			logger.Info("comment with id=%d not found in post_id=%d", commentID, postID)
+			return ErrCommentNotFound
+		}
+		logger.Error("failed to fetch comment id=%d: %v", commentID, err)
+		return ErrDatabase
+	}
+	return nil
+}
+
+func newReactionSummary(targetType string, targetID int, counts model.ReactionCounts, mine string) *model.ReactionSummary {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return &model.ReactionSummary{
+		TargetType:    targetType,
+		TargetID:      targetID,
+		Reactions:     counts,
+		ReactionCount: total,
+		Mine:          mine,
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestReactionService(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	svc := NewReactionService(repository.NewInMemoryReactionRepo(postRepo, commentRepo), postRepo, commentRepo)
+	posts := NewPostService(postRepo, repository.NewInMemoryUserRepo(), repository.NewInMemoryEventRepo(), nil, nil, nil, nil)
+
+	quiet := &model.Post{Title: "Quiet", Content: "Content", Published: true, AuthorID: 1}
+	popular := &model.Post{Title: "Popular", Content: "Content", Published: true, AuthorID: 1}
+	draft := &model.Post{Title: "Draft", Content: "Content", Published: false, AuthorID: 1}
+	for _, p := range []*model.Post{quiet, popular, draft} {
+		postRepo.Create(ctx, p)
+	}
+	comment := &model.Comment{PostID: popular.ID, AuthorID: 1, Content: "comment"}
+	commentRepo.Create(ctx, comment)
+
+	// one reaction per user, a new kind replaces the old one
+	svc.SetPostReaction(ctx, 1, popular.ID, model.ReactionLike)
+	svc.SetPostReaction(ctx, 2, popular.ID, model.ReactionLike)
+	summary, err := svc.SetPostReaction(ctx, 2, popular.ID, model.ReactionLove)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if summary.ReactionCount != 2 || summary.Reactions[model.ReactionLike] != 1 || summary.Reactions[model.ReactionLove] != 1 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	// counts are denormalized on the target
+	got, _ := postRepo.GetPost(ctx, popular.ID, nil)
+	if got.ReactionCount != 2 {
+		t.Fatalf("expected denormalized count 2, got %d", got.ReactionCount)
+	}
+
+	// removal
+	summary, err = svc.RemovePostReaction(ctx, 1, popular.ID)
+	if err != nil || summary.ReactionCount != 1 || summary.Reactions[model.ReactionLike] != 0 {
+		t.Fatalf("unexpected summary after removal: %+v, err=%v", summary, err)
+	}
+	if _, err := svc.RemovePostReaction(ctx, 1, popular.ID); !errors.Is(err, ErrReactionNotFound) {
+		t.Fatalf("expected ErrReactionNotFound, got %v", err)
+	}
+
+	// unpublished posts and missing comments
+	if _, err := svc.SetPostReaction(ctx, 1, draft.ID, model.ReactionLike); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+	if _, err := svc.SetCommentReaction(ctx, 1, quiet.ID, comment.ID, model.ReactionLike); !errors.Is(err, ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound for a comment of another post, got %v", err)
+	}
+
+	summary, err = svc.SetCommentReaction(ctx, 1, popular.ID, comment.ID, model.ReactionLaugh)
+	if err != nil || summary.ReactionCount != 1 || comment.ReactionCount != 1 {
+		t.Fatalf("unexpected comment summary: %+v, err=%v", summary, err)
+	}
+
+	// popularity sort
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	list, _, err := posts.List(ctx, &model.PostListParams{Sort: model.PostSortPopularity, Order: model.SortDesc}, pagination)
+	if err != nil || len(list) != 2 || list[0].ID != popular.ID {
+		t.Fatalf("expected popular post first, got %+v, err=%v", list, err)
+	}
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/settings"
+)
+
+// config
+type ReportConfig struct {
+	AutoHideThreshold int // distinct open reports hiding the content until a moderator decides, 0 disables
+}
+
+func (c *ReportConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "REPORTS_AUTO_HIDE_THRESHOLD", Default: 3, Field: &c.AutoHideThreshold},
+	}
+}
+
+var (
+	ErrReportNotFound  = errors.New("report not found")
+	ErrReportClosed    = errors.New("report already closed")
+	ErrAlreadyReported = errors.New("content already reported by the user")
+	ErrSelfReport      = errors.New("cannot report own content")
+)
+
+type ReportService struct {
+	reportRepo  repository.ReportRepository
+	logRepo     repository.ModerationLogRepository
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	userRepo    repository.UserRepository
+	config      *ReportConfig
+}
+
+func NewReportService(
+	reportRepo repository.ReportRepository,
+	logRepo repository.ModerationLogRepository,
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	userRepo repository.UserRepository,
+	config *ReportConfig,
+) *ReportService {
+	return &ReportService{
+		reportRepo:  reportRepo,
+		logRepo:     logRepo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		userRepo:    userRepo,
+		config:      config,
+	}
+}
+
+// ReportPost files a report of the user on a published post
+func (s *ReportService) ReportPost(
+	ctx context.Context,
+	userID, postID int,
+	req *model.ReportCreateRequest,
+) (*model.Report, error) {
+
+	post, err := s.publishedPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID == userID {
+		logger.Info("user_id=%d tried to report own post id=%d", userID, postID)
+		return nil, ErrSelfReport
+	}
+
+	return s.create(ctx, &model.Report{
+		TargetType: model.ReportTargetPost,
+		TargetID:   postID,
+		ReporterID: userID,
+		Reason:     req.Reason,
+		Text:       req.Text,
+	})
+}
+
+// ReportComment files a report of the user on an approved comment of a published post
+func (s *ReportService) ReportComment(
+	ctx context.Context,
+	userID, postID, commentID int,
+	req *model.ReportCreateRequest,
+) (*model.Report, error) {
+
+	if _, err := s.publishedPost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.commentRepo.GetComment(ctx, commentID, &repository.CommentFilter{
+		PostID:   &postID,
+		Statuses: []string{model.CommentStatusApproved},
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			logger.Info("comment with id=%d not found in post_id=%d", commentID, postID)
+			return nil, ErrCommentNotFound
+		}
+		logger.Error("failed to fetch comment id=%d: %v", commentID, err)
+		return nil, ErrDatabase
+	}
+	if comment.AuthorID == userID {
+		logger.Info("user_id=%d tried to report own comment id=%d", userID, commentID)
+		return nil, ErrSelfReport
+	}
+
+	return s.create(ctx, &model.Report{
+		TargetType: model.ReportTargetComment,
+		TargetID:   commentID,
+		ReporterID: userID,
+		Reason:     req.Reason,
+		Text:       req.Text,
+	})
+}
+
+// GetReports lists reports for moderators, oldest first
+func (s *ReportService) GetReports(
+	ctx context.Context,
+	userID int,
+	query *model.ReportListParams,
+	pagination *model.PaginationParams,
+) ([]*model.Report, int, error) {
+
+	if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+		return nil, 0, err
+	}
+
+	filter := &repository.ReportFilter{Status: &query.Status}
+	if query.TargetType != "" {
+		filter.TargetType = &query.TargetType
+	}
+
+	reports, err := s.reportRepo.GetReports(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch %s reports: %v", query.Status, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return reports, 0, nil
+	}
+
+	total, err := s.reportRepo.GetReportsCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count %s reports: %v", query.Status, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return reports, total, nil
+}
+
+// Resolve upholds the report, removes the reported content and closes every open report on it
+func (s *ReportService) Resolve(ctx context.Context, userID, reportID int, note string) (*model.Report, error) {
+	report, err := s.openReport(ctx, userID, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.reportRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.remove(ctx, report.TargetType, report.TargetID); err != nil {
+			return err
+		}
+		return s.close(ctx, userID, report, model.ReportStatusResolved, model.ModerationActionResolve, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getReport(ctx, reportID)
+}
+
+// Dismiss rejects the report, closes every open report on the content and brings back content hidden by reports
+func (s *ReportService) Dismiss(ctx context.Context, userID, reportID int, note string) (*model.Report, error) {
+	report, err := s.openReport(ctx, userID, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.reportRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.restore(ctx, report.TargetType, report.TargetID); err != nil {
+			return err
+		}
+		return s.close(ctx, userID, report, model.ReportStatusDismissed, model.ModerationActionDismiss, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getReport(ctx, reportID)
+}
+
+// GetLog lists recorded moderation decisions for moderators, newest first
+func (s *ReportService) GetLog(
+	ctx context.Context,
+	userID int,
+	query *model.ModerationLogParams,
+	pagination *model.PaginationParams,
+) ([]*model.ModerationLogEntry, int, error) {
+
+	if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+		return nil, 0, err
+	}
+
+	filter := &repository.ModerationLogFilter{TargetID: query.TargetID}
+	if query.TargetType != "" {
+		filter.TargetType = &query.TargetType
+	}
+
+	entries, err := s.logRepo.GetEntries(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch moderation log: %v", err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return entries, 0, nil
+	}
+
+	total, err := s.logRepo.GetEntriesCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count moderation log: %v", err)
+		return nil, 0, ErrDatabase
+	}
+
+	return entries, total, nil
+}
+
+func (s *ReportService) create(ctx context.Context, report *model.Report) (*model.Report, error) {
+	err := s.reportRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.reportRepo.Create(ctx, report); err != nil {
+			if errors.Is(err, repository.ErrReportExists) {
+				logger.Info("user_id=%d already reported %s id=%d", report.ReporterID, report.TargetType, report.TargetID)
+				return ErrAlreadyReported
+			}
+			logger.Error("failed to create report on %s id=%d: %v", report.TargetType, report.TargetID, err)
+			return ErrDatabase
+		}
+		return s.autoHide(ctx, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("user_id=%d reported %s id=%d as %s", report.ReporterID, report.TargetType, report.TargetID, report.Reason)
+	return report, nil
+}
+
+// autoHide takes the content out of public view once enough distinct readers reported it
+func (s *ReportService) autoHide(ctx context.Context, report *model.Report) error {
+	if s.config.AutoHideThreshold <= 0 {
+		return nil
+	}
+
+	status := model.ReportStatusOpen
+	count, err := s.reportRepo.GetReportsCount(ctx, &repository.ReportFilter{
+		Status:     &status,
+		TargetType: &report.TargetType,
+		TargetID:   &report.TargetID,
+	})
+	if err != nil {
+		logger.Error("failed to count reports on %s id=%d: %v", report.TargetType, report.TargetID, err)
+		return ErrDatabase
+	}
+	if count < s.config.AutoHideThreshold {
+		return nil
+	}
+
+	hidden, err := s.hide(ctx, report.TargetType, report.TargetID)
+	if err != nil || !hidden {
+		return err
+	}
+
+	logger.Info("%s id=%d hidden after %d reports", report.TargetType, report.TargetID, count)
+	return recordDecision(ctx, s.logRepo, &model.ModerationLogEntry{
+		Action:     model.ModerationActionHide,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		ReportID:   &report.ID,
+		Note:       fmt.Sprintf("%d open reports", count),
+	})
+}
+
+// hide sends a public post to the held queue and an approved comment to the pending queue
+func (s *ReportService) hide(ctx context.Context, targetType string, targetID int) (bool, error) {
+	switch targetType {
+	case model.ReportTargetPost:
+		post, err := s.postRepo.GetPost(ctx, targetID, nil)
+		if err != nil {
+			logger.Error("failed to fetch post id=%d: %v", targetID, err)
+			return false, ErrDatabase
+		}
+		if post.Held {
+			return false, nil
+		}
+		post.Held = true
+		post.Published = false
+		if err := s.postRepo.Update(ctx, post); err != nil {
+			logger.Error("failed to hide post id=%d: %v", targetID, err)
+			return false, ErrDatabase
+		}
+	case model.ReportTargetComment:
+		comment, err := s.commentRepo.GetByID(ctx, targetID)
+		if err != nil {
+			logger.Error("failed to fetch comment id=%d: %v", targetID, err)
+			return false, ErrDatabase
+		}
+		if comment.Status != model.CommentStatusApproved {
+			return false, nil
+		}
+		if _, err := s.commentRepo.SetStatus(ctx, []int{targetID}, model.CommentStatusPending); err != nil {
+			logger.Error("failed to hide comment id=%d: %v", targetID, err)
+			return false, ErrDatabase
+		}
+	}
+	return true, nil
+}
+
+// restore undoes the automatic hiding unless a moderator has decided on the content since
+func (s *ReportService) restore(ctx context.Context, targetType string, targetID int) error {
+	latest, err := s.logRepo.GetEntries(ctx, &repository.ModerationLogFilter{
+		TargetType: &targetType,
+		TargetID:   &targetID,
+	}, 1, 0)
+	if err != nil {
+		logger.Error("failed to fetch moderation log of %s id=%d: %v", targetType, targetID, err)
+		return ErrDatabase
+	}
+	if len(latest) == 0 || latest[0].Action != model.ModerationActionHide {
+		return nil
+	}
+
+	switch targetType {
+	case model.ReportTargetPost:
+		held := true
+		post, err := s.postRepo.GetPost(ctx, targetID, &repository.PostFilter{Held: &held})
+		if err != nil {
+			if errors.Is(err, repository.ErrPostNotFound) {
+				return nil
+			}
+			logger.Error("failed to fetch post id=%d: %v", targetID, err)
+			return ErrDatabase
+		}
+		post.Held = false
+		post.Published = post.PublishAt == nil || !post.PublishAt.After(time.Now())
+		if err := s.postRepo.Update(ctx, post); err != nil {
+			logger.Error("failed to restore post id=%d: %v", targetID, err)
+			return ErrDatabase
+		}
+	case model.ReportTargetComment:
+		_, err := s.commentRepo.GetComment(ctx, targetID, &repository.CommentFilter{
+			Statuses: []string{model.CommentStatusPending},
+		})
+		if err != nil {
+			if errors.Is(err, repository.ErrCommentNotFound) {
+				return nil
+			}
+			logger.Error("failed to fetch comment id=%d: %v", targetID, err)
+			return ErrDatabase
+		}
+		if _, err := s.commentRepo.SetStatus(ctx, []int{targetID}, model.CommentStatusApproved); err != nil {
+			logger.Error("failed to restore comment id=%d: %v", targetID, err)
+			return ErrDatabase
+		}
+	}
+
+	logger.Info("%s id=%d restored after dismissed reports", targetType, targetID)
+	return nil
+}
+
+// remove trashes a reported post and rejects a reported comment
+func (s *ReportService) remove(ctx context.Context, targetType string, targetID int) error {
+	switch targetType {
+	case model.ReportTargetPost:
+		if err := s.postRepo.Delete(ctx, targetID); err != nil && !errors.Is(err, repository.ErrPostNotFound) {
+			logger.Error("failed to remove post id=%d: %v", targetID, err)
+			return ErrDatabase
+		}
+	case model.ReportTargetComment:
+		if _, err := s.commentRepo.SetStatus(ctx, []int{targetID}, model.CommentStatusRejected); err != nil {
+			logger.Error("failed to remove comment id=%d: %v", targetID, err)
+			return ErrDatabase
+		}
+	}
+	return nil
+}
+
+func (s *ReportService) close(
+	ctx context.Context,
+	userID int,
+	report *model.Report,
+	status, action, note string,
+) error {
+
+	closed, err := s.reportRepo.Close(ctx, report.TargetType, report.TargetID, status, userID)
+	if err != nil {
+		logger.Error("failed to close reports on %s id=%d: %v", report.TargetType, report.TargetID, err)
+		return ErrDatabase
+	}
+
+	logger.Info("user_id=%d %s %d reports on %s id=%d", userID, status, closed, report.TargetType, report.TargetID)
+	return recordDecision(ctx, s.logRepo, &model.ModerationLogEntry{
+		ModeratorID: &userID,
+		Action:      action,
+		TargetType:  report.TargetType,
+		TargetID:    report.TargetID,
+		ReportID:    &report.ID,
+		Note:        note,
+	})
+}
+
+// openReport fetches a report a moderator is about to decide on
+func (s *ReportService) openReport(ctx context.Context, userID, reportID int) (*model.Report, error) {
+	if err := ensureModerator(ctx, s.userRepo, userID); err != nil {
+		return nil, err
+	}
+
+	report, err := s.getReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != model.ReportStatusOpen {
+		logger.Info("report id=%d is already %s", reportID, report.Status)
+		return nil, ErrReportClosed
+	}
+	return report, nil
+}
+
+func (s *ReportService) getReport(ctx context.Context, reportID int) (*model.Report, error) {
+	report, err := s.reportRepo.GetByID(ctx, reportID)
+	if err != nil {
+		if errors.Is(err, repository.ErrReportNotFound) {
+			logger.Info("report with id=%d not found", reportID)
+			return nil, ErrReportNotFound
+		}
+		logger.Error("failed to fetch report id=%d: %v", reportID, err)
+		return nil, ErrDatabase
+	}
+	return report, nil
+}
+
+func (s *ReportService) publishedPost(ctx context.Context, postID int) (*model.Post, error) {
+	published := true
+	post, err := s.postRepo.GetPost(ctx, postID, &repository.PostFilter{Published: &published})
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			logger.Info("post with id=%d not found or not published", postID)
+			return nil, ErrPostNotFound
+		}
+		logger.Error("failed to fetch post id=%d: %v", postID, err)
+		return nil, ErrDatabase
+	}
+	return post, nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func TestReportServiceAutoHide(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	logRepo := repository.NewInMemoryModerationLogRepo()
+
+	reports := NewReportService(
+		repository.NewInMemoryReportRepo(),
+		logRepo,
+		postRepo,
+		commentRepo,
+		userRepo,
+		&ReportConfig{AutoHideThreshold: 2},
+	)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	moderator := &model.User{Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator}
+	readers := []*model.User{
+		{Username: "reader1", Email: "reader1@example.com"},
+		{Username: "reader2", Email: "reader2@example.com"},
+	}
+	for _, u := range append([]*model.User{author, moderator}, readers...) {
+		userRepo.Create(ctx, u)
+	}
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID}
+	postRepo.Create(ctx, post)
+	comment := &model.Comment{Content: "rude", PostID: post.ID, AuthorID: author.ID}
+	commentRepo.Create(ctx, comment)
+
+	req := &model.ReportCreateRequest{Reason: model.ReportReasonAbuse}
+
+	// one report per reader and target, own content cannot be reported
+	first, err := reports.ReportComment(ctx, readers[0].ID, post.ID, comment.ID, req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := reports.ReportComment(ctx, readers[0].ID, post.ID, comment.ID, req); !errors.Is(err, ErrAlreadyReported) {
+		t.Fatalf("expected ErrAlreadyReported, got %v", err)
+	}
+	if _, err := reports.ReportPost(ctx, author.ID, post.ID, req); !errors.Is(err, ErrSelfReport) {
+		t.Fatalf("expected ErrSelfReport, got %v", err)
+	}
+
+	// the duplicate does not count towards the threshold
+	stored, _ := commentRepo.GetByID(ctx, comment.ID)
+	if stored.Status != model.CommentStatusApproved {
+		t.Fatalf("expected comment to stay visible after one reader, got %q", stored.Status)
+	}
+
+	if _, err := reports.ReportComment(ctx, readers[1].ID, post.ID, comment.ID, req); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored, _ = commentRepo.GetByID(ctx, comment.ID)
+	if stored.Status != model.CommentStatusPending {
+		t.Fatalf("expected comment to be hidden after two readers, got %q", stored.Status)
+	}
+
+	// hidden content cannot be reported any more
+	if _, err := reports.ReportComment(ctx, moderator.ID, post.ID, comment.ID, req); !errors.Is(err, ErrCommentNotFound) {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+
+	// dismissal closes every report on the target and brings the comment back
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	if _, _, err := reports.GetReports(ctx, author.ID, &model.ReportListParams{Status: model.ReportStatusOpen}, pagination); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	dismissed, err := reports.Dismiss(ctx, moderator.ID, first.ID, "banter")
+	if err != nil || dismissed.Status != model.ReportStatusDismissed {
+		t.Fatalf("expected dismissed report, got %+v err=%v", dismissed, err)
+	}
+	if _, total, _ := reports.GetReports(ctx, moderator.ID, &model.ReportListParams{Status: model.ReportStatusOpen}, pagination); total != 0 {
+		t.Fatalf("expected no open reports, got %d", total)
+	}
+	stored, _ = commentRepo.GetByID(ctx, comment.ID)
+	if stored.Status != model.CommentStatusApproved {
+		t.Fatalf("expected dismissal to restore the comment, got %q", stored.Status)
+	}
+	if _, err := reports.Resolve(ctx, moderator.ID, first.ID, ""); !errors.Is(err, ErrReportClosed) {
+		t.Fatalf("expected ErrReportClosed, got %v", err)
+	}
+
+	// every decision is in the log, newest first
+	entries, total, err := reports.GetLog(ctx, moderator.ID, &model.ModerationLogParams{TargetType: model.ReportTargetComment}, pagination)
+	if err != nil || total != 2 {
+		t.Fatalf("expected 2 log entries, got %d err=%v", total, err)
+	}
+	if entries[0].Action != model.ModerationActionDismiss || entries[1].Action != model.ModerationActionHide || entries[1].ModeratorID != nil {
+		t.Fatalf("unexpected log: %+v %+v", entries[0], entries[1])
+	}
+}
+
+func TestReportServiceResolve(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	logRepo := repository.NewInMemoryModerationLogRepo()
+
+	reports := NewReportService(
+		repository.NewInMemoryReportRepo(),
+		logRepo,
+		postRepo,
+		commentRepo,
+		userRepo,
+		&ReportConfig{AutoHideThreshold: 0},
+	)
+	moderation := NewModerationService(commentRepo, postRepo, userRepo, repository.NewInMemorySpamSampleRepo(), logRepo, repository.NewInMemoryEventRepo(), nil)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	moderator := &model.User{Username: "moderator", Email: "moderator@example.com", Role: model.UserRoleModerator}
+	for _, u := range []*model.User{author, reader, moderator} {
+		userRepo.Create(ctx, u)
+	}
+
+	post := &model.Post{Title: "Post", Content: "Content", Published: true, AuthorID: author.ID}
+	postRepo.Create(ctx, post)
+
+	report, err := reports.ReportPost(ctx, reader.ID, post.ID, &model.ReportCreateRequest{Reason: model.ReportReasonIllegal, Text: "pirated"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stored, _ := postRepo.GetPost(ctx, post.ID, nil)
+	if stored.Held || !stored.Published {
+		t.Fatalf("expected post to stay public with auto-hide disabled")
+	}
+
+	if _, err := reports.Resolve(ctx, reader.ID, report.ID, ""); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	resolved, err := reports.Resolve(ctx, moderator.ID, report.ID, "copyright")
+	if err != nil || resolved.Status != model.ReportStatusResolved || resolved.ResolvedAt == nil {
+		t.Fatalf("expected resolved report, got %+v err=%v", resolved, err)
+	}
+	if _, err := postRepo.GetPost(ctx, post.ID, nil); !errors.Is(err, repository.ErrPostNotFound) {
+		t.Fatalf("expected resolved post to be trashed, got %v", err)
+	}
+
+	// queue decisions are logged too
+	comment := &model.Comment{Content: "queued", PostID: post.ID, AuthorID: reader.ID, Status: model.CommentStatusPending}
+	commentRepo.Create(ctx, comment)
+	if _, err := moderation.Reject(ctx, moderator.ID, []int{comment.ID}, true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	entries, total, err := reports.GetLog(ctx, moderator.ID, &model.ModerationLogParams{}, pagination)
+	if err != nil || total != 2 {
+		t.Fatalf("expected 2 log entries, got %d err=%v", total, err)
+	}
+	if entries[0].Action != model.ModerationActionSpam || entries[1].Action != model.ModerationActionResolve || entries[1].Note != "copyright" {
+		t.Fatalf("unexpected log: %+v %+v", entries[0], entries[1])
+	}
+}
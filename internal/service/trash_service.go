@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/settings"
+)
+
+// config
+type TrashConfig struct {
+	RetentionHours int
+}
+
+func (c *TrashConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "TRASH_RETENTION_HOURS", Default: 720, Field: &c.RetentionHours},
+	}
+}
+
+func (c *TrashConfig) Retention() time.Duration {
+	return time.Duration(c.RetentionHours) * time.Hour
+}
+
+type TrashService struct {
+	postRepo     repository.PostRepository
+	commentRepo  repository.CommentRepository
+	reactionRepo repository.ReactionRepository
+	reportRepo   repository.ReportRepository
+}
+
+func NewTrashService(
+	postRepo repository.PostRepository,
+	commentRepo repository.CommentRepository,
+	reactionRepo repository.ReactionRepository,
+	reportRepo repository.ReportRepository,
+) *TrashService {
+	return &TrashService{
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		reactionRepo: reactionRepo,
+		reportRepo:   reportRepo,
+	}
+}
+
+func (s *TrashService) GetPosts(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+) ([]*model.Post, int, error) {
+	filter := &repository.PostFilter{
+		AuthorID: &userID,
+		Trashed:  true,
+	}
+
+	posts, err := s.postRepo.GetPosts(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch trashed posts for user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return posts, 0, nil
+	}
+
+	total, err := s.postRepo.GetPostsCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count trashed posts for user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return posts, total, nil
+}
+
+func (s *TrashService) GetComments(
+	ctx context.Context,
+	userID int,
+	pagination *model.PaginationParams,
+) ([]*model.Comment, int, error) {
+	filter := &repository.CommentFilter{
+		AuthorID: &userID,
+		Trashed:  true,
+	}
+
+	comments, err := s.commentRepo.GetComments(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch trashed comments for user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	if !pagination.CountTotal() {
+		return comments, 0, nil
+	}
+
+	total, err := s.commentRepo.GetCommentsCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count trashed comments for user_id=%d: %v", userID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return comments, total, nil
+}
+
+func (s *TrashService) RestorePost(ctx context.Context, id int, userID int) (*model.Post, error) {
+	post, err := s.postRepo.GetPost(ctx, id, &repository.PostFilter{Trashed: true})
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			logger.Info("trashed post with id=%d not found", id)
+			return nil, ErrPostNotFound
+		}
+		logger.Error("failed to fetch trashed post id=%d: %v", id, err)
+		return nil, ErrDatabase
+	}
+
+	if post.AuthorID != userID {
+		logger.Info("user_id=%d is not the author of trashed post_id=%d", userID, id)
+		return nil, ErrForbidden
+	}
+
+	if err := s.postRepo.Restore(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			return nil, ErrPostNotFound
+		}
+		logger.Error("failed to restore post id=%d: %v", id, err)
+		return nil, ErrDatabase
+	}
+
+	post.DeletedAt.Valid = false
+	return post, nil
+}
+
+func (s *TrashService) RestoreComment(ctx context.Context, id int, userID int) (*model.Comment, error) {
+	comment, err := s.commentRepo.GetComment(ctx, id, &repository.CommentFilter{Trashed: true})
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			logger.Info("trashed comment with id=%d not found", id)
+			return nil, ErrCommentNotFound
+		}
+		logger.Error("failed to fetch trashed comment id=%d: %v", id, err)
+		return nil, ErrDatabase
+	}
+
+	if comment.AuthorID != userID {
+		logger.Info("user_id=%d is not the author of trashed comment_id=%d", userID, id)
+		return nil, ErrForbidden
+	}
+
+	if err := s.commentRepo.Restore(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		logger.Error("failed to restore comment id=%d: %v", id, err)
+		return nil, ErrDatabase
+	}
+
+	comment.DeletedAt.Valid = false
+	return comment, nil
+}
+
+/*
+Purge hard deletes posts and comments trashed before the given moment. The reactions and reports
+of the purged posts and comments, including the comments deleted along with their posts, go in the same transaction.
+*/
+func (s *TrashService) Purge(ctx context.Context, before time.Time) error {
+	var posts, comments, reactions, reports int
+	err := s.reportRepo.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if posts, err = s.postRepo.Purge(ctx, before); err != nil {
+			return fmt.Errorf("failed to purge trashed posts: %w", err)
+		}
+		if comments, err = s.commentRepo.Purge(ctx, before); err != nil {
+			return fmt.Errorf("failed to purge trashed comments: %w", err)
+		}
+		if reactions, err = s.reactionRepo.DeleteOrphaned(ctx); err != nil {
+			return err
+		}
+		if reports, err = s.reportRepo.DeleteOrphaned(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to purge trash: %v", err)
+		return ErrDatabase
+	}
+
+	if posts > 0 || comments > 0 {
+		logger.Info("trash purged: posts=%d, comments=%d, reactions=%d, reports=%d", posts, comments, reactions, reports)
+	}
+	return nil
+}
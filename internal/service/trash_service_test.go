@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+)
+
+func setupTrashServiceForTest() (*TrashService, *PostService, *CommentService) {
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	reportRepo := repository.NewInMemoryReportRepo()
+	reportRepo.LinkTargets(postRepo, commentRepo)
+
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	return NewTrashService(postRepo, commentRepo, repository.NewInMemoryReactionRepo(postRepo, commentRepo), reportRepo),
+		NewPostService(postRepo, userRepo, repository.NewInMemoryEventRepo(), nil, nil, nil, nil),
+		NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, repository.NewInMemoryEventRepo(), &CommentConfig{MaxDepth: 5}, nil, nil, nil)
+}
+
+func TestTrashServicePosts(t *testing.T) {
+	ctx := context.Background()
+	trash, posts, _ := setupTrashServiceForTest()
+
+	userID := 1
+	post, _ := posts.Create(ctx, userID, &model.PostCreateRequest{Title: "Trashed", Content: "Content"})
+	if err := posts.Delete(ctx, post.ID, userID); err != nil {
+		t.Fatalf("expected no error deleting post, got %v", err)
+	}
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+
+	// hidden from normal queries
+	if _, err := posts.GetByID(ctx, post.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound for trashed post, got %v", err)
+	}
+	if _, total, _ := posts.List(ctx, &model.PostListParams{Sort: model.PostSortCreatedAt, Order: model.SortDesc}, pagination); total != 0 {
+		t.Fatalf("expected trashed post to be excluded, got total %d", total)
+	}
+
+	// listed in own trash only
+	trashed, total, err := trash.GetPosts(ctx, userID, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 1 || len(trashed) != 1 || trashed[0].ID != post.ID {
+		t.Fatalf("expected trashed post in trash, got total=%d", total)
+	}
+	if _, total, _ := trash.GetPosts(ctx, userID+1, pagination); total != 0 {
+		t.Fatalf("expected empty trash for another user, got %d", total)
+	}
+
+	// restore by another user
+	if _, err := trash.RestorePost(ctx, post.ID, userID+1); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	// restore
+	if _, err := trash.RestorePost(ctx, post.ID, userID); err != nil {
+		t.Fatalf("expected no error restoring post, got %v", err)
+	}
+	if _, err := posts.GetByID(ctx, post.ID); err != nil {
+		t.Fatalf("expected restored post to be visible, got %v", err)
+	}
+
+	// restore not trashed
+	if _, err := trash.RestorePost(ctx, post.ID, userID); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+}
+
+func TestTrashServiceComments(t *testing.T) {
+	ctx := context.Background()
+	trash, posts, comments := setupTrashServiceForTest()
+
+	userID := 1
+	post, _ := posts.Create(ctx, userID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	comment, _ := comments.Create(ctx, userID, post.ID, &model.CommentCreateRequest{Content: "Comment"})
+
+	if err := comments.Delete(ctx, comment.ID, userID); err != nil {
+		t.Fatalf("expected no error deleting comment, got %v", err)
+	}
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	if _, total, _ := comments.GetByPost(ctx, post.ID, nil, pagination); total != 0 {
+		t.Fatalf("expected trashed comment to be excluded, got total %d", total)
+	}
+
+	trashed, total, err := trash.GetComments(ctx, userID, pagination)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 1 || len(trashed) != 1 {
+		t.Fatalf("expected 1 trashed comment, got %d", total)
+	}
+
+	if _, err := trash.RestoreComment(ctx, comment.ID, userID); err != nil {
+		t.Fatalf("expected no error restoring comment, got %v", err)
+	}
+	if _, total, _ := comments.GetByPost(ctx, post.ID, nil, pagination); total != 1 {
+		t.Fatalf("expected restored comment to be listed, got total %d", total)
+	}
+}
+
+func TestTrashServicePurge(t *testing.T) {
+	ctx := context.Background()
+	trash, posts, comments := setupTrashServiceForTest()
+
+	userID := 1
+	post, _ := posts.Create(ctx, userID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	comment, _ := comments.Create(ctx, userID, post.ID, &model.CommentCreateRequest{Content: "Comment"})
+	_ = comments.Delete(ctx, comment.ID, userID)
+	_ = posts.Delete(ctx, post.ID, userID)
+
+	// retention not reached
+	if err := trash.Purge(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+	if _, total, _ := trash.GetPosts(ctx, userID, pagination); total != 1 {
+		t.Fatalf("expected post to stay in trash, got %d", total)
+	}
+
+	// retention reached
+	if err := trash.Purge(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, total, _ := trash.GetPosts(ctx, userID, pagination); total != 0 {
+		t.Fatalf("expected trash to be purged, got %d posts", total)
+	}
+	if _, total, _ := trash.GetComments(ctx, userID, pagination); total != 0 {
+		t.Fatalf("expected trash to be purged, got %d comments", total)
+	}
+	if _, err := trash.RestorePost(ctx, post.ID, userID); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound for purged post, got %v", err)
+	}
+}
+
+func TestTrashServicePurgeReactionsAndReports(t *testing.T) {
+	ctx := context.Background()
+	postRepo := repository.NewInMemoryPostRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	reactionRepo := repository.NewInMemoryReactionRepo(postRepo, commentRepo)
+	reportRepo := repository.NewInMemoryReportRepo()
+	reportRepo.LinkTargets(postRepo, commentRepo)
+	trash := NewTrashService(postRepo, commentRepo, reactionRepo, reportRepo)
+
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo.Create(ctx, &model.Post{ID: 1, Title: "Trashed", Content: "Content", AuthorID: 1})
+	postRepo.Create(ctx, &model.Post{ID: 2, Title: "Live", Content: "Content", AuthorID: 1})
+	commentRepo.Create(ctx, &model.Comment{ID: 1, Content: "Trashed", PostID: 2, AuthorID: 1})
+	for _, target := range []struct {
+		targetType string
+		targetID   int
+	}{{model.ReactionTargetPost, 1}, {model.ReactionTargetPost, 2}, {model.ReactionTargetComment, 1}} {
+		_, _ = reactionRepo.Set(ctx, &model.Reaction{TargetType: target.targetType, TargetID: target.targetID, UserID: 2, Kind: model.ReactionLike})
+		_ = reportRepo.Create(ctx, &model.Report{TargetType: target.targetType, TargetID: target.targetID, ReporterID: 2, Reason: model.ReportReasonSpam})
+	}
+	postRepo.Delete(ctx, 1)
+	commentRepo.Delete(ctx, 1)
+
+	if err := trash.Purge(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// purged targets lose their reactions and reports
+	if _, err := reactionRepo.Delete(ctx, model.ReactionTargetPost, 1, 2); !errors.Is(err, repository.ErrReactionNotFound) {
+		t.Fatalf("expected no reaction left on purged post, got %v", err)
+	}
+	if _, err := reactionRepo.Delete(ctx, model.ReactionTargetComment, 1, 2); !errors.Is(err, repository.ErrReactionNotFound) {
+		t.Fatalf("expected no reaction left on purged comment, got %v", err)
+	}
+	for _, targetType := range []string{model.ReportTargetPost, model.ReportTargetComment} {
+		filter := &repository.ReportFilter{TargetType: ptr(targetType), TargetID: ptr(1)}
+		if count, _ := reportRepo.GetReportsCount(ctx, filter); count != 0 {
+			t.Fatalf("expected no reports left on purged %s, got %d", targetType, count)
+		}
+	}
+
+	// live targets keep theirs
+	if _, err := reactionRepo.Delete(ctx, model.ReactionTargetPost, 2, 2); err != nil {
+		t.Fatalf("expected reaction on live post to stay, got %v", err)
+	}
+	filter := &repository.ReportFilter{TargetType: ptr(model.ReportTargetPost), TargetID: ptr(2)}
+	if count, _ := reportRepo.GetReportsCount(ctx, filter); count != 1 {
+		t.Fatalf("expected report on live post to stay, got %d", count)
+	}
+}
+
+func TestTrashServicePurgeKeepsTombstones(t *testing.T) {
+	ctx := context.Background()
+	trash, posts, comments := setupTrashServiceForTest()
+
+	userID := 1
+	post, _ := posts.Create(ctx, userID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	parent, _ := comments.Create(ctx, userID, post.ID, &model.CommentCreateRequest{Content: "Parent"})
+	reply, _ := comments.Create(ctx, userID, post.ID, &model.CommentCreateRequest{Content: "Reply", ParentID: &parent.ID})
+	_ = comments.Delete(ctx, parent.ID, userID)
+
+	pagination := &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)}
+
+	// parent with a live reply survives
+	if err := trash.Purge(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, total, _ := trash.GetComments(ctx, userID, pagination); total != 1 {
+		t.Fatalf("expected tombstone to stay in trash, got %d", total)
+	}
+
+	// once the thread is gone the whole branch is purged leaf first
+	_ = comments.Delete(ctx, reply.ID, userID)
+	_ = trash.Purge(ctx, time.Now().Add(time.Second))
+	_ = trash.Purge(ctx, time.Now().Add(time.Second))
+	if _, total, _ := trash.GetComments(ctx, userID, pagination); total != 0 {
+		t.Fatalf("expected branch to be purged, got %d", total)
+	}
+}
@@ -26,6 +26,7 @@ var (
 type UserService struct {
 	userRepo         repository.UserRepository
 	refreshTokenRepo repository.RefreshTokenRepository
+	eventRepo        repository.EventRepository
 	jwtManager       *auth.JWTManager
 	passwordManager  *auth.PasswordManager
 }
@@ -33,12 +34,14 @@ type UserService struct {
 func NewUserService(
 	userRepo repository.UserRepository,
 	refreshTokenRepo repository.RefreshTokenRepository,
+	eventRepo repository.EventRepository,
 	jwtManager *auth.JWTManager,
 	passwordManager *auth.PasswordManager,
 ) *UserService {
 	return &UserService{
 		userRepo:         userRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		eventRepo:        eventRepo,
 		jwtManager:       jwtManager,
 		passwordManager:  passwordManager,
 	}
@@ -91,6 +94,12 @@ func (s *UserService) Register(ctx context.Context, req *model.UserCreateRequest
 				return ErrDatabase
 			}
 
+			summary := &model.UserSummary{ID: user.ID, Username: user.Username}
+			if err := recordEvent(txCtx, s.eventRepo, model.AggregateUser, user.ID, model.EventUserRegistered, summary); err != nil {
+				logger.Error("failed to record registration of user_id=%d: %v", user.ID, err)
+				return ErrDatabase
+			}
+
 			tokenResp, err = s.createTokenPair(txCtx, user.ID)
 			return err
 		},
@@ -202,6 +211,19 @@ func (s *UserService) createTokenPair(ctx context.Context, userID int) (*model.T
 	}, nil
 }
 
+// PurgeExpiredRefreshTokens deletes the refresh tokens that can no longer be exchanged
+func (s *UserService) PurgeExpiredRefreshTokens(ctx context.Context) error {
+	deleted, err := s.refreshTokenRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		logger.Error("failed to delete expired refresh tokens: %v", err)
+		return ErrDatabase
+	}
+	if deleted > 0 {
+		logger.Info("expired refresh tokens purged: %d", deleted)
+	}
+	return nil
+}
+
 func (s *UserService) GetByID(ctx context.Context, id int) (*model.User, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -31,7 +31,7 @@ func setupUserServiceForTest(
 		SymbolsRequired:   false,
 	})
 
-	return NewUserService(userRepo, rtRepo, jwtMgr, passMgr)
+	return NewUserService(userRepo, rtRepo, repository.NewInMemoryEventRepo(), jwtMgr, passMgr)
 }
 
 func TestUserServiceRegister(t *testing.T) {
@@ -0,0 +1,425 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/settings"
+	"blog-api/pkg/webhook"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryNotDead  = errors.New("only dead deliveries can be retried")
+)
+
+// config
+type WebhookConfig struct {
+	DispatchIntervalSeconds int
+	BatchSize               int
+	Workers                 int
+	MaxAttempts             int
+	RetryBaseSeconds        int
+	RetryMaxMinutes         int
+	TimeoutSeconds          int
+	AllowPrivateNetworks    bool // lets the deliveries reach loopback and private addresses, for local development
+}
+
+func (c *WebhookConfig) Setup() []settings.EnvLoadable {
+	return []settings.EnvLoadable{
+		settings.Item[int]{Name: "WEBHOOKS_DISPATCH_INTERVAL_SECONDS", Default: 5, Field: &c.DispatchIntervalSeconds},
+		settings.Item[int]{Name: "WEBHOOKS_BATCH_SIZE", Default: 50, Field: &c.BatchSize},
+		settings.Item[int]{Name: "WEBHOOKS_WORKERS", Default: 4, Field: &c.Workers},
+		settings.Item[int]{Name: "WEBHOOKS_MAX_ATTEMPTS", Default: 8, Field: &c.MaxAttempts},
+		settings.Item[int]{Name: "WEBHOOKS_RETRY_BASE_SECONDS", Default: 30, Field: &c.RetryBaseSeconds},
+		settings.Item[int]{Name: "WEBHOOKS_RETRY_MAX_MINUTES", Default: 360, Field: &c.RetryMaxMinutes},
+		settings.Item[int]{Name: "WEBHOOKS_TIMEOUT_SECONDS", Default: 10, Field: &c.TimeoutSeconds},
+		settings.Item[bool]{Name: "WEBHOOKS_ALLOW_PRIVATE_NETWORKS", Default: false, Field: &c.AllowPrivateNetworks},
+	}
+}
+
+func (c *WebhookConfig) Timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed ones
+func (c *WebhookConfig) Backoff(attempts int) time.Duration {
+	maxDelay := time.Duration(c.RetryMaxMinutes) * time.Minute
+	delay := time.Duration(c.RetryBaseSeconds) * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return min(delay, maxDelay)
+}
+
+// StartWebhookDispatcher launches a background loop that sends the due webhook deliveries
+func StartWebhookDispatcher(ctx context.Context, cfg *WebhookConfig, svc *WebhookService) {
+	interval := time.Duration(cfg.DispatchIntervalSeconds) * time.Second
+	logger.Info("webhook dispatcher started, interval=%s, workers=%d", interval, cfg.Workers)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+			if _, err := svc.Dispatch(ctx); err != nil {
+				logger.Error("webhook dispatcher failed: %v", err)
+			}
+		}
+	}
+}
+
+// WebhookService registers webhooks and delivers events to them through the deliveries outbox, a nil service drops events
+type WebhookService struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	userRepo     repository.UserRepository
+	config       *WebhookConfig
+	client       *http.Client
+}
+
+func NewWebhookService(
+	webhookRepo repository.WebhookRepository,
+	deliveryRepo repository.WebhookDeliveryRepository,
+	userRepo repository.UserRepository,
+	config *WebhookConfig,
+) *WebhookService {
+	return &WebhookService{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		userRepo:     userRepo,
+		config:       config,
+		client:       webhook.NewClient(config.Timeout(), config.AllowPrivateNetworks),
+	}
+}
+
+// Register creates a webhook of the actor, the signing secret is returned only here
+func (s *WebhookService) Register(ctx context.Context, actorID int, req *model.WebhookCreateRequest) (*model.Webhook, error) {
+	// only admins watch the content of all users
+	if req.AllUsers {
+		if err := ensureAdmin(ctx, s.userRepo, actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhook.NewSecret()
+		if err != nil {
+			logger.Error("failed to generate webhook secret: %v", err)
+			return nil, err
+		}
+		secret = generated
+	}
+
+	hook := &model.Webhook{
+		OwnerID:  actorID,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   req.Events,
+		AllUsers: req.AllUsers,
+		Active:   true,
+	}
+	if err := s.webhookRepo.Create(ctx, hook); err != nil {
+		logger.Error("failed to create webhook for user_id=%d: %v", actorID, err)
+		return nil, ErrDatabase
+	}
+
+	logger.Info("user_id=%d registered webhook_id=%d for %v", actorID, hook.ID, hook.Events)
+	return hook, nil
+}
+
+// List returns the webhooks of the actor without their secrets, newest first
+func (s *WebhookService) List(
+	ctx context.Context,
+	actorID int,
+	pagination *model.PaginationParams,
+) ([]*model.Webhook, int, error) {
+	hooks, err := s.webhookRepo.GetByOwnerID(ctx, actorID, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch webhooks of user_id=%d: %v", actorID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	total, err := s.webhookRepo.GetCountByOwnerID(ctx, actorID)
+	if err != nil {
+		logger.Error("failed to count webhooks of user_id=%d: %v", actorID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	return hooks, total, nil
+}
+
+// Delete removes the webhook of the actor together with its deliveries
+func (s *WebhookService) Delete(ctx context.Context, actorID, webhookID int) error {
+	if _, err := s.getOwned(ctx, actorID, webhookID); err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.Delete(ctx, webhookID); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return ErrWebhookNotFound
+		}
+		logger.Error("failed to delete webhook_id=%d: %v", webhookID, err)
+		return ErrDatabase
+	}
+
+	logger.Info("user_id=%d deleted webhook_id=%d", actorID, webhookID)
+	return nil
+}
+
+// GetDeliveries returns the delivery log of the webhook of the actor, newest first
+func (s *WebhookService) GetDeliveries(
+	ctx context.Context,
+	actorID, webhookID int,
+	params *model.WebhookDeliveryListParams,
+	pagination *model.PaginationParams,
+) ([]*model.WebhookDelivery, int, error) {
+	if _, err := s.getOwned(ctx, actorID, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	filter := &repository.WebhookDeliveryFilter{WebhookID: webhookID}
+	if params.Status != "" {
+		filter.Status = &params.Status
+	}
+
+	deliveries, err := s.deliveryRepo.GetDeliveries(ctx, filter, *pagination.Limit, *pagination.Offset)
+	if err != nil {
+		logger.Error("failed to fetch deliveries of webhook_id=%d: %v", webhookID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	total, err := s.deliveryRepo.GetDeliveriesCount(ctx, filter)
+	if err != nil {
+		logger.Error("failed to count deliveries of webhook_id=%d: %v", webhookID, err)
+		return nil, 0, ErrDatabase
+	}
+
+	return deliveries, total, nil
+}
+
+// Retry puts a dead delivery back into the outbox with a fresh attempts budget
+func (s *WebhookService) Retry(ctx context.Context, actorID, webhookID, deliveryID int) (*model.WebhookDelivery, error) {
+	if _, err := s.getOwned(ctx, actorID, webhookID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookDeliveryNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		logger.Error("failed to fetch webhook delivery_id=%d: %v", deliveryID, err)
+		return nil, ErrDatabase
+	}
+	if delivery.WebhookID != webhookID {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if delivery.Status != model.WebhookDeliveryDead {
+		return nil, ErrWebhookDeliveryNotDead
+	}
+
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.deliveryRepo.Update(ctx, delivery); err != nil {
+		logger.Error("failed to requeue webhook delivery_id=%d: %v", deliveryID, err)
+		return nil, ErrDatabase
+	}
+
+	logger.Info("user_id=%d requeued webhook delivery_id=%d", actorID, deliveryID)
+	return delivery, nil
+}
+
+/*
+Emit queues the event for every active webhook subscribed to it, either owned by the owner
+of the content or registered for all users. A failure is logged and does not affect the action
+that caused the event.
+*/
+func (s *WebhookService) Emit(ctx context.Context, event string, ownerID int, data any) {
+	if s == nil {
+		return
+	}
+
+	hooks, err := s.webhookRepo.GetSubscribed(ctx, event, ownerID)
+	if err != nil {
+		logger.Error("failed to fetch webhooks subscribed to %s of user_id=%d: %v", event, ownerID, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, hook := range hooks {
+		payload, err := json.Marshal(&model.WebhookPayload{
+			ID:        uuid.Must(uuid.NewV4()).String(),
+			Event:     event,
+			CreatedAt: now,
+			Data:      data,
+		})
+		if err != nil {
+			logger.Error("failed to encode %s payload: %v", event, err)
+			return
+		}
+
+		delivery := &model.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event,
+			Payload:       payload,
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+		if err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+			logger.Error("failed to queue %s for webhook_id=%d: %v", event, hook.ID, err)
+			continue
+		}
+		logger.Debug("queued %s for webhook_id=%d", event, hook.ID)
+	}
+}
+
+/*
+Dispatch claims a batch of due deliveries and sends them concurrently. The claim leases the
+deliveries so that parallel dispatchers skip them, a crashed dispatcher leaves them to be picked
+up again once the lease expires. Returns the number of processed deliveries.
+*/
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	lease := now.Add(2 * s.config.Timeout())
+
+	deliveries, err := s.deliveryRepo.Claim(ctx, now, lease, s.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	jobs := make(chan *model.WebhookDelivery)
+	var wg sync.WaitGroup
+	for range max(s.config.Workers, 1) {
+		wg.Go(func() {
+			for delivery := range jobs {
+				s.deliver(ctx, delivery)
+			}
+		})
+	}
+	for _, delivery := range deliveries {
+		jobs <- delivery
+	}
+	close(jobs)
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver makes a single attempt and records its outcome
+func (s *WebhookService) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	hook, err := s.webhookRepo.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrWebhookNotFound) {
+			logger.Error("failed to fetch webhook_id=%d: %v", delivery.WebhookID, err)
+			return
+		}
+		delivery.Status = model.WebhookDeliveryDead
+		delivery.LastError = "webhook was deleted"
+		s.saveDelivery(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	code, err := s.send(ctx, hook, delivery)
+	delivery.ResponseCode = code
+
+	if err == nil {
+		at := time.Now()
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &at
+		delivery.LastError = ""
+		logger.Info("delivered %s delivery_id=%d to webhook_id=%d", delivery.Event, delivery.ID, hook.ID)
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= s.config.MaxAttempts {
+			delivery.Status = model.WebhookDeliveryDead
+			logger.Warn("gave up on delivery_id=%d to webhook_id=%d after %d attempts: %v", delivery.ID, hook.ID, delivery.Attempts, err)
+		} else {
+			delivery.Status = model.WebhookDeliveryPending
+			delivery.NextAttemptAt = time.Now().Add(s.config.Backoff(delivery.Attempts))
+			logger.Info("delivery_id=%d to webhook_id=%d failed, retry at %s: %v", delivery.ID, hook.ID, delivery.NextAttemptAt, err)
+		}
+	}
+
+	s.saveDelivery(ctx, delivery)
+}
+
+// send posts the signed payload, any non 2xx response is a failure
+func (s *WebhookService) send(ctx context.Context, hook *model.Webhook, delivery *model.WebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, delivery.Event)
+	req.Header.Set(webhook.HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	code := resp.StatusCode
+	if code < 200 || code >= 300 {
+		return &code, fmt.Errorf("unexpected response status %d", code)
+	}
+	return &code, nil
+}
+
+func (s *WebhookService) saveDelivery(ctx context.Context, delivery *model.WebhookDelivery) {
+	if err := s.deliveryRepo.Update(ctx, delivery); err != nil {
+		logger.Error("failed to save webhook delivery_id=%d: %v", delivery.ID, err)
+	}
+}
+
+// getOwned fetches the webhook, webhooks of other users are reported as missing
+func (s *WebhookService) getOwned(ctx context.Context, actorID, webhookID int) (*model.Webhook, error) {
+	hook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		logger.Error("failed to fetch webhook_id=%d: %v", webhookID, err)
+		return nil, ErrDatabase
+	}
+	if hook.OwnerID != actorID {
+		logger.Info("user_id=%d tried to access webhook_id=%d of user_id=%d", actorID, webhookID, hook.OwnerID)
+		return nil, ErrWebhookNotFound
+	}
+	return hook, nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blog-api/internal/model"
+	"blog-api/internal/repository"
+	"blog-api/pkg/logging"
+	"blog-api/pkg/webhook"
+)
+
+func TestWebhookServiceDelivery(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	const secret = "0123456789abcdef"
+
+	var (
+		mu       sync.Mutex
+		events   []string
+		failing  bool
+		verified = true
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !webhook.Verify(secret, timestamp, body, r.Header.Get(webhook.HeaderSignature)) {
+			verified = false
+		}
+		var payload model.WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Event != r.Header.Get(webhook.HeaderEvent) {
+			verified = false
+		}
+		events = append(events, payload.Event)
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	deliveryRepo := repository.NewInMemoryWebhookDeliveryRepo()
+	cfg := &WebhookConfig{BatchSize: 10, Workers: 2, MaxAttempts: 3, RetryBaseSeconds: 30, RetryMaxMinutes: 60, TimeoutSeconds: 5, AllowPrivateNetworks: true}
+	webhooks := NewWebhookService(repository.NewInMemoryWebhookRepo(), deliveryRepo, userRepo, cfg)
+	eventRepo := repository.NewInMemoryEventRepo()
+	posts := NewPostService(postRepo, userRepo, eventRepo, nil, nil, webhooks, nil)
+	// the relay queues the deliveries
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("post_creations", posts.AnnounceCreated, model.EventPostCreated)
+	relay.Subscribe("post_publications", posts.AnnouncePublished, model.EventPostPublished)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	other := &model.User{Username: "other", Email: "other@example.com"}
+	for _, u := range []*model.User{author, other} {
+		userRepo.Create(ctx, u)
+	}
+
+	hook, err := webhooks.Register(ctx, author.ID, &model.WebhookCreateRequest{
+		URL:    server.URL,
+		Events: []string{model.WebhookPostCreated, model.WebhookPostPublished},
+		Secret: secret,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	deliveries := func(status string) []*model.WebhookDelivery {
+		list, _, err := webhooks.GetDeliveries(ctx, author.ID, hook.ID, &model.WebhookDeliveryListParams{Status: status}, &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return list
+	}
+
+	// a published post is both created and published, posts of other users are ignored
+	posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	posts.Create(ctx, other.ID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	relay.Relay(ctx)
+	if n, err := webhooks.Dispatch(ctx); err != nil || n != 2 {
+		t.Fatalf("expected 2 deliveries to be sent, got %d err=%v", n, err)
+	}
+	if !verified || len(events) != 2 {
+		t.Fatalf("expected 2 correctly signed events, got %v", events)
+	}
+	if delivered := deliveries(model.WebhookDeliveryDelivered); len(delivered) != 2 || delivered[0].DeliveredAt == nil {
+		t.Fatalf("expected 2 delivered deliveries, got %+v", delivered)
+	}
+
+	// failures are retried with a backoff
+	failing = true
+	posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Draft", Content: "Content", PublishAt: ptr(time.Now().Add(time.Hour))})
+	relay.Relay(ctx)
+	before := time.Now()
+	if n, _ := webhooks.Dispatch(ctx); n != 1 {
+		t.Fatalf("expected 1 delivery to be sent, got %d", n)
+	}
+	pending := deliveries(model.WebhookDeliveryPending)
+	if len(pending) != 1 || pending[0].Attempts != 1 || *pending[0].ResponseCode != http.StatusInternalServerError {
+		t.Fatalf("expected a failed delivery waiting for a retry, got %+v", pending)
+	}
+	if pending[0].NextAttemptAt.Before(before.Add(30 * time.Second)) {
+		t.Fatalf("expected the retry to be delayed, got %s", pending[0].NextAttemptAt)
+	}
+	if n, _ := webhooks.Dispatch(ctx); n != 0 {
+		t.Fatalf("expected the retry not to be due yet, got %d", n)
+	}
+
+	// the last attempt moves the delivery to the dead letters
+	cfg.MaxAttempts = 1
+	posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Draft", Content: "Content", PublishAt: ptr(time.Now().Add(time.Hour))})
+	relay.Relay(ctx)
+	webhooks.Dispatch(ctx)
+	dead := deliveries(model.WebhookDeliveryDead)
+	if len(dead) != 1 || dead[0].LastError == "" {
+		t.Fatalf("expected a dead delivery, got %+v", dead)
+	}
+
+	// dead deliveries can be retried by hand
+	failing = false
+	if _, err := webhooks.Retry(ctx, author.ID, hook.ID, dead[0].ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	webhooks.Dispatch(ctx)
+	if len(deliveries(model.WebhookDeliveryDead)) != 0 || len(deliveries(model.WebhookDeliveryDelivered)) != 3 {
+		t.Fatalf("expected the retried delivery to be delivered")
+	}
+}
+
+func TestWebhookServiceOutbox(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	postRepo := repository.NewInMemoryPostRepo()
+	userRepo := repository.NewInMemoryUserRepo()
+	commentRepo := repository.NewInMemoryCommentRepo()
+	commentRepo.LinkPosts(postRepo)
+	eventRepo := repository.NewInMemoryEventRepo()
+	cfg := &WebhookConfig{BatchSize: 10, Workers: 1, MaxAttempts: 3, RetryBaseSeconds: 30, RetryMaxMinutes: 60, TimeoutSeconds: 5}
+	webhooks := NewWebhookService(repository.NewInMemoryWebhookRepo(), repository.NewInMemoryWebhookDeliveryRepo(), userRepo, cfg)
+	posts := NewPostService(postRepo, userRepo, eventRepo, nil, nil, webhooks, nil)
+	comments := NewCommentService(commentRepo, repository.NewInMemoryCommentRevisionRepo(), postRepo, userRepo, eventRepo, &CommentConfig{MaxDepth: 5}, nil, nil, webhooks)
+
+	author := &model.User{Username: "author", Email: "author@example.com"}
+	reader := &model.User{Username: "reader", Email: "reader@example.com"}
+	for _, u := range []*model.User{author, reader} {
+		userRepo.Create(ctx, u)
+	}
+	hook, err := webhooks.Register(ctx, author.ID, &model.WebhookCreateRequest{
+		URL:    "https://example.com/hook",
+		Events: []string{model.WebhookPostDeleted, model.WebhookCommentCreated},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	pending := func() []string {
+		list, _, _ := webhooks.GetDeliveries(ctx, author.ID, hook.ID, &model.WebhookDeliveryListParams{Status: model.WebhookDeliveryPending}, &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)})
+		var events []string
+		for _, delivery := range list {
+			events = append(events, delivery.Event)
+		}
+		slices.Sort(events)
+		return events
+	}
+
+	post, _ := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+	draft, _ := posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Draft", Content: "Content"})
+	if _, err := comments.Create(ctx, reader.ID, post.ID, &model.CommentCreateRequest{Content: "Comment"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := posts.Delete(ctx, draft.ID, author.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// nothing is queued outside the outbox, a crash after the commit loses no delivery
+	if events := pending(); len(events) != 0 {
+		t.Fatalf("expected no deliveries before the relay, got %v", events)
+	}
+	relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+	relay.Subscribe("post_deletions", posts.AnnounceDeleted, model.EventPostDeleted)
+	relay.Subscribe("comment_announcements", comments.AnnounceCreated, model.EventCommentCreated, model.EventCommentApproved)
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if events := pending(); !slices.Equal(events, []string{model.WebhookCommentCreated, model.WebhookPostDeleted}) {
+		t.Fatalf("expected the comment and the deletion to be queued, got %v", events)
+	}
+}
+
+func TestWebhookServiceNetworkGuard(t *testing.T) {
+	ctx := context.Background()
+	logging.Init(&logging.LoggerConfig{Level: "DEBUG"})
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/hook" {
+			http.Redirect(w, r, "/internal", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		url          string
+		allowPrivate bool
+		wantHits     int32
+		wantCode     *int
+		wantError    string
+	}{
+		{"loopback refused", server.URL + "/hook", false, 0, nil, "webhook address is not public"},
+		{"shared address space refused", "http://100.64.0.1/hook", false, 0, nil, "webhook address is not public"},
+		{"benchmarking range refused", "http://198.18.0.1/hook", false, 0, nil, "webhook address is not public"},
+		{"redirect not followed", server.URL + "/hook", true, 1, ptr(http.StatusFound), "unexpected response status 302"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			userRepo := repository.NewInMemoryUserRepo()
+			cfg := &WebhookConfig{BatchSize: 10, Workers: 1, MaxAttempts: 3, RetryBaseSeconds: 30, RetryMaxMinutes: 60, TimeoutSeconds: 5, AllowPrivateNetworks: tt.allowPrivate}
+			webhooks := NewWebhookService(repository.NewInMemoryWebhookRepo(), repository.NewInMemoryWebhookDeliveryRepo(), userRepo, cfg)
+			eventRepo := repository.NewInMemoryEventRepo()
+			posts := NewPostService(repository.NewInMemoryPostRepo(), userRepo, eventRepo, nil, nil, webhooks, nil)
+			relay := NewEventRelay(eventRepo, &EventsConfig{BatchSize: 100, Workers: 1, MaxAttempts: 3, HandlerTimeoutSeconds: 1})
+			relay.Subscribe("post_creations", posts.AnnounceCreated, model.EventPostCreated)
+
+			author := &model.User{Username: "author", Email: "author@example.com"}
+			userRepo.Create(ctx, author)
+			hook, err := webhooks.Register(ctx, author.ID, &model.WebhookCreateRequest{
+				URL:    tt.url,
+				Events: []string{model.WebhookPostCreated},
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			posts.Create(ctx, author.ID, &model.PostCreateRequest{Title: "Post", Content: "Content"})
+			relay.Relay(ctx)
+			webhooks.Dispatch(ctx)
+
+			pending, _, _ := webhooks.GetDeliveries(ctx, author.ID, hook.ID, &model.WebhookDeliveryListParams{Status: model.WebhookDeliveryPending}, &model.PaginationParams{Limit: ptr(10), Offset: ptr(0)})
+			if len(pending) != 1 || !strings.Contains(pending[0].LastError, tt.wantError) {
+				t.Fatalf("expected a failed delivery with %q, got %+v", tt.wantError, pending)
+			}
+			if code := pending[0].ResponseCode; (code == nil) != (tt.wantCode == nil) || (code != nil && *code != *tt.wantCode) {
+				t.Fatalf("expected response code %v, got %v", tt.wantCode, code)
+			}
+			if hits.Load() != tt.wantHits {
+				t.Fatalf("expected %d requests to the server, got %d", tt.wantHits, hits.Load())
+			}
+		})
+	}
+}
+
+func TestWebhookConfigBackoff(t *testing.T) {
+	cfg := &WebhookConfig{RetryBaseSeconds: 30, RetryMaxMinutes: 5}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute},
+		{20, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := cfg.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+
+-- existing content is plain text: escape it the same way markup.renderPlain does
+CREATE OR REPLACE FUNCTION render_plain_content(content TEXT) RETURNS TEXT AS $$
+    SELECT '<p>' || replace(
+        replace(replace(replace(replace(replace(replace(content, E'\r\n', E'\n'),
+            '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'),
+        E'\n', E'<br>\n'
+    ) || '</p>'
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE posts SET content_html = render_plain_content(content) WHERE content_html = '';
+UPDATE comments SET content_html = render_plain_content(content) WHERE content_html = '';
+
+DROP FUNCTION render_plain_content(TEXT);
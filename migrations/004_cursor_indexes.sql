@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_author_created_at_id ON posts(author_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_comments_post_created_at_id ON comments(post_id, created_at, id);
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_posts_updated_at ON posts(updated_at);
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts(publish_at);
+CREATE INDEX IF NOT EXISTS idx_posts_title ON posts(title);
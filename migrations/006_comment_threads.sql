@@ -0,0 +1,7 @@
+-- replies keep a reference to their parent, a removed parent stays soft deleted as a tombstone
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_id INT NULL REFERENCES comments(id);
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS root_id INT NULL REFERENCES comments(id);
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_root_id ON comments(root_id);
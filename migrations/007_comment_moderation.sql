@@ -0,0 +1,8 @@
+-- roles are assigned manually: UPDATE users SET role = 'moderator' WHERE id = ...
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+-- existing comments stay visible, new ones may wait in the queue as pending
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'approved';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_premoderation BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_comments_status ON comments(status, created_at, id);
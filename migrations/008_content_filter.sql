@@ -0,0 +1,11 @@
+-- posts put on hold by the content filter stay unpublished until a moderator releases them
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_posts_held ON posts(held) WHERE held;
+
+-- moderation decisions the spam classifier is trained on
+CREATE TABLE IF NOT EXISTS spam_samples (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    content TEXT NOT NULL,
+    spam BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
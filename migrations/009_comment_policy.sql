@@ -0,0 +1,4 @@
+-- per post comment controls, a NULL close period falls back to COMMENTS_AUTO_CLOSE_DAYS
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_policy VARCHAR(16) NOT NULL DEFAULT 'open';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_min_account_age_days INT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_close_after_days INT NULL;
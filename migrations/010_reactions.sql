@@ -0,0 +1,20 @@
+-- one reaction per user and target, the counts are denormalized onto posts and comments
+CREATE TABLE IF NOT EXISTS reactions (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    target_type VARCHAR(16) NOT NULL,
+    target_id INT NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_target_user ON reactions(target_type, target_id, user_id);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reactions JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reaction_count INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reactions JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reaction_count INT NOT NULL DEFAULT 0;
+
+-- sort=popularity
+CREATE INDEX IF NOT EXISTS idx_posts_reaction_count ON posts(reaction_count DESC, id DESC) WHERE deleted_at IS NULL;
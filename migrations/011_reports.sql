@@ -0,0 +1,30 @@
+-- reader complaints about posts and comments, one per reporter and target
+CREATE TABLE IF NOT EXISTS reports (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    target_type VARCHAR(16) NOT NULL,
+    target_id INT NOT NULL,
+    reporter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(32) NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    resolved_by INT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_target_reporter ON reports(target_type, target_id, reporter_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status, created_at, id);
+
+-- every moderation decision, moderator_id is NULL for automatic ones
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    moderator_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(16) NOT NULL,
+    target_type VARCHAR(16) NOT NULL,
+    target_id INT NOT NULL,
+    report_id INT REFERENCES reports(id) ON DELETE SET NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_target ON moderation_log(target_type, target_id, id DESC);
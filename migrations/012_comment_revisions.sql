@@ -0,0 +1,14 @@
+-- replaced versions of comments, the comment itself keeps the latest one
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    comment_id INT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    content TEXT NOT NULL,
+    content_format VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_comment_revisions_comment ON comment_revisions(comment_id, revision);
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edit_count INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
@@ -0,0 +1,11 @@
+-- kinds of notifications each user receives, users without a row receive all of them
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    comment BOOLEAN NOT NULL DEFAULT TRUE,
+    reply BOOLEAN NOT NULL DEFAULT TRUE,
+    mention BOOLEAN NOT NULL DEFAULT TRUE,
+    post_published BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id, id DESC) WHERE read_at IS NULL;
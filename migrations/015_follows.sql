@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id, created_at DESC);
+
+-- feed reads the newest published posts of each followed author
+CREATE INDEX IF NOT EXISTS idx_posts_feed ON posts(author_id, created_at DESC, id DESC)
+    WHERE published AND deleted_at IS NULL;
@@ -0,0 +1,16 @@
+-- transactional outbox of domain events, written in the transaction of the change
+CREATE TABLE IF NOT EXISTS events (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    aggregate_type VARCHAR(20) NOT NULL,
+    aggregate_id INT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP
+);
+
+-- relay reads the oldest pending events
+CREATE INDEX IF NOT EXISTS idx_events_pending ON events(id) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_events_aggregate ON events(aggregate_type, aggregate_id, id);
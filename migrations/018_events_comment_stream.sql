@@ -0,0 +1,3 @@
+-- comment stream replays the missed comment events of one post
+CREATE INDEX IF NOT EXISTS idx_events_comment_post ON events(((payload->>'post_id')::int), id)
+    WHERE aggregate_type = 'comment';
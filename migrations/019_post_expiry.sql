@@ -0,0 +1,5 @@
+-- posts are taken down at unpublish_at and stay visible to their authors among the delayed posts
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMP NULL;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS expired BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_posts_unpublish_at ON posts(unpublish_at)
+    WHERE NOT expired AND unpublish_at IS NOT NULL AND deleted_at IS NULL;
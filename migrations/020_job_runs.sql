@@ -0,0 +1,16 @@
+-- runs of background jobs, an occurrence of a schedule is claimed by a single replica
+CREATE TABLE IF NOT EXISTS job_runs (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    job VARCHAR(100) NOT NULL,
+    trigger VARCHAR(20) NOT NULL,
+    scheduled_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (job, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_job ON job_runs(job, id DESC);
@@ -0,0 +1,84 @@
+package broker
+
+import (
+	"context"
+	"path"
+	"sync"
+)
+
+/*
+Package broker fans messages out to every subscribed process.
+
+Usage:
+
+	b := broker.NewRedisBroker(addr, password, db)
+	messages, err := b.Subscribe(ctx, "comments:post:*")
+	...
+	b.Publish(ctx, "comments:post:1", payload)
+
+Delivery is at-most-once: subscribers that are not connected when a message is published miss it.
+*/
+
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+type Broker interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe delivers the messages of the channels matching the glob pattern until ctx is done
+	Subscribe(ctx context.Context, pattern string) (<-chan *Message, error)
+}
+
+// MemoryBroker delivers messages inside the process, for tests and single instance setups
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[*memorySubscriber]struct{}
+}
+
+type memorySubscriber struct {
+	pattern  string
+	messages chan *Message
+	done     <-chan struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: map[*memorySubscriber]struct{}{}}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if ok, _ := path.Match(sub.pattern, channel); !ok {
+			continue
+		}
+		select {
+		case sub.messages <- &Message{Channel: channel, Payload: payload}:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, pattern string) (<-chan *Message, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	sub := &memorySubscriber{pattern: pattern, messages: make(chan *Message, 64), done: ctx.Done()}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+		close(sub.messages)
+	}()
+	return sub.messages, nil
+}
@@ -0,0 +1,87 @@
+package broker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	postgresMinReconnect = time.Second
+	postgresMaxReconnect = time.Minute
+	postgresPingInterval = 90 * time.Second
+)
+
+// PostgresBroker fans messages out through Postgres LISTEN/NOTIFY, each subscription holds its own connection.
+// Postgres channels are plain names: Subscribe accepts an exact channel and no glob pattern,
+// payloads are limited to 8000 bytes.
+type PostgresBroker struct {
+	dsn string
+	db  *sql.DB
+}
+
+func NewPostgresBroker(dsn string) *PostgresBroker {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(fmt.Errorf("failed to open the Postgres broker connection: %w", err))
+	}
+	db.SetMaxOpenConns(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		panic(fmt.Errorf("failed to connect the Postgres broker: %w", err))
+	}
+	return &PostgresBroker{dsn: dsn, db: db}
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if _, err := b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", channel, err)
+	}
+	return nil
+}
+
+func (b *PostgresBroker) Subscribe(ctx context.Context, channel string) (<-chan *Message, error) {
+	if strings.ContainsAny(channel, `*?[\`) {
+		return nil, fmt.Errorf("postgres channel %q can not be a pattern", channel)
+	}
+
+	listener := pq.NewListener(b.dsn, postgresMinReconnect, postgresMaxReconnect, nil)
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen to %s: %w", channel, err)
+	}
+
+	messages := make(chan *Message, 64)
+	go func() {
+		defer close(messages)
+		defer listener.Close()
+		// the listener reconnects on its own, pings reveal a connection that died silently
+		ping := time.NewTicker(postgresPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				listener.Ping()
+			case n := <-listener.Notify:
+				// nil follows a reconnect, the notifications sent meanwhile are lost
+				if n == nil {
+					continue
+				}
+				select {
+				case messages <- &Message{Channel: n.Channel, Payload: []byte(n.Extra)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
@@ -0,0 +1,69 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker fans messages out to every replica through Redis pub/sub
+type RedisBroker struct {
+	client *redis.Client
+}
+
+func NewRedisBroker(addr, password string, db int) *RedisBroker {
+	client := redis.NewClient(
+		&redis.Options{
+			Addr:     addr,
+			Password: password,
+			DB:       db,
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		panic(fmt.Errorf("failed to connect to Redis at %s: %w", addr, err))
+	}
+	return &RedisBroker{client: client}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := b.client.Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+	return nil
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, pattern string) (<-chan *Message, error) {
+	pubsub := b.client.PSubscribe(ctx, pattern)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", pattern, err)
+	}
+
+	messages := make(chan *Message, 64)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- &Message{Channel: msg.Channel, Payload: []byte(msg.Payload)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
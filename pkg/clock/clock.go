@@ -0,0 +1,214 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Package clock abstracts the time for the background loops.
+
+Real is backed by the time package. Fake stands still until the tests move it with Advance,
+firing the timers and tickers that come due:
+
+	fake := clock.NewFake(time.Now())
+	fake.BlockUntil(2) // the loop under test has armed its timer and ticker
+	fake.Advance(time.Minute)
+
+Timers follow the Go 1.23 semantics: Reset and Stop drop a fire that has not been received.
+*/
+
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real returns the clock of the time package
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// Fake is a manually advanced clock for tests
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters map[*fakeWaiter]struct{}
+	// closed and replaced whenever a timer or ticker is armed
+	armed chan struct{}
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		now:     now,
+		waiters: map[*fakeWaiter]struct{}{},
+		armed:   make(chan struct{}),
+	}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1)}
+	w.arm(d)
+	return &fakeTimer{w}
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1), period: d}
+	w.arm(d)
+	return &fakeTicker{w}
+}
+
+// Advance moves the time forward and fires the timers and tickers due by then, a ticker fires once however many periods passed
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for w := range f.waiters {
+		if w.at.After(f.now) {
+			continue
+		}
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		if w.period > 0 {
+			for !w.at.After(f.now) {
+				w.at = w.at.Add(w.period)
+			}
+		} else {
+			delete(f.waiters, w)
+		}
+	}
+}
+
+// BlockUntil waits until at least n timers and tickers are armed
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		armed, count := f.armed, len(f.waiters)
+		f.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-armed
+	}
+}
+
+type fakeWaiter struct {
+	clock  *Fake
+	c      chan time.Time
+	at     time.Time
+	period time.Duration // tickers only
+}
+
+// arm schedules the next fire and drops the pending one, reports whether the waiter was armed before
+func (w *fakeWaiter) arm(d time.Duration) bool {
+	f := w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, active := f.waiters[w]
+	w.drain()
+	w.at = f.now.Add(d)
+	// like a real one, a timer reset to a past time fires at once
+	if w.period == 0 && !w.at.After(f.now) {
+		w.c <- f.now
+		delete(f.waiters, w)
+		return active
+	}
+	f.waiters[w] = struct{}{}
+	close(f.armed)
+	f.armed = make(chan struct{})
+	return active
+}
+
+func (w *fakeWaiter) stop() bool {
+	f := w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, active := f.waiters[w]
+	w.drain()
+	delete(f.waiters, w)
+	return active
+}
+
+func (w *fakeWaiter) drain() {
+	select {
+	case <-w.c:
+	default:
+	}
+}
+
+type fakeTimer struct {
+	*fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	return t.arm(d)
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.stop()
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.stop()
+}
@@ -0,0 +1,87 @@
+package contentfilter
+
+import (
+	"math"
+	"sync"
+	"unicode/utf8"
+)
+
+// token length bounds, longer strings are usually hashes or urls
+const (
+	minTokenLength = 2
+	maxTokenLength = 32
+)
+
+// Bayes is a naive Bayes spam classifier trained online from moderation decisions
+type Bayes struct {
+	mu     sync.RWMutex
+	docs   [2]int
+	tokens [2]map[string]int
+}
+
+const (
+	classHam = iota
+	classSpam
+)
+
+func NewBayes() *Bayes {
+	return &Bayes{
+		tokens: [2]map[string]int{make(map[string]int), make(map[string]int)},
+	}
+}
+
+// Train counts each distinct token of the text once for the given class
+func (b *Bayes) Train(text string, spam bool) {
+	class := classHam
+	if spam {
+		class = classSpam
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.docs[class]++
+	for token := range tokenSet(text) {
+		b.tokens[class][token]++
+	}
+}
+
+// Samples reports how many spam and ham texts the classifier has seen
+func (b *Bayes) Samples() (spam, ham int) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.docs[classSpam], b.docs[classHam]
+}
+
+// SpamProbability returns P(spam | text) with Laplace smoothing, 0.5 when nothing is known
+func (b *Bayes) SpamProbability(text string) float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	total := b.docs[classSpam] + b.docs[classHam]
+	if b.docs[classSpam] == 0 || b.docs[classHam] == 0 {
+		return 0.5
+	}
+
+	var score [2]float64
+	for class := range score {
+		score[class] = math.Log(float64(b.docs[class]) / float64(total))
+	}
+	for token := range tokenSet(text) {
+		for class := range score {
+			p := float64(b.tokens[class][token]+1) / float64(b.docs[class]+2)
+			score[class] += math.Log(p)
+		}
+	}
+
+	return 1 / (1 + math.Exp(score[classHam]-score[classSpam]))
+}
+
+func tokenSet(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, w := range words(text) {
+		if n := utf8.RuneCountInString(w); n >= minTokenLength && n <= maxTokenLength {
+			set[w] = struct{}{}
+		}
+	}
+	return set
+}
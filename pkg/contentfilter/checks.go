@@ -0,0 +1,193 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WordList rejects content containing banned words or matching banned patterns
+type WordList struct {
+	words    map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+func NewWordList(words []string, patterns []string) (*WordList, error) {
+	list := &WordList{words: make(map[string]struct{}, len(words))}
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			list.words[w] = struct{}{}
+		}
+	}
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banned pattern %q: %w", p, err)
+		}
+		list.patterns = append(list.patterns, re)
+	}
+	return list, nil
+}
+
+func (l *WordList) Name() string { return "word_list" }
+
+func (l *WordList) Check(ctx context.Context, item *Item) (*Result, error) {
+	var reasons []string
+	seen := make(map[string]bool)
+	for _, w := range words(item.Text) {
+		if _, banned := l.words[w]; banned && !seen[w] {
+			seen[w] = true
+			reasons = append(reasons, fmt.Sprintf("contains banned word %q", w))
+		}
+	}
+	for _, re := range l.patterns {
+		if re.MatchString(item.Text) {
+			reasons = append(reasons, fmt.Sprintf("matches banned pattern %q", re.String()))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return allow(), nil
+	}
+	return &Result{Verdict: Reject, Reasons: reasons}, nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s"'<>]+`)
+
+// LinkLimit holds content with more than Max links, zero disables the check
+type LinkLimit struct {
+	Max int
+}
+
+func (l LinkLimit) Name() string { return "link_limit" }
+
+func (l LinkLimit) Check(ctx context.Context, item *Item) (*Result, error) {
+	if l.Max <= 0 {
+		return allow(), nil
+	}
+	if n := len(linkPattern.FindAllStringIndex(item.Text, -1)); n > l.Max {
+		return &Result{
+			Verdict: Hold,
+			Reasons: []string{fmt.Sprintf("contains %d links, at most %d allowed", n, l.Max)},
+		}, nil
+	}
+	return allow(), nil
+}
+
+// RepeatLimit holds content where a character repeats more than Max times in a row, zero disables the check
+type RepeatLimit struct {
+	Max int
+}
+
+func (l RepeatLimit) Name() string { return "repeat_limit" }
+
+func (l RepeatLimit) Check(ctx context.Context, item *Item) (*Result, error) {
+	if l.Max <= 0 {
+		return allow(), nil
+	}
+
+	var (
+		prev    rune
+		run     int
+		longest int
+	)
+	for _, r := range item.Text {
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		longest = max(longest, run)
+	}
+
+	if longest > l.Max {
+		return &Result{
+			Verdict: Hold,
+			Reasons: []string{fmt.Sprintf("repeats a character %d times in a row, at most %d allowed", longest, l.Max)},
+		}, nil
+	}
+	return allow(), nil
+}
+
+// Sample is a previously saved item of the same author
+type Sample struct {
+	ID   int
+	Text string
+}
+
+// History provides the most recent items of the author of the same kind
+type History interface {
+	Recent(ctx context.Context, kind Kind, authorID int, limit int) ([]Sample, error)
+}
+
+// duplicateMinLength skips short replies like "thanks!" that are legitimately repeated
+const duplicateMinLength = 20
+
+// Duplicate rejects content equal to one of the last Window items of the author, ignoring case, spacing and punctuation
+type Duplicate struct {
+	History History
+	Window  int
+}
+
+func (d Duplicate) Name() string { return "duplicate" }
+
+func (d Duplicate) Check(ctx context.Context, item *Item) (*Result, error) {
+	if d.Window <= 0 {
+		return allow(), nil
+	}
+
+	text := normalize(item.Text)
+	if len(text) < duplicateMinLength {
+		return allow(), nil
+	}
+
+	samples, err := d.History.Recent(ctx, item.Kind, item.AuthorID, d.Window)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range samples {
+		if s.ID != item.ID && normalize(s.Text) == text {
+			return &Result{
+				Verdict: Reject,
+				Reasons: []string{fmt.Sprintf("duplicates your recent %s id=%d", item.Kind, s.ID)},
+			}, nil
+		}
+	}
+	return allow(), nil
+}
+
+func normalize(text string) string {
+	return strings.Join(words(text), " ")
+}
+
+// SpamFilter scores content with the classifier, probabilities at or above the thresholds hold or reject it
+type SpamFilter struct {
+	Classifier *Bayes
+	Hold       float64
+	Reject     float64
+	MinSamples int // per class, an undertrained classifier allows everything
+}
+
+func (f SpamFilter) Name() string { return "spam_classifier" }
+
+func (f SpamFilter) Check(ctx context.Context, item *Item) (*Result, error) {
+	spam, ham := f.Classifier.Samples()
+	if spam < f.MinSamples || ham < f.MinSamples {
+		return allow(), nil
+	}
+
+	p := f.Classifier.SpamProbability(item.Text)
+	reason := fmt.Sprintf("looks like spam (score %.2f)", p)
+	switch {
+	case p >= f.Reject:
+		return &Result{Verdict: Reject, Reasons: []string{reason}}, nil
+	case p >= f.Hold:
+		return &Result{Verdict: Hold, Reasons: []string{reason}}, nil
+	default:
+		return allow(), nil
+	}
+}
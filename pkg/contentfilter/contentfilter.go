@@ -0,0 +1,127 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+/*
+Package contentfilter runs user supplied content through a chain of checks before it is saved.
+
+Usage:
+
+	words, err := contentfilter.NewWordList([]string{"casino"}, []string{`(?i)free\s+money`})
+	pipeline := contentfilter.NewPipeline(words, contentfilter.LinkLimit{Max: 5})
+	decision, err := pipeline.Run(ctx, &contentfilter.Item{Kind: contentfilter.KindComment, Text: text})
+
+Every check returns its own verdict with the reasons, the strictest verdict wins.
+A nil pipeline allows everything.
+*/
+
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Hold
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Allow:
+		return "allow"
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	default:
+		return fmt.Sprintf("verdict(%d)", int(v))
+	}
+}
+
+type Kind string
+
+const (
+	KindPost    Kind = "post"
+	KindComment Kind = "comment"
+)
+
+// Item is the content under check, ID is zero for new content
+type Item struct {
+	Kind     Kind
+	ID       int
+	AuthorID int
+	Text     string
+}
+
+type Result struct {
+	Check   string
+	Verdict Verdict
+	Reasons []string
+}
+
+// Checker inspects a single aspect of the content
+type Checker interface {
+	Name() string
+	Check(ctx context.Context, item *Item) (*Result, error)
+}
+
+// Decision is the outcome of the whole pipeline
+type Decision struct {
+	Verdict Verdict
+	Results []*Result
+}
+
+// Reasons lists the reasons of every check that did not allow the content
+func (d *Decision) Reasons() []string {
+	var reasons []string
+	for _, r := range d.Results {
+		if r.Verdict != Allow {
+			reasons = append(reasons, r.Reasons...)
+		}
+	}
+	return reasons
+}
+
+type Pipeline struct {
+	checkers []Checker
+}
+
+func NewPipeline(checkers ...Checker) *Pipeline {
+	return &Pipeline{checkers: checkers}
+}
+
+// Run applies every check in order, the first failing check aborts the run
+func (p *Pipeline) Run(ctx context.Context, item *Item) (*Decision, error) {
+	decision := &Decision{Verdict: Allow}
+	if p == nil {
+		return decision, nil
+	}
+
+	for _, checker := range p.checkers {
+		result, err := checker.Check(ctx, item)
+		if err != nil {
+			return nil, fmt.Errorf("%s check failed: %w", checker.Name(), err)
+		}
+		result.Check = checker.Name()
+		decision.Results = append(decision.Results, result)
+		if result.Verdict > decision.Verdict {
+			decision.Verdict = result.Verdict
+		}
+	}
+
+	return decision, nil
+}
+
+func allow() *Result {
+	return &Result{Verdict: Allow}
+}
+
+// words splits the text into lowercase words of letters and digits
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
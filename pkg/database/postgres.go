@@ -43,6 +43,24 @@ func (c *DatabaseConfig) Setup() []settings.EnvLoadable {
 	}
 }
 
+// DSN is the connection string of the database
+func (c *DatabaseConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host,
+		c.Port,
+		c.User,
+		c.Password,
+		c.DBName,
+		func() string {
+			if c.SSLMode {
+				return "require"
+			}
+			return "disable"
+		}(),
+	)
+}
+
 // Manager
 type DatabaseManager struct {
 	connection *sql.DB
@@ -50,23 +68,7 @@ type DatabaseManager struct {
 }
 
 func NewDatabaseManager(config *DatabaseConfig) (*DatabaseManager, error) {
-	conn, err := sql.Open(
-		"postgres",
-		fmt.Sprintf(
-			"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			config.Host,
-			config.Port,
-			config.User,
-			config.Password,
-			config.DBName,
-			func() string {
-				if config.SSLMode {
-					return "require"
-				}
-				return "disable"
-			}(),
-		),
-	)
+	conn, err := sql.Open("postgres", config.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed create db connection: %w", err)
 	}
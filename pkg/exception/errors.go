@@ -19,11 +19,13 @@ const (
 	RequestBodyTooBig = 21
 
 	// Access
-	AuthForbidden = 30
-	UserBlocked   = 31
+	AuthForbidden  = 30
+	UserBlocked    = 31
+	CommentsLocked = 32
 
 	// Validation
 	InvalidBodyStructure = 40
+	ContentRejected      = 41
 
 	// Resource
 	NotExist     = 50
@@ -95,6 +97,10 @@ func BadRequestError(msg string) *ApiError {
 	return NewApiError(http.StatusBadRequest, InvalidBodyStructure, msg)
 }
 
+func ContentRejectedError(msg string) *ApiError {
+	return NewApiError(http.StatusUnprocessableEntity, ContentRejected, msg)
+}
+
 func UnauthorizedError(msg string) *ApiError {
 	return NewApiError(http.StatusUnauthorized, AuthTokenInvalid, msg)
 }
@@ -103,6 +109,10 @@ func ForbiddenError(msg string) *ApiError {
 	return NewApiError(http.StatusForbidden, AuthForbidden, msg)
 }
 
+func CommentsLockedError(msg string) *ApiError {
+	return NewApiError(http.StatusForbidden, CommentsLocked, msg)
+}
+
 func NotFoundError(msg string) *ApiError {
 	return NewApiError(http.StatusNotFound, NotExist, msg)
 }
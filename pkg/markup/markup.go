@@ -0,0 +1,121 @@
+package markup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+/*
+Package markup renders user supplied content into HTML that is safe to embed.
+
+Usage:
+
+	renderer := markup.NewPostRenderer()
+	contentHTML, err := renderer.Render(markup.FormatMarkdown, "**hello**")
+
+Every format ends up in the sanitizer, so the allow-list of the renderer
+is the only thing that decides what markup reaches the clients.
+*/
+
+type Format string
+
+const (
+	FormatPlain    Format = "plain"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported content format")
+
+var codeLanguageClass = regexp.MustCompile(`^language-[a-zA-Z0-9_+\-]+$`)
+
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+}
+
+// NewPostRenderer allows the full article subset: headings, lists, tables, images, code and links
+func NewPostRenderer() *Renderer {
+	policy := commonPolicy()
+	policy.AllowElements("h1", "h2", "h3", "h4", "h5", "h6", "hr", "u", "sub", "sup")
+	policy.AllowImages()
+	policy.AllowTables()
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|right|center)$`)).OnElements("th", "td")
+
+	return &Renderer{
+		markdown: goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		policy:   policy,
+	}
+}
+
+// NewCommentRenderer allows inline formatting, quotes, lists, code and links only
+func NewCommentRenderer() *Renderer {
+	return &Renderer{
+		markdown: goldmark.New(goldmark.WithExtensions(extension.Strikethrough, extension.Linkify)),
+		policy:   commonPolicy(),
+	}
+}
+
+func commonPolicy() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements(
+		"p", "br", "strong", "b", "em", "i", "s", "del",
+		"blockquote", "ul", "ol", "li", "code", "pre",
+	)
+	policy.AllowAttrs("class").Matching(codeLanguageClass).OnElements("code")
+	policy.AllowAttrs("href").OnElements("a")
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.RequireParseableURLs(true)
+	policy.RequireNoFollowOnLinks(true)
+	policy.RequireNoReferrerOnLinks(true)
+	return policy
+}
+
+// Render converts content of the given format to sanitized HTML
+func (r *Renderer) Render(format Format, content string) (string, error) {
+	switch format {
+	case FormatPlain, "":
+		return renderPlain(content), nil
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := r.markdown.Convert([]byte(content), &buf); err != nil {
+			return "", fmt.Errorf("failed to render markdown: %w", err)
+		}
+		return strings.TrimSpace(r.policy.Sanitize(buf.String())), nil
+	case FormatHTML:
+		return strings.TrimSpace(r.policy.Sanitize(content)), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// keep in sync with the backfill in migrations/003_content_format.sql
+func renderPlain(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return "<p>" + strings.ReplaceAll(html.EscapeString(content), "\n", "<br>\n") + "</p>"
+}
+
+var textPolicy = bluemonday.StrictPolicy().AddSpaceWhenStrippingTag(true)
+
+// Excerpt turns rendered HTML into plain text, longer texts are cut at a word boundary within limit characters and end with an ellipsis
+func Excerpt(contentHTML string, limit int) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(textPolicy.Sanitize(contentHTML))), " ")
+
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:!?") + "…"
+}
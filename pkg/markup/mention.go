@@ -0,0 +1,33 @@
+package markup
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mention is an @username reference in the source text, offsets count characters and End is exclusive
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// an @ glued to a word or another @ is part of an e-mail address or similar, not a mention
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_][\p{L}\p{N}_.\-]*)`)
+
+// FindMentions lists the @username references of the text in order of appearance
+func FindMentions(text string) []Mention {
+	var mentions []Mention
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		username := strings.TrimRight(text[match[2]:match[3]], ".-")
+		at := match[2] - 1
+		start := utf8.RuneCountInString(text[:at])
+		mentions = append(mentions, Mention{
+			Username: username,
+			Start:    start,
+			End:      start + 1 + utf8.RuneCountInString(username),
+		})
+	}
+	return mentions
+}